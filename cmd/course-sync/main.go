@@ -0,0 +1,13 @@
+// Command course-sync syncs Udemy and Pluralsight catalogs and learner
+// progress with Eightfold. Run it without arguments for the command list.
+package main
+
+import (
+	"os"
+
+	"course-sync/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
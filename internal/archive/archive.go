@@ -0,0 +1,270 @@
+// Package archive keeps what course-sync generated and what it sent to
+// Eightfold, so both can be shown later.
+//
+// Every run that writes files gets a directory of its own under the archive
+// root, named after its start time and run ID:
+//
+//	out/archive/20261018T021500Z-3f9a1c2b7d4e/
+//	    ef_course_add.xml.gz
+//	    ef_course_update.xml.gz
+//	    ef_course_delete.xml.gz
+//	    manifest.json
+//
+// The files are gzip-compressed; manifest.json records the size and SHA-256
+// of each original file. Prune removes run directories past the retention.
+// Uploads are recorded separately in an append-only audit log (see audit.go).
+package archive
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"course-sync/internal/atomicfile"
+)
+
+// ManifestName is the manifest file in every run directory.
+const ManifestName = "manifest.json"
+
+// stampFormat starts every run directory name; Prune reads it back.
+const stampFormat = "20060102T150405Z"
+
+// Manifest lists the files archived by one run.
+type Manifest struct {
+	RunID   string    `json:"run_id"`
+	Command string    `json:"command"`
+	Created time.Time `json:"created"`
+	Files   []Entry   `json:"files"`
+}
+
+// Entry is one archived file. Size and SHA256 are those of the original,
+// uncompressed file, so they compare directly with the audit log and with
+// the .sha256 files next to the uploads.
+type Entry struct {
+	// Name is the original base name; Archive the gzip file in the run
+	// directory; Source the path the file was written to.
+	Name     string    `json:"name"`
+	Archive  string    `json:"archive"`
+	Source   string    `json:"source"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Archived time.Time `json:"archived"`
+}
+
+// Run is the archive directory of one run. It is created on the first Add.
+type Run struct {
+	Dir string
+
+	mu       sync.Mutex
+	manifest Manifest
+}
+
+// NewRun returns the archive of the run runID of command, started at
+// start, under root.
+func NewRun(root, runID, command string, start time.Time) *Run {
+	start = start.UTC()
+	return &Run{
+		Dir:      filepath.Join(root, start.Format(stampFormat)+"-"+runID),
+		manifest: Manifest{RunID: runID, Command: command, Created: start},
+	}
+}
+
+// Add stores a gzip copy of the file at path and records it in the
+// manifest. Adding a file with the same base name again replaces it.
+func (a *Run) Add(path string) (Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(a.Dir, 0o755); err != nil {
+		return Entry{}, fmt.Errorf("archive: %w", err)
+	}
+	e, err := compress(path, a.Dir)
+	if err != nil {
+		return Entry{}, fmt.Errorf("archive %s: %w", path, err)
+	}
+
+	m := a.manifest
+	m.Files = append([]Entry{}, m.Files...)
+	replaced := false
+	for i := range m.Files {
+		if m.Files[i].Name == e.Name {
+			m.Files[i], replaced = e, true
+		}
+	}
+	if !replaced {
+		m.Files = append(m.Files, e)
+	}
+	if err := writeJSON(filepath.Join(a.Dir, ManifestName), m); err != nil {
+		return Entry{}, fmt.Errorf("archive: manifest: %w", err)
+	}
+	a.manifest = m
+	return e, nil
+}
+
+// Find returns the entry of the file archived from path, if any.
+func (a *Run) Find(path string) (Entry, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, e := range a.manifest.Files {
+		if filepath.Clean(e.Source) == filepath.Clean(path) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// compress writes dir/<base name>.gz and returns its entry.
+func compress(path, dir string) (e Entry, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	name := filepath.Base(path)
+	e = Entry{Name: name, Archive: name + ".gz", Source: path}
+	err = atomicfile.Write(filepath.Join(dir, e.Archive), 0o600, func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		zw.Name, zw.ModTime = name, fi.ModTime()
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(zw, h), src)
+		if err != nil {
+			return err
+		}
+		e.Size, e.SHA256 = n, hex.EncodeToString(h.Sum(nil))
+		return zw.Close()
+	})
+	e.Archived = time.Now().UTC()
+	return e, err
+}
+
+// ReadManifest reads the manifest of the run directory dir.
+func ReadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	b, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", filepath.Join(dir, ManifestName), err)
+	}
+	return m, nil
+}
+
+// Verify decompresses every file in the manifest of dir and checks its size
+// and SHA-256. It returns the manifest and all mismatches found.
+func Verify(dir string) (Manifest, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return m, err
+	}
+	var errs []error
+	for _, e := range m.Files {
+		size, sum, err := checksum(filepath.Join(dir, e.Archive))
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", e.Archive, err))
+		case size != e.Size || sum != e.SHA256:
+			errs = append(errs, fmt.Errorf("%s: %w: %d bytes, sha256 %s; manifest has %d bytes, sha256 %s",
+				e.Archive, ErrMismatch, size, sum, e.Size, e.SHA256))
+		}
+	}
+	return m, errors.Join(errs...)
+}
+
+// ErrMismatch is returned, wrapped, by Verify for a file that does not
+// match its manifest entry.
+var ErrMismatch = errors.New("does not match the manifest")
+
+func checksum(gzPath string) (int64, string, error) {
+	f, err := os.Open(gzPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, "", err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, zr)
+	if err != nil {
+		return n, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), zr.Close()
+}
+
+// Runs returns the run directories under root, oldest first. Other entries
+// are ignored.
+func Runs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, de := range entries {
+		if _, ok := runTime(de.Name()); ok && de.IsDir() {
+			dirs = append(dirs, filepath.Join(root, de.Name()))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// runTime reads the start time off a run directory name.
+func runTime(name string) (time.Time, bool) {
+	if len(name) < len(stampFormat)+2 || name[len(stampFormat)] != '-' {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(stampFormat, name[:len(stampFormat)])
+	return t, err == nil
+}
+
+// Prune removes the run directories under root that started more than
+// retention before now and returns them. A retention of 0 keeps everything.
+func Prune(root string, retention time.Duration, now time.Time) ([]string, error) {
+	if retention <= 0 {
+		return nil, nil
+	}
+	dirs, err := Runs(root)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-retention)
+	var removed []string
+	var errs []error
+	for _, dir := range dirs {
+		if t, _ := runTime(filepath.Base(dir)); !t.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, dir)
+	}
+	return removed, errors.Join(errs...)
+}
+
+func writeJSON(path string, v any) error {
+	return atomicfile.Write(path, 0o600, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
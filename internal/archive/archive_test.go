@@ -0,0 +1,146 @@
+package archive
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunAddAndVerify(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	start := time.Date(2026, 10, 18, 2, 15, 0, 0, time.UTC)
+	a := NewRun(root, "3f9a1c2b7d4e", "sync courses", start)
+	if want := filepath.Join(root, "20261018T021500Z-3f9a1c2b7d4e"); a.Dir != want {
+		t.Fatalf("Dir = %s, want %s", a.Dir, want)
+	}
+
+	add := filepath.Join(out, "ef_course_add.xml")
+	writeFile(t, add, "<courses>old</courses>")
+	if _, err := a.Add(add); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, add, "<courses>new</courses>")
+	e, err := a.Add(add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del := filepath.Join(out, "ef_course_delete.xml")
+	writeFile(t, del, "<courses/>")
+	if _, err := a.Add(del); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte("<courses>new</courses>"))); e.SHA256 != want || e.Size != 22 {
+		t.Errorf("Entry = %+v, want sha256 %s and 22 bytes", e, want)
+	}
+	f, err := os.Open(filepath.Join(a.Dir, "ef_course_add.xml.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != "<courses>new</courses>" || zr.Name != "ef_course_add.xml" {
+		t.Errorf("Archived %q named %q", b, zr.Name)
+	}
+
+	m, err := Verify(a.Dir)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if m.RunID != "3f9a1c2b7d4e" || m.Command != "sync courses" || len(m.Files) != 2 || m.Files[1].Name != "ef_course_delete.xml" {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+
+	writeFile(t, filepath.Join(a.Dir, "ef_course_delete.xml.gz"), "not gzip")
+	if _, err := Verify(a.Dir); err == nil {
+		t.Error("Expected Verify to report a damaged file")
+	}
+	gz, _ := os.Create(filepath.Join(a.Dir, "ef_course_delete.xml.gz"))
+	zw := gzip.NewWriter(gz)
+	zw.Write([]byte("<courses>edited</courses>"))
+	zw.Close()
+	gz.Close()
+	if _, err := Verify(a.Dir); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected a mismatch for an edited file, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{
+		"20260601T000000Z-aaaaaaaaaaaa",
+		"20261010T000000Z-bbbbbbbbbbbb",
+		"20261018T110000Z-cccccccccccc",
+		"keep-me",
+	} {
+		if err := os.Mkdir(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(root, "20200101T000000Z-file"), "not a run directory")
+
+	if removed, err := Prune(root, 0, now); err != nil || len(removed) != 0 {
+		t.Fatalf("Expected a retention of 0 to keep everything, removed %v, %v", removed, err)
+	}
+	removed, err := Prune(root, 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || filepath.Base(removed[0]) != "20260601T000000Z-aaaaaaaaaaaa" {
+		t.Errorf("Expected the two runs older than a week removed, got %v", removed)
+	}
+	runs, _ := Runs(root)
+	if len(runs) != 1 || filepath.Base(runs[0]) != "20261018T110000Z-cccccccccccc" {
+		t.Errorf("Runs() = %v", runs)
+	}
+	for _, name := range []string{"keep-me", "20200101T000000Z-file"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("Expected %s to be left alone: %v", name, err)
+		}
+	}
+}
+
+func TestUploads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "uploads.jsonl")
+	if recs, err := ReadUploads(path); err != nil || recs != nil {
+		t.Fatalf("Expected no records before the first upload, got %v, %v", recs, err)
+	}
+	at := time.Date(2026, 10, 18, 2, 20, 0, 0, time.UTC)
+	if err := AppendUploads(path,
+		Upload{Time: at, RunID: "r1", RemotePath: "/inbound/ef_course_add.xml", Size: 10, SHA256: "aa"},
+		Upload{Time: at, RunID: "r1", RemotePath: "/inbound/ef_course_delete.xml", Size: 5, SHA256: "bb"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"time":"torn`)
+	f.Close()
+	if err := AppendUploads(path, Upload{Time: at.Add(time.Hour), RunID: "r2", RemotePath: "/inbound/x.csv"}); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := ReadUploads(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || recs[1].RemotePath != "/inbound/ef_course_delete.xml" || !recs[0].Time.Equal(at) || recs[2].RunID != "r2" {
+		t.Errorf("Unexpected records %+v", recs)
+	}
+}
@@ -0,0 +1,89 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Upload is one line of the upload audit log: a file that reached the
+// Eightfold SFTP server. The log is only appended to; nothing prunes it.
+type Upload struct {
+	Time    time.Time `json:"time"`
+	RunID   string    `json:"run_id"`
+	Command string    `json:"command"`
+	// Host is the SFTP server, RemotePath where the file was put.
+	Host       string `json:"host"`
+	RemotePath string `json:"remote_path"`
+	LocalPath  string `json:"local_path"`
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256"`
+	// Archive is the archived copy of the file, when this run made one.
+	Archive string `json:"archive,omitempty"`
+}
+
+// AppendUploads appends recs to the audit log at path and syncs it. A line
+// left incomplete by an earlier crash is terminated first, so it costs only
+// itself.
+func AppendUploads(path string, recs ...Upload) error {
+	if dir := filepath.Dir(path); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	var b []byte
+	for _, rec := range recs {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		b = append(append(b, line...), '\n')
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, fi.Size()-1); err == nil && last[0] != '\n' {
+			b = append([]byte{'\n'}, b...)
+		}
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadUploads returns the records in the audit log at path, oldest first.
+// Lines that do not parse are skipped so a torn write does not hide the
+// rest of the log.
+func ReadUploads(path string) ([]Upload, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Upload
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var rec Upload
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, sc.Err()
+}
@@ -0,0 +1,41 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "feed.xml")
+
+	if err := Write(path, 0o644, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "new" {
+		t.Errorf("Expected %s to hold %q, got %q, %v", path, "new", b, err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o644 {
+		t.Errorf("Expected mode 0644, got %v, %v", fi.Mode(), err)
+	}
+
+	failed := errors.New("disk full")
+	if err := Write(path, 0o600, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failed
+	}); !errors.Is(err, failed) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("Expected a failed write to keep the old content, got %q", b)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected the temporary file removed, got %v", entries)
+	}
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"course-sync/internal/archive"
+	"course-sync/internal/config"
+	"course-sync/internal/logging"
+	"course-sync/internal/sftpclient"
+)
+
+// archiveFiles keeps a gzip copy of each generated file in the run's
+// archive directory (see internal/archive). The first call of a run also
+// removes the run directories past archive.retention. An empty archive.dir
+// turns archiving off.
+func archiveFiles(r *Run, cfg config.Config, paths ...string) error {
+	if cfg.ArchiveDir == "" {
+		return nil
+	}
+	if r.archive == nil {
+		r.archive = archive.NewRun(cfg.ArchiveDir, r.ID, r.Command, r.Start)
+		removed, err := archive.Prune(cfg.ArchiveDir, cfg.ArchiveRetention, time.Now())
+		if err != nil {
+			r.Log.Warn("could not remove old archived runs", "component", "archive", logging.Err(err))
+		}
+		if len(removed) > 0 {
+			r.Log.Info("removed old archived runs", "component", "archive", "runs", len(removed), "retention", cfg.ArchiveRetention)
+		}
+	}
+	for _, p := range paths {
+		e, err := r.archive.Add(p)
+		if err != nil {
+			return err
+		}
+		r.Log.Debug("archived", "component", "archive", "file", p, "archive", filepath.Join(r.archive.Dir, e.Archive), "sha256", e.SHA256)
+	}
+	r.Summary["archive"] = r.archive.Dir
+	return nil
+}
+
+// recordUploads appends the files that were uploaded to archive.audit_log.
+func recordUploads(r *Run, cfg sftpclient.Config, auditLog string, results []sftpclient.Result) error {
+	if auditLog == "" {
+		return nil
+	}
+	var recs []archive.Upload
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		rec := archive.Upload{
+			Time:       res.Finished.UTC(),
+			RunID:      r.ID,
+			Command:    r.Command,
+			Host:       cfg.Host,
+			RemotePath: res.RemotePath,
+			LocalPath:  res.LocalPath,
+			Size:       res.Bytes,
+			SHA256:     res.SHA256,
+		}
+		if r.archive != nil {
+			if e, ok := r.archive.Find(res.LocalPath); ok && e.SHA256 == res.SHA256 {
+				rec.Archive = filepath.Join(r.archive.Dir, e.Archive)
+			}
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) == 0 {
+		return nil
+	}
+	if err := archive.AppendUploads(auditLog, recs...); err != nil {
+		return fmt.Errorf("recording the uploads in %s: %w", auditLog, err)
+	}
+	return nil
+}
+
+// archiveUploads prints the upload audit log, optionally limited to a range
+// of days.
+func archiveUploads(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		since  = fs.String("since", "", "only uploads on or after this day (YYYY-MM-DD, local time)")
+		until  = fs.String("until", "", "only uploads on or before this day (YYYY-MM-DD, local time)")
+		asJSON = fs.Bool("json", false, "print the records as JSON lines")
+	)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	from, err := parseDay(fs.Output(), "since", *since)
+	if err != nil {
+		return err
+	}
+	to, err := parseDay(fs.Output(), "until", *until)
+	if err != nil {
+		return err
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+
+	recs, err := archive.ReadUploads(cfg.ArchiveAuditLog)
+	if err != nil {
+		return err
+	}
+	filtered := recs[:0]
+	for _, rec := range recs {
+		if (from.IsZero() || !rec.Time.Before(from)) && (to.IsZero() || rec.Time.Before(to)) {
+			filtered = append(filtered, rec)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(r.Stdout)
+		for _, rec := range filtered {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(r.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tREMOTE\tSIZE\tSHA256\tRUN")
+	for _, rec := range filtered {
+		fmt.Fprintf(tw, "%s\t%s:%s\t%d\t%s\t%s\n",
+			rec.Time.Local().Format("2006-01-02 15:04:05"), rec.Host, rec.RemotePath, rec.Size, rec.SHA256, rec.RunID)
+	}
+	return tw.Flush()
+}
+
+// parseDay parses a -since/-until day; empty is the zero time.
+func parseDay(out io.Writer, name, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		fmt.Fprintf(out, "-%s: expected YYYY-MM-DD, got %q\n", name, s)
+		return time.Time{}, errUsage
+	}
+	return t, nil
+}
+
+// archiveVerify checks the archived files of the given run directories, or
+// of every run under archive.dir, against their manifests.
+func archiveVerify(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync archive verify [flags] [RUN_DIR...]")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		cfg, err := r.config(ctx)
+		if err != nil {
+			return err
+		}
+		if cfg.ArchiveDir == "" {
+			return errors.New("config: archive.dir is not set")
+		}
+		if dirs, err = archive.Runs(cfg.ArchiveDir); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, dir := range dirs {
+		m, err := archive.Verify(dir)
+		if err != nil {
+			failed++
+			fmt.Fprintf(r.Stdout, "FAIL %s: %v\n", dir, err)
+			continue
+		}
+		fmt.Fprintf(r.Stdout, "ok   %s (%d files)\n", dir, len(m.Files))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d archived runs failed verification", failed, len(dirs))
+	}
+	return nil
+}
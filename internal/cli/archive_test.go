@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"course-sync/internal/archive"
+)
+
+func TestArchiveCommands(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("ARCHIVE_DIR", filepath.Join(dir, "archive"))
+	t.Setenv("ARCHIVE_AUDIT_LOG", filepath.Join(dir, "uploads.jsonl"))
+
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 12, 0, 0, 0, time.Local) }
+	if err := archive.AppendUploads(filepath.Join(dir, "uploads.jsonl"),
+		archive.Upload{Time: day(16), Host: "sftp.example.com", RemotePath: "/inbound/ef_course_add.xml", SHA256: "aa16"},
+		archive.Upload{Time: day(17), Host: "sftp.example.com", RemotePath: "/inbound/ef_course_add.xml", SHA256: "aa17"},
+		archive.Upload{Time: day(18), Host: "sftp.example.com", RemotePath: "/inbound/ef_course_delete.xml", SHA256: "dd18"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(dir, "ef_course_add.xml")
+	if err := os.WriteFile(local, []byte("<courses/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a := archive.NewRun(filepath.Join(dir, "archive"), "3f9a1c2b7d4e", "export xml", day(17))
+	if _, err := a.Add(local); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := func(args ...string) (int, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), append([]string{"archive"}, args...), &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	code, out := cmd("uploads", "-since", "2026-10-17", "-until", "2026-10-17")
+	if code != 0 || !strings.Contains(out, "sftp.example.com:/inbound/ef_course_add.xml") || !strings.Contains(out, "aa17") ||
+		strings.Contains(out, "aa16") || strings.Contains(out, "dd18") {
+		t.Errorf("Expected only the upload of the 17th, exit %d:\n%s", code, out)
+	}
+	if code, _ := cmd("uploads", "-since", "yesterday"); code != 2 {
+		t.Errorf("Expected a usage error for a bad day, got %d", code)
+	}
+
+	if code, out := cmd("verify"); code != 0 || !strings.Contains(out, "ok   "+a.Dir+" (1 files)") {
+		t.Errorf("Expected the archive to verify, exit %d:\n%s", code, out)
+	}
+	if err := os.WriteFile(filepath.Join(a.Dir, "ef_course_add.xml.gz"), []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, out := cmd("verify", a.Dir); code != 1 || !strings.Contains(out, "FAIL "+a.Dir) {
+		t.Errorf("Expected a tampered archive to fail, exit %d:\n%s", code, out)
+	}
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"course-sync/internal/atomicfile"
+)
+
+const (
+	checkpointDefault = "out/sync_employees.checkpoint.json"
+
+	// A checkpoint is saved after this many finished employees or this
+	// long since the last save, whichever comes first.
+	checkpointEvery    = 200
+	checkpointInterval = 30 * time.Second
+)
+
+// errCheckpointExpired is returned by loadCheckpoint for a checkpoint
+// older than the allowed age.
+var errCheckpointExpired = errors.New("checkpoint expired")
+
+// checkpoint records the employees a sync employees run has finished, so
+// that a run started with -resume after a crash, timeout or deploy can skip
+// them. An employee is finished when its courses were patched, or when it
+// had none to patch; one with any failure is left for the next run (and is
+// in the dead-letter file).
+//
+// A nil *checkpoint records nothing (dry runs, or -checkpoint ""). It is
+// safe for concurrent use: the employees are checked against it while they
+// are paged and marked in it as they are written.
+type checkpoint struct {
+	path string
+
+	mu   sync.Mutex
+	file checkpointFile
+	done map[string]bool
+
+	unsaved   int
+	lastSaved time.Time
+}
+
+// checkpointFile is the JSON stored on disk.
+type checkpointFile struct {
+	// RunID and Started are those of the run that began the sync; resumed
+	// runs keep them, so the age of a checkpoint is the age of its data.
+	RunID   string    `json:"run_id"`
+	Started time.Time `json:"started"`
+	Saved   time.Time `json:"saved"`
+	Done    []string  `json:"done"`
+}
+
+func newCheckpoint(path, runID string) *checkpoint {
+	return &checkpoint{
+		path:      path,
+		file:      checkpointFile{RunID: runID, Started: time.Now().UTC()},
+		done:      map[string]bool{},
+		lastSaved: time.Now(),
+	}
+}
+
+// loadCheckpoint reads the checkpoint in path. It returns nil and no error
+// when there is none, and errCheckpointExpired when it was started more
+// than maxAge ago (0 means it never expires).
+func loadCheckpoint(path string, maxAge time.Duration) (*checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{path: path, done: map[string]bool{}, lastSaved: time.Now()}
+	if err := json.Unmarshal(b, &c.file); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if age := time.Since(c.file.Started); maxAge > 0 && age > maxAge {
+		return nil, fmt.Errorf("%w: %s started %s ago (max %s)", errCheckpointExpired, path, age.Round(time.Second), maxAge)
+	}
+	for _, id := range c.file.Done {
+		c.done[id] = true
+	}
+	return c, nil
+}
+
+// has reports whether profileID was finished by the checkpointed run.
+func (c *checkpoint) has(profileID string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[profileID]
+}
+
+// len is the number of finished employees.
+func (c *checkpoint) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+// mark records profileID as finished, saving the checkpoint now and then.
+func (c *checkpoint) mark(profileID string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done[profileID] {
+		return nil
+	}
+	c.done[profileID] = true
+	c.unsaved++
+	if c.unsaved >= checkpointEvery || time.Since(c.lastSaved) >= checkpointInterval {
+		return c.saveLocked()
+	}
+	return nil
+}
+
+// save writes the checkpoint atomically.
+func (c *checkpoint) save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+func (c *checkpoint) saveLocked() error {
+	c.file.Saved = time.Now().UTC()
+	c.file.Done = c.file.Done[:0]
+	for id := range c.done {
+		c.file.Done = append(c.file.Done, id)
+	}
+	sort.Strings(c.file.Done)
+	if err := atomicfile.Write(c.path, 0o644, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(c.file)
+	}); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	c.unsaved = 0
+	c.lastSaved = time.Now()
+	return nil
+}
+
+// remove deletes the checkpoint once the run has gone through every
+// employee, so the next -resume starts a full sync.
+func (c *checkpoint) remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
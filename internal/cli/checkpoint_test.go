@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "cp.json")
+
+	if cp, err := loadCheckpoint(path, time.Hour); cp != nil || err != nil {
+		t.Fatalf("loadCheckpoint() of a missing file = %v, %v; want nil, nil", cp, err)
+	}
+
+	cp := newCheckpoint(path, "run1")
+	for i := 0; i < checkpointEvery-1; i++ {
+		cp.mark(fmt.Sprintf("e%d", i))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected no save before %d marks, got %v", checkpointEvery, err)
+	}
+	cp.mark("p1")
+	cp.mark("p1") // already finished: not counted again
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected a save after %d marks: %v", checkpointEvery, err)
+	}
+
+	got, err := loadCheckpoint(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() error: %v", err)
+	}
+	if got.len() != checkpointEvery || !got.has("p1") || got.has("p2") || got.file.RunID != "run1" {
+		t.Errorf("Unexpected checkpoint: %d finished, run %s", got.len(), got.file.RunID)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected only the checkpoint in its directory (no temp files), got %v", entries)
+	}
+
+	if err := got.remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.remove(); err != nil {
+		t.Errorf("remove() of a removed checkpoint: %v", err)
+	}
+}
+
+func TestCheckpointExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cp.json")
+	b, _ := json.Marshal(checkpointFile{RunID: "old", Started: time.Now().Add(-48 * time.Hour), Done: []string{"p1"}})
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadCheckpoint(path, 24*time.Hour); !errors.Is(err, errCheckpointExpired) {
+		t.Errorf("Expected errCheckpointExpired, got %v", err)
+	}
+	if cp, err := loadCheckpoint(path, 0); err != nil || !cp.has("p1") {
+		t.Errorf("Expected a max age of 0 to never expire, got %v", err)
+	}
+}
+
+func TestNilCheckpoint(t *testing.T) {
+	var cp *checkpoint
+	if cp.has("p1") || cp.len() != 0 || cp.mark("p1") != nil || cp.save() != nil || cp.remove() != nil {
+		t.Error("Expected a nil checkpoint to record nothing")
+	}
+}
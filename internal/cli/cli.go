@@ -0,0 +1,419 @@
+// Package cli implements the course-sync command line: one binary whose
+// subcommands share config loading, Eightfold/provider auth, SFTP upload,
+// logging and run history.
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/archive"
+	"course-sync/internal/config"
+	"course-sync/internal/httpx/cassette"
+	"course-sync/internal/logging"
+	"course-sync/internal/tracing"
+)
+
+// command is one subcommand. name may have several words ("sync courses").
+type command struct {
+	name    string
+	summary string
+	// profile is the config commands.<profile> section, and the key into
+	// config.Requirements. Empty for commands that do not load config.
+	profile string
+	// record makes the run show up in `course-sync history`.
+	record bool
+	// lock names the lock that keeps runs from overlapping (see acquireLock).
+	// Commands that touch the same state share one: those that write the
+	// course feeds or the SFTP inbound directory take sync-courses. It is
+	// taken in lock.dir once the config is loaded. Empty for no lock.
+	lock string
+	run  func(ctx context.Context, r *Run, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "sync courses", profile: "sync-courses", record: true, lock: "sync-courses", run: syncCourses,
+			summary: "diff provider catalogs against Eightfold and write add/update/delete XML"},
+		{name: "sync employees", profile: "sync-employees", record: true, lock: "sync-employees", run: syncEmployees,
+			summary: "push Udemy/Pluralsight course progress to Eightfold employee profiles"},
+		{name: "replay", profile: "replay", record: true, lock: "sync-employees", run: replay,
+			summary: "retry the employees sync employees recorded in its dead-letter file"},
+		{name: "export csv", profile: "export-csv", record: true, lock: "sync-courses", run: exportCSV,
+			summary: "export provider catalogs to the Eightfold course CSV"},
+		{name: "export xml", profile: "export-xml", record: true, lock: "sync-courses", run: exportXML,
+			summary: "export provider catalogs to Eightfold ef_course XML"},
+		{name: "export employees", profile: "export-employees", record: true, lock: "sync-courses", run: exportEmployees,
+			summary: "export Eightfold employees to EF_Employee_List XML"},
+		{name: "upload", profile: "upload", record: true, lock: "sync-courses", run: upload,
+			summary: "upload files to the Eightfold SFTP inbound directory"},
+		{name: "sftp list", profile: "upload", run: sftpList,
+			summary: "list the SFTP inbound directory (or another directory)"},
+		{name: "sftp stat", profile: "upload", run: sftpStat,
+			summary: "show the size and modification time of remote files"},
+		{name: "sftp download", profile: "upload", run: sftpDownload,
+			summary: "download a remote file"},
+		{name: "sftp delete", profile: "upload", record: true, lock: "sync-courses", run: sftpDelete,
+			summary: "delete remote files by name, or those older than -older-than"},
+		{name: "sftp wait", profile: "upload", record: true, run: sftpWait,
+			summary: "wait until Eightfold has consumed (removed or moved) uploaded files"},
+		{name: "sftp hostkey", profile: "upload", run: sftpHostKey,
+			summary: "show the SFTP server's host key fingerprint and whether it is trusted"},
+		{name: "pgp keygen", run: pgpKeygen,
+			summary: "write a new PGP key pair, to try encryption locally"},
+		{name: "pgp encrypt", profile: "pgp", run: pgpEncrypt,
+			summary: "encrypt (and sign) a file as uploads do with pgp.encrypt"},
+		{name: "pgp decrypt", profile: "pgp", run: pgpDecrypt,
+			summary: "decrypt an encrypted file and check its signature"},
+		{name: "archive uploads", profile: "archive", run: archiveUploads,
+			summary: "show the uploads recorded in the audit log (what was sent to Eightfold, and when)"},
+		{name: "archive verify", profile: "archive", run: archiveVerify,
+			summary: "check archived files against their manifests"},
+		{name: "serve", profile: "serve", run: serve,
+			summary: "run syncs on schedules, with a local HTTP trigger"},
+		{name: "validate", run: validate,
+			summary: "check generated XML/CSV files against the Eightfold feed formats"},
+		{name: "history", run: history,
+			summary: "show recent runs"},
+		{name: "config check", run: configCheck,
+			summary: "print the resolved, redacted configuration and validate it"},
+		{name: "secrets keygen", run: secretsKeygen,
+			summary: "print a new COURSE_SYNC_SECRETS_KEY"},
+		{name: "secrets seal", run: secretsSeal,
+			summary: "encrypt NAME=value lines into the secrets file"},
+		{name: "secrets list", run: secretsList,
+			summary: "print the names stored in the secrets file"},
+	}
+}
+
+// Main runs the command line and returns the process exit code.
+func Main(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return run(ctx, args, os.Stdout, os.Stderr)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cmd, rest, ok := lookup(args)
+	if !ok {
+		if len(args) > 0 && args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+			fmt.Fprintf(stderr, "course-sync: unknown command %q\n\n", strings.Join(args, " "))
+			usage(stderr)
+			return 2
+		}
+		usage(stdout)
+		return 0
+	}
+
+	logOpts, err := logging.OptionsFromEnv()
+	if err != nil {
+		fmt.Fprintf(stderr, "course-sync: %v\n", err)
+		return 2
+	}
+	// Code that logs without a run's logger (logging.FromContext falls
+	// back to slog.Default) is redacted too.
+	slog.SetDefault(logging.New(stderr, logOpts))
+
+	shutdown, err := tracing.Setup(ctx, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "course-sync: %v\n", err)
+		return 2
+	}
+	defer func() {
+		sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := shutdown(sctx); err != nil {
+			fmt.Fprintf(stderr, "course-sync: flushing traces: %v\n", err)
+		}
+	}()
+
+	r, err := execute(ctx, cmd, rest, stdout, stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		r.Log.Error("command failed", logging.Err(err))
+		return 1
+	}
+}
+
+var tracer = otel.Tracer("course-sync/internal/cli")
+
+// execute runs cmd under a new Run, holding the command's lock and
+// recording the run history as configured. serve uses it for every job.
+func execute(ctx context.Context, cmd command, args []string, stdout, stderr io.Writer) (*Run, error) {
+	r := &Run{
+		ID:      newRunID(),
+		Command: cmd.name,
+		Profile: cmd.profile,
+		lock:    cmd.lock,
+		Start:   time.Now(),
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Summary: map[string]any{},
+	}
+	// run validated the environment already; serve jobs share it.
+	logOpts, _ := logging.OptionsFromEnv()
+	r.Log = logging.New(stderr, logOpts).With("run_id", r.ID, "command", cmd.name)
+
+	ctx, span := tracer.Start(ctx, cmd.name, trace.WithAttributes(
+		attribute.String("run_id", r.ID),
+		attribute.String("command", cmd.name),
+	))
+	var err error
+	defer tracing.End(span, &err)
+	if sc := span.SpanContext(); sc.IsValid() {
+		r.Log = r.Log.With("trace_id", sc.TraceID().String())
+	}
+	ctx = logging.NewContext(ctx, r.Log)
+
+	start := r.Start
+	err = cmd.run(ctx, r, args)
+	if r.release != nil {
+		r.release()
+	}
+	// A failed run is worth recording too: it is what a regression test
+	// wants to reproduce.
+	if serr := r.saveCassette(); serr != nil {
+		r.Log.Error("could not save the HTTP cassette", logging.Err(serr))
+		if err == nil {
+			err = serr
+		}
+	}
+
+	if cmd.record && !errors.Is(err, flag.ErrHelp) && !errors.Is(err, errUsage) {
+		rec := HistoryRecord{
+			ID:       r.ID,
+			Command:  cmd.name,
+			Start:    start.UTC(),
+			End:      time.Now().UTC(),
+			Duration: time.Since(start).Round(time.Millisecond).String(),
+			Status:   "ok",
+			Summary:  r.Summary,
+		}
+		switch {
+		case errors.Is(err, errLocked):
+			rec.Status = "skipped"
+			rec.Error = err.Error()
+		case err != nil:
+			rec.Status = "error"
+			rec.Error = err.Error()
+		}
+		if herr := appendHistory(historyPath(), rec); herr != nil {
+			r.Log.Warn("could not record run history", logging.Err(herr))
+		}
+		recordRunMetrics(r, rec)
+		r.Log.Info("run finished", "status", rec.Status, "duration", rec.Duration)
+	}
+	return r, err
+}
+
+// lookup finds the command with the longest name matching the start of args.
+func lookup(args []string) (command, []string, bool) {
+	var (
+		best  command
+		words int
+	)
+	for _, c := range commands {
+		name := strings.Fields(c.name)
+		if len(name) <= words || len(args) < len(name) {
+			continue
+		}
+		match := true
+		for i, w := range name {
+			if args[i] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			best, words = c, len(name)
+		}
+	}
+	if words == 0 {
+		return command{}, nil, false
+	}
+	return best, args[words:], true
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: course-sync <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'course-sync <command> -h' for the flags of a command.")
+}
+
+// errUsage is returned for bad command lines; the message was already printed.
+var errUsage = errors.New("usage error")
+
+// Run carries what every command shares: identity, output, logger, config
+// flags and the summary recorded in the run history.
+type Run struct {
+	ID      string
+	Command string
+	Profile string
+	Start   time.Time
+
+	Stdout io.Writer
+	Stderr io.Writer
+	// Log carries run_id and command; it is also in the context passed to
+	// the command (see logging.FromContext).
+	Log *slog.Logger
+
+	// Summary holds counts and file names for the history record.
+	Summary map[string]any
+
+	cfgOpts *config.Options
+
+	// lock is the command's lock; settings takes it and sets release.
+	lock    string
+	release func()
+
+	// httpRecord and httpReplay are the -http-record/-http-replay files;
+	// cassette is opened from them once the flags are parsed.
+	httpRecord string
+	httpReplay string
+	cassette   *cassette.Cassette
+
+	// archive keeps the files the run generated; see archiveFiles.
+	archive *archive.Run
+}
+
+// flags returns a FlagSet for the command with the shared -config, -env
+// and -set flags registered when the command loads config.
+func (r *Run) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("course-sync "+r.Command, flag.ContinueOnError)
+	fs.SetOutput(r.Stderr)
+	if r.Profile != "" {
+		r.cfgOpts = config.RegisterFlags(fs, r.Profile)
+		r.cfgOpts.Profiles = profileNames()
+		fs.StringVar(&r.httpRecord, "http-record", "", "record the HTTP exchanges with Eightfold and the providers to this cassette file (secrets and PII scrubbed)")
+		fs.StringVar(&r.httpReplay, "http-replay", "", "answer HTTP requests from this cassette file instead of the network")
+	}
+	return fs
+}
+
+// parse parses args into fs, mapping parse failures to errUsage.
+func (r *Run) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return r.openCassette()
+}
+
+// openCassette opens the cassette named by -http-record or -http-replay.
+func (r *Run) openCassette() error {
+	path, mode := r.httpRecord, cassette.Record
+	switch {
+	case r.httpRecord != "" && r.httpReplay != "":
+		fmt.Fprintln(r.Stderr, "-http-record and -http-replay are mutually exclusive")
+		return errUsage
+	case r.httpReplay != "":
+		path, mode = r.httpReplay, cassette.Replay
+	case r.httpRecord == "":
+		return nil
+	}
+	c, err := cassette.Open(path, mode)
+	if err != nil {
+		return err
+	}
+	r.cassette = c
+	r.Log.Info("HTTP cassette enabled", "mode", mode.String(), "file", path, "exchanges", c.Len())
+	return nil
+}
+
+// saveCassette writes a recorded cassette at the end of the run.
+func (r *Run) saveCassette() error {
+	if r.cassette == nil || r.cassette.Mode != cassette.Record {
+		return nil
+	}
+	if err := r.cassette.Save(); err != nil {
+		return err
+	}
+	r.Log.Info("recorded HTTP exchanges", "file", r.cassette.Path, "exchanges", r.cassette.Len())
+	return nil
+}
+
+// httpClient routes c through the cassette, if any.
+func (r *Run) httpClient(c *http.Client) {
+	if r.cassette != nil {
+		c.Transport = r.cassette.Transport(c.Transport)
+	}
+}
+
+// settings loads the layered config for the command's profile and takes
+// the command's lock in lock.dir, held until the run ends.
+func (r *Run) settings(ctx context.Context) (config.Config, error) {
+	opts := config.Options{Command: r.Profile, Profiles: profileNames()}
+	if r.cfgOpts != nil {
+		opts = *r.cfgOpts
+	}
+	cfg, err := config.LoadContext(ctx, opts)
+	if err != nil {
+		return config.Config{}, err
+	}
+	if r.lock != "" && r.release == nil {
+		path, err := lockPath(cfg.LockDir, r.lock)
+		if err != nil {
+			return config.Config{}, err
+		}
+		if r.release, err = acquireLock(path); err != nil {
+			return config.Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// config loads the config as settings does and checks the requirements of
+// the profile plus any extra sections (e.g. "sftp").
+func (r *Run) config(ctx context.Context, extra ...string) (config.Config, error) {
+	cfg, err := r.settings(ctx)
+	if err != nil {
+		return config.Config{}, err
+	}
+	required := append(append([]string{}, config.Requirements[r.Profile]...), extra...)
+	if err := cfg.Require(required...); err != nil {
+		return config.Config{}, err
+	}
+	return cfg, nil
+}
+
+func newRunID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
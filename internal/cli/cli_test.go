@@ -0,0 +1,340 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"course-sync/internal/domain"
+	syncx "course-sync/internal/sync"
+)
+
+func TestLookup(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		wantName string
+		wantRest []string
+		wantOK   bool
+	}{
+		{[]string{"sync", "courses", "-dry-run"}, "sync courses", []string{"-dry-run"}, true},
+		{[]string{"sync", "employees"}, "sync employees", []string{}, true},
+		{[]string{"export", "employees", "-upload"}, "export employees", []string{"-upload"}, true},
+		{[]string{"upload", "a.xml", "b.xml"}, "upload", []string{"a.xml", "b.xml"}, true},
+		{[]string{"secrets", "list"}, "secrets list", []string{}, true},
+		{[]string{"sync"}, "", nil, false},
+		{[]string{"sync", "nope"}, "", nil, false},
+		{nil, "", nil, false},
+	}
+
+	for _, tc := range testCases {
+		cmd, rest, ok := lookup(tc.args)
+		if ok != tc.wantOK || cmd.name != tc.wantName {
+			t.Errorf("lookup(%q) = %q, %v; want %q, %v", tc.args, cmd.name, ok, tc.wantName, tc.wantOK)
+			continue
+		}
+		if ok && strings.Join(rest, " ") != strings.Join(tc.wantRest, " ") {
+			t.Errorf("lookup(%q) rest = %q, want %q", tc.args, rest, tc.wantRest)
+		}
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), nil, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected exit code 0 for no arguments, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "sync courses") || !strings.Contains(stdout.String(), "config check") {
+		t.Errorf("Expected usage to list commands:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run(context.Background(), []string{"frobnicate"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown command, got %d", code)
+	}
+	if code := run(context.Background(), []string{"validate", "-bogus"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for a bad flag, got %d", code)
+	}
+}
+
+func TestRunRedactsDefaultLogger(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	t.Setenv(historyEnv, filepath.Join(t.TempDir(), "history.jsonl"))
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"history", "-n", "1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("history exited with %d: %s", code, stderr.String())
+	}
+	slog.Default().Info("fallback", "password", "hunter2", "user", "jane@femsa.com")
+	if s := stderr.String(); !strings.Contains(s, "fallback") || strings.Contains(s, "hunter2") || strings.Contains(s, "jane@") {
+		t.Errorf("Expected the default logger to redact, got:\n%s", s)
+	}
+}
+
+// TestSyncCoursesFromMocks runs `sync courses` offline end to end, then
+// validates its output and reads it back from the run history.
+func TestSyncCoursesFromMocks(t *testing.T) {
+	dir := t.TempDir()
+	mocks := filepath.Join(dir, "mocks")
+	if err := os.MkdirAll(mocks, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestJSON(t, filepath.Join(mocks, udemyJSONFile), []domain.UnifiedCourse{
+		{Source: "udemy", SourceID: "1", Title: udemyCourse1, Language: "en", CourseURL: "https://u/1"},
+		{Source: "udemy", SourceID: "2", Title: "Udemy Course 2", Language: "es", CourseURL: "https://u/2"},
+	})
+	writeTestJSON(t, filepath.Join(mocks, pluralSightJSONFile), []domain.UnifiedCourse{
+		{Source: "pluralsight", SourceID: "3", Title: pluralSightCourse1, Language: "en", CourseURL: "https://p/3"},
+	})
+	writeTestJSON(t, filepath.Join(mocks, eightfoldJSONFile), []syncx.EFCourse{
+		{SystemID: "UDM+1", Title: udemyCourse1},
+		{SystemID: "PLS+99", Title: "Gone"},
+	})
+
+	history := filepath.Join(dir, "history.jsonl")
+	t.Setenv(historyEnv, history)
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+	t.Setenv(metricsDirEnv, filepath.Join(dir, "textfile"))
+
+	out := filepath.Join(dir, "out")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{
+		"sync", "courses",
+		"-mock-dir", mocks,
+		"-out-add", filepath.Join(out, "add.xml"),
+		"-out-update", filepath.Join(out, "update.xml"),
+		"-out-delete", filepath.Join(out, "delete.xml"),
+	}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("sync courses exited %d:\n%s", code, stderr.String())
+	}
+
+	code = run(context.Background(), []string{
+		"validate", filepath.Join(out, "add.xml"), filepath.Join(out, "update.xml"), filepath.Join(out, "delete.xml"),
+	}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("validate exited %d:\n%s", code, stdout.String())
+	}
+
+	recs, err := readHistory(history)
+	if err != nil {
+		t.Fatalf("readHistory() error: %v", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("Expected one recorded run (validate is not recorded), got %d", len(recs))
+	}
+	rec := recs[0]
+	if rec.Command != "sync courses" || rec.Status != "ok" || rec.ID == "" {
+		t.Errorf("Unexpected history record: %+v", rec)
+	}
+	if rec.Summary["providers"] != float64(3) || rec.Summary["eightfold"] != float64(2) {
+		t.Errorf("Unexpected summary: %v", rec.Summary)
+	}
+
+	prom, err := os.ReadFile(filepath.Join(dir, "textfile", "course_sync_sync-courses.prom"))
+	if err != nil {
+		t.Fatalf("Expected a textfile for the run: %v", err)
+	}
+	for _, want := range []string{
+		`course_sync_diff_courses_total{profile="sync-courses",action="create"}`,
+		`course_sync_runs_total{profile="sync-courses",command="sync courses",status="ok"}`,
+		`course_sync_last_success_timestamp_seconds{profile="sync-courses",command="sync courses"}`,
+	} {
+		if !strings.Contains(string(prom), want) {
+			t.Errorf("Expected %s in the textfile:\n%s", want, prom)
+		}
+	}
+
+	stdout.Reset()
+	if code := run(context.Background(), []string{"history", "-json"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("history exited %d", code)
+	}
+	var got HistoryRecord
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil || got.ID != rec.ID {
+		t.Errorf("history -json = %q, %v; want record %s", stdout.String(), err, rec.ID)
+	}
+}
+
+// TestRecordRunMetricsFailedRun checks that a failed run without an earlier
+// success leaves the last success out of the textfile rather than writing 0.
+func TestRecordRunMetricsFailedRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv(metricsDirEnv, dir)
+
+	r := &Run{Profile: "metrics-test", Log: slog.New(slog.DiscardHandler)}
+	now := time.Now()
+	recordRunMetrics(r, HistoryRecord{Command: "metrics test", Status: "error", Start: now.Add(-time.Second), End: now})
+
+	prom, err := os.ReadFile(filepath.Join(dir, "course_sync_metrics-test.prom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(prom), `course_sync_runs_total{profile="metrics-test",command="metrics test",status="error"} 1`) ||
+		strings.Contains(string(prom), `course_sync_last_success_timestamp_seconds{profile="metrics-test",command="metrics test"}`) {
+		t.Errorf("Expected the failed run counted and no last success, got:\n%s", prom)
+	}
+}
+
+// TestSyncCoursesTraces checks that a run exports its span tree with
+// COURSE_SYNC_TRACES=file:<path>.
+func TestSyncCoursesTraces(t *testing.T) {
+	dir := t.TempDir()
+	mocks := filepath.Join(dir, "mocks")
+	if err := os.MkdirAll(mocks, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestJSON(t, filepath.Join(mocks, udemyJSONFile), []domain.UnifiedCourse{
+		{Source: "udemy", SourceID: "1", Title: udemyCourse1, Language: "en"},
+	})
+	writeTestJSON(t, filepath.Join(mocks, pluralSightJSONFile), []domain.UnifiedCourse{})
+	writeTestJSON(t, filepath.Join(mocks, eightfoldJSONFile), []syncx.EFCourse{})
+
+	traces := filepath.Join(dir, "spans.jsonl")
+	t.Setenv("COURSE_SYNC_TRACES", "file:"+traces)
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+	t.Setenv(metricsDirEnv, filepath.Join(dir, "textfile"))
+
+	out := filepath.Join(dir, "out")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{
+		"sync", "courses",
+		"-mock-dir", mocks,
+		"-out-add", filepath.Join(out, "add.xml"),
+		"-out-update", filepath.Join(out, "update.xml"),
+		"-out-delete", filepath.Join(out, "delete.xml"),
+	}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("sync courses exited %d:\n%s", code, stderr.String())
+	}
+
+	f, err := os.Open(traces)
+	if err != nil {
+		t.Fatalf("Expected a trace file: %v", err)
+	}
+	defer f.Close()
+	names := map[string]int{}
+	dec := json.NewDecoder(f)
+	for {
+		var span struct{ Name string }
+		if err := dec.Decode(&span); err != nil {
+			break
+		}
+		names[span.Name]++
+	}
+	for name, want := range map[string]int{
+		"sync courses":         1,
+		"diff":                 1,
+		"diff.index_providers": 1,
+		"diff.deletes":         1,
+		"export.write":         3,
+	} {
+		if names[name] != want {
+			t.Errorf("Expected %d %q spans, got %d (all: %v)", want, name, names[name], names)
+		}
+	}
+	if !strings.Contains(stderr.String(), "trace_id=") {
+		t.Errorf("Expected log lines to carry trace_id:\n%s", stderr.String())
+	}
+}
+
+func TestFailedRunIsRecorded(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history.jsonl")
+	t.Setenv(historyEnv, history)
+	t.Setenv("COURSE_SYNC_LOCK_DIR", t.TempDir())
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"sync", "courses", "-mock-dir", "/non/existent"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+
+	recs, err := readHistory(history)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("readHistory() = %v, %v", recs, err)
+	}
+	if recs[0].Status != "error" || !strings.Contains(recs[0].Error, "mock: read") {
+		t.Errorf("Expected the failure to be recorded, got %+v", recs[0])
+	}
+}
+
+func TestHistoryFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	for i, cmd := range []string{"sync courses", "export csv", "sync courses", "sync employees"} {
+		rec := HistoryRecord{ID: string(rune('a' + i)), Command: cmd, Status: "ok"}
+		if err := appendHistory(path, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A torn line must not hide the other records.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"id":"tor`)
+	f.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"history", "-file", path, "-json", "-command", "sync courses", "-n", "1"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("history exited %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"id":"c"`) {
+		t.Errorf("Expected only the latest sync courses run, got:\n%s", stdout.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	ok := write("ok.csv", "systemId,title,COURSE_ID\nUDM+1,Go,1\nPLS+2,Rust,2\n")
+	bad := write("bad.xml", `<EF_Course_List>
+  <EF_Course><title>Go</title><lms_course_id>1</lms_course_id><course_type>Course</course_type></EF_Course>
+  <EF_Course><title>Go</title><lms_course_id>1</lms_course_id><published_ts>yesterday</published_ts></EF_Course>
+</EF_Course_List>`)
+	truncated := write("truncated.xml", `<EF_Course_List><EF_Course>`)
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"validate", ok}, &stdout, &stderr); code != 0 ||
+		!strings.Contains(stdout.String(), "ok   "+ok+" (course CSV, 2 records)") {
+		t.Errorf("Expected %s to pass, exit %d:\n%s", ok, code, stdout.String())
+	}
+	stdout.Reset()
+	code := run(context.Background(), []string{"validate", ok, bad, truncated}, &stdout, &stderr)
+	for _, want := range []string{
+		"FAIL " + bad + " (course XML, 2 records): 2 problems",
+		`     line 3: EF_Course 1: lms_course_id "1" is a duplicate, first at line 2`,
+		`     line 3: EF_Course 1: published_ts "yesterday" is not a date`,
+		"FAIL " + truncated + ": ",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %q in the output:\n%s", want, stdout.String())
+		}
+	}
+	if code != 1 || !strings.Contains(stderr.String(), "2 of 3 files failed validation") {
+		t.Errorf("Expected exit 1 for 2 failed files, got %d: %s", code, stderr.String())
+	}
+
+	// Without a title, a course passes only when asked for as a delete.
+	deletes := write("deletes.xml", `<EF_Course_List><EF_Course><lms_course_id>1</lms_course_id></EF_Course></EF_Course_List>`)
+	stdout.Reset()
+	if code := run(context.Background(), []string{"validate", deletes}, &stdout, &stderr); code != 1 ||
+		!strings.Contains(stdout.String(), "title is missing") {
+		t.Errorf("Expected %s to fail as a course feed, exit %d:\n%s", deletes, code, stdout.String())
+	}
+	stdout.Reset()
+	if code := run(context.Background(), []string{"validate", "-delete", deletes, ok}, &stdout, &stderr); code != 0 ||
+		!strings.Contains(stdout.String(), "ok   "+deletes+" (course delete XML, 1 records)") {
+		t.Errorf("Expected -delete to check %s as a delete feed, exit %d:\n%s", deletes, code, stdout.String())
+	}
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"course-sync/internal/config"
+	"course-sync/internal/providers/eightfold"
+	"course-sync/internal/providers/pluralsight"
+	"course-sync/internal/providers/udemy"
+	"course-sync/internal/sftpclient"
+)
+
+const (
+	authTimeout   = 2 * time.Minute
+	uploadTimeout = 5 * time.Minute
+)
+
+// newEightfold returns an authenticated Eightfold client. A configured
+// bearer token is used as is; otherwise the password grant is used, which
+// also refreshes the token before it expires.
+func newEightfold(ctx context.Context, r *Run, cfg config.Config) (*eightfold.Client, error) {
+	ef := eightfold.New(cfg.EightfoldBaseURL)
+	ef.MaxPageSize = cfg.EightfoldMaxPageSize
+	r.httpClient(ef.HTTP)
+
+	if tok := strings.TrimSpace(cfg.EightfoldBearerToken); tok != "" {
+		ef.BearerToken = tok
+		return ef, nil
+	}
+
+	authCtx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+
+	r.Log.Info("authenticating with Eightfold", "component", "eightfold")
+	if err := ef.Authenticate(authCtx, cfg.EightfoldBasicAuth, eightfold.AuthRequest{
+		GrantType: "password",
+		Username:  cfg.EightfoldUser,
+		Password:  cfg.EightfoldPass,
+	}); err != nil {
+		return nil, fmt.Errorf("eightfold auth: %w", err)
+	}
+	return ef, nil
+}
+
+func newUdemy(r *Run, cfg config.Config) *udemy.Client {
+	c := udemy.New(cfg.UdemyBaseURL, cfg.UdemyClientID, cfg.UdemyClientSecret)
+	r.httpClient(c.HTTP)
+	return c
+}
+
+func newPluralsight(r *Run, cfg config.Config) *pluralsight.Client {
+	c := pluralsight.New(cfg.PluralsightBaseURL, cfg.PluralsightToken)
+	r.httpClient(c.HTTP)
+	return c
+}
+
+func sftpConfig(cfg config.Config) sftpclient.Config {
+	return sftpclient.Config{
+		Host:                  cfg.SFTPHost,
+		Port:                  cfg.SFTPPort,
+		User:                  cfg.SFTPUser,
+		Pass:                  cfg.SFTPPass,
+		RemoteDir:             cfg.SFTPDir,
+		InsecureIgnoreHostKey: cfg.SFTPInsecureIgnoreHostKey,
+		HostKey:               cfg.SFTPHostKey,
+		KnownHosts:            cfg.SFTPKnownHostsFiles(),
+		TrustOnFirstUse:       cfg.SFTPTrustOnFirstUse,
+		KeyPath:               cfg.SFTPKeyPath,
+		KeyPassphrase:         cfg.SFTPKeyPassphrase,
+		Checksum:              cfg.SFTPChecksum,
+		Verify:                cfg.SFTPVerify,
+		Attempts:              cfg.SFTPAttempts,
+	}
+}
+
+// uploadFile sends localPath to the configured SFTP directory as remoteName.
+func uploadFile(ctx context.Context, r *Run, cfg config.Config, localPath, remoteName string) error {
+	_, err := uploadFiles(ctx, r, cfg, []sftpclient.File{{LocalPath: localPath, RemoteName: remoteName}})
+	return err
+}
+
+// uploadFiles sends files to the configured SFTP directory, in order and
+// over one connection, stopping at the first failure. Once started, an
+// upload is not cancelled with ctx (e.g. on SIGTERM): a half-written file in
+// the inbound directory is worse than a late exit. uploadTimeout
+// per file still bounds the batch.
+func uploadFiles(ctx context.Context, r *Run, cfg config.Config, files []sftpclient.File) ([]sftpclient.Result, error) {
+	for _, f := range files {
+		if _, err := os.Stat(f.LocalPath); err != nil {
+			return nil, fmt.Errorf("upload: %w", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	if err := validateFeeds(ctx, r, cfg, files); err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	files, err := encryptFiles(ctx, r, cfg, files)
+	if err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+
+	upCfg := sftpConfig(cfg)
+	upCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(len(files))*uploadTimeout)
+	defer cancel()
+
+	log := r.Log.With("component", "sftp", "host", upCfg.Host)
+	log.Info("uploading", "files", len(files))
+	results, err := sftpclient.UploadFiles(upCtx, upCfg, files)
+	for _, res := range results {
+		flog := log.With("file", res.LocalPath, "remote", path.Join(upCfg.RemoteDir, res.RemoteName))
+		switch {
+		case res.Err == nil:
+			flog.Info("uploaded", "bytes", res.Bytes, "duration", res.Duration)
+		case errors.Is(res.Err, sftpclient.ErrSkipped):
+			flog.Warn("not uploaded after an earlier failure")
+		default:
+			err = fmt.Errorf("upload %s: %w", res.LocalPath, res.Err)
+		}
+	}
+	// Record what did reach the server even when a later file failed.
+	if aerr := recordUploads(r, upCfg, cfg.ArchiveAuditLog, results); aerr != nil {
+		err = errors.Join(err, fmt.Errorf("upload: %w", aerr))
+	}
+	return results, err
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"course-sync/internal/config"
+)
+
+// configCheck layers the config file, environment variables and overrides
+// exactly like the other commands, resolves secret references, validates
+// the result and prints every setting (secrets redacted) with where it came
+// from. With -command it also checks that command's required settings.
+func configCheck(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	opts := config.RegisterFlags(fs, "")
+	opts.Profiles = profileNames()
+	fs.StringVar(&opts.Command, "command", "", "also check the requirements of this command: "+strings.Join(profileNames(), ", "))
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	return checkConfig(ctx, r.Stdout, *opts)
+}
+
+func checkConfig(ctx context.Context, w io.Writer, opts config.Options) error {
+	if opts.Command != "" {
+		if !slices.Contains(profileNames(), opts.Command) {
+			return fmt.Errorf("unknown command %q (known: %s)", opts.Command, strings.Join(profileNames(), ", "))
+		}
+	}
+
+	cfg, err := config.LoadContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		source := s.Source
+		if source == "" {
+			source = "unset"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, source)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if opts.Command != "" {
+		if err := cfg.Require(config.Requirements[opts.Command]...); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nconfig OK for %s\n", opts.Command)
+		return nil
+	}
+	fmt.Fprintln(w, "\nconfig OK")
+	return nil
+}
+
+// profileNames lists the config profiles of the command table, which are
+// also the -command values of `config check` and the commands.<name>
+// sections a config file may have.
+func profileNames() []string {
+	var names []string
+	for _, c := range commands {
+		if c.profile != "" && !slices.Contains(names, c.profile) {
+			names = append(names, c.profile)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"course-sync/internal/config"
+)
+
+func TestCheckPrintsRedactedConfig(t *testing.T) {
+	for _, k := range []string{"COURSE_SYNC_CONFIG", "COURSE_SYNC_ENV", "SFTP_DIR", "SFTP_PASS", "SFTP_HOST", "SFTP_USER"} {
+		t.Setenv(k, "")
+	}
+	path := filepath.Join(t.TempDir(), "course-sync.yaml")
+	err := os.WriteFile(path, []byte(`
+sftp:
+  host: sftp.test
+  user: svc
+environments:
+  sandbox:
+    sftp:
+      dir: /ef-sftp/femsa-sandbox/home/inbound
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SFTP_PASS", "hunter2")
+
+	var out bytes.Buffer
+	err = checkConfig(t.Context(), &out, config.Options{Path: path, Environment: "sandbox"})
+	if err != nil {
+		t.Fatalf("checkConfig() error: %v", err)
+	}
+	s := out.String()
+	if strings.Contains(s, "hunter2") {
+		t.Errorf("checkConfig() leaked a secret:\n%s", s)
+	}
+	for _, want := range []string{
+		"sftp.dir",
+		"/ef-sftp/femsa-sandbox/home/inbound",
+		"[environments.sandbox]",
+		"env SFTP_PASS",
+		"config OK",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected output to contain %q:\n%s", want, s)
+		}
+	}
+}
+
+func TestCheckCommandRequirements(t *testing.T) {
+	for _, k := range []string{"COURSE_SYNC_CONFIG", "COURSE_SYNC_ENV", "UDEMY_BASE_URL", "UDEMY_CLIENT_ID", "UDEMY_CLIENT_SECRET"} {
+		t.Setenv(k, "")
+	}
+
+	var out bytes.Buffer
+	if err := checkConfig(t.Context(), &out, config.Options{Command: "export-csv"}); err == nil || !strings.Contains(err.Error(), "udemy.base_url") {
+		t.Errorf("Expected missing udemy settings, got %v", err)
+	}
+	if err := checkConfig(t.Context(), &out, config.Options{Command: "nope"}); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("Expected unknown command error, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/config"
+	"course-sync/internal/domain"
+	"course-sync/internal/logging"
+	"course-sync/internal/providers/pluralsight"
+	"course-sync/internal/providers/udemy"
+	"course-sync/internal/tracing"
+)
+
+// catalogFlags are the provider paging flags shared by the course commands.
+type catalogFlags struct {
+	udemyPages int
+	psPages    int
+	pageSize   int
+}
+
+func (f *catalogFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.udemyPages, "udemy-max-pages", 1, "max pages to fetch from udemy (0 = all)")
+	fs.IntVar(&f.psPages, "ps-max-pages", 1, "max pages to fetch from pluralsight (0 = all)")
+	fs.IntVar(&f.pageSize, "page-size", 100, "page size for providers (Udemy page_size / Pluralsight first). Udemy will be clamped to its max.")
+}
+
+// tagFlags are the eligibility tag flags shared by the XML course commands.
+type tagFlags struct {
+	udemy       string
+	pluralsight string
+	operation   string
+}
+
+func (f *tagFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.udemy, "udemy-tags", "IC1,IC2,IC3,IC4", "eligibility tags for Udemy courses (comma-separated)")
+	fs.StringVar(&f.pluralsight, "pluralsight-tags", "IC5,IC6,IC7,M1,M2,M3", "eligibility tags for Pluralsight courses (comma-separated)")
+	fs.StringVar(&f.operation, "operation", "upsert", "EF_Course @operation attribute value (empty to omit)")
+}
+
+func (f *tagFlags) bySource() map[string][]string {
+	return map[string][]string{
+		"udemy":       splitCSV(f.udemy),
+		"pluralsight": splitCSV(f.pluralsight),
+	}
+}
+
+// exportLangs are the course languages exported to Eightfold.
+var exportLangs = map[string]bool{
+	"es": true,
+	"en": true,
+	"pt": true,
+}
+
+// fetchCatalogs lists Udemy and Pluralsight in parallel. A failing provider
+// is logged and its partial result kept, so one outage does not block the
+// other catalog. It returns the merged courses, the count per provider and
+// the providers whose listing may be incomplete: those that failed or were
+// capped by a page limit. Courses missing from such a listing must not be
+// taken for deleted.
+func fetchCatalogs(ctx context.Context, r *Run, cfg config.Config, f catalogFlags) (all []domain.UnifiedCourse, totals map[string]int, incomplete []string) {
+	type provResult struct {
+		name    string
+		courses []domain.UnifiedCourse
+		err     error
+	}
+	resultsCh := make(chan provResult, 2)
+
+	fetch := func(name string, list func(context.Context) ([]domain.UnifiedCourse, error)) {
+		ctx, span := tracer.Start(ctx, "catalog.fetch", trace.WithAttributes(attribute.String("provider", name)))
+		courses, err := list(ctx)
+		span.SetAttributes(attribute.Int("courses", len(courses)))
+		tracing.End(span, &err)
+		resultsCh <- provResult{name: name, courses: courses, err: err}
+	}
+
+	udProv := udemy.Provider{C: newUdemy(r, cfg), PageSize: f.pageSize, MaxPages: f.udemyPages}
+	psProv := pluralsight.Provider{C: newPluralsight(r, cfg), First: f.pageSize, MaxPages: f.psPages}
+	go fetch("udemy", udProv.ListCourses)
+	go fetch("pluralsight", psProv.ListCourses)
+
+	maxPages := map[string]int{"udemy": f.udemyPages, "pluralsight": f.psPages}
+	totals = map[string]int{}
+	for i := 0; i < 2; i++ {
+		res := <-resultsCh
+		totals[res.name] = len(res.courses)
+		coursesFetched.Add(float64(len(res.courses)), res.name)
+		if res.err != nil {
+			providerErrors.Inc(res.name)
+			// keep partial results
+			r.Log.Warn("catalog fetch failed; using partial results", "provider", res.name, "courses", len(res.courses), logging.Err(res.err))
+		}
+		if res.err != nil || maxPages[res.name] > 0 {
+			incomplete = append(incomplete, res.name)
+		}
+		all = append(all, res.courses...)
+	}
+	slices.Sort(incomplete)
+	return all, totals, incomplete
+}
+
+func filterCoursesByLang(courses []domain.UnifiedCourse, allowed map[string]bool) []domain.UnifiedCourse {
+	out := make([]domain.UnifiedCourse, 0, len(courses))
+	for _, c := range courses {
+		lang := normalizeLang(c.Language)
+		if allowed[lang] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// normalizeLang maps a provider language ("English", "es_MX", "pt-BR") to
+// its two-letter primary subtag.
+func normalizeLang(lang string) string {
+	s := strings.TrimSpace(strings.ToLower(lang))
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "_", "-")
+
+	switch s {
+	case "english":
+		return "en"
+	case "spanish", "español", "espanol":
+		return "es"
+	case "portuguese", "português", "portugues":
+		return "pt"
+	}
+
+	if len(s) >= 2 {
+		return s[:2]
+	}
+	return s
+}
+
+// splitCSV splits a comma-separated string into a slice of strings,
+// trimming whitespace and removing empty entries.
+func splitCSV(s string) []string {
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		v := strings.TrimSpace(p)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
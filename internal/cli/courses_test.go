@@ -1,9 +1,10 @@
-package main
+package cli
 
 import (
-	"course-sync/internal/domain"
 	"reflect"
 	"testing"
+
+	"course-sync/internal/domain"
 )
 
 func TestFilterCoursesByLang(t *testing.T) {
@@ -19,14 +20,14 @@ func TestFilterCoursesByLang(t *testing.T) {
 			Source:        "udemy",
 			SourceID:      "2",
 			Title:         "English Course",
-			Language:      "en",
+			Language:      "en-US",
 			DurationHours: 2.0,
 		},
 		{
 			Source:        "pluralsight",
 			SourceID:      "3",
 			Title:         "Portuguese Course",
-			Language:      "pt",
+			Language:      "Portuguese",
 			DurationHours: 3.0,
 		},
 		{
@@ -48,12 +49,15 @@ func TestFilterCoursesByLang(t *testing.T) {
 	if len(filtered) != 2 {
 		t.Errorf("Expected 2 courses, got %d", len(filtered))
 	}
-
 	for _, c := range filtered {
-		if c.Language != "es" && c.Language != "en" {
-			t.Errorf("Unexpected language %s in filtered courses", c.Language)
+		if c.SourceID != "1" && c.SourceID != "2" {
+			t.Errorf("Unexpected course %s (%s) in filtered courses", c.SourceID, c.Language)
 		}
 	}
+
+	if got := filterCoursesByLang(courses, exportLangs); len(got) != 3 {
+		t.Errorf("Expected 3 courses in the export languages, got %d", len(got))
+	}
 }
 
 func TestNormalizeLang(t *testing.T) {
@@ -64,17 +68,20 @@ func TestNormalizeLang(t *testing.T) {
 		{"en", "en"},
 		{"EN", "en"},
 		{"en-US", "en"},
+		{"en_US", "en"},
 		{"english", "en"},
 		{"English", "en"},
 		{"es", "es"},
 		{"ES", "es"},
 		{"es-MX", "es"},
+		{"es_MX", "es"},
 		{"spanish", "es"},
 		{"español", "es"},
 		{"espanol", "es"},
 		{"pt", "pt"},
 		{"PT", "pt"},
 		{"pt-BR", "pt"},
+		{"pt_BR", "pt"},
 		{"portuguese", "pt"},
 		{"português", "pt"},
 		{"portugues", "pt"},
@@ -84,7 +91,11 @@ func TestNormalizeLang(t *testing.T) {
 		{"french", "fr"},
 		{"", ""},
 		{"  ", ""},
+		{"de", "de"},
+		{"de-DE", "de"},
 		{"de_DE", "de"},
+		{"it-IT", "it"},
+		{"ja-JP", "ja"},
 	}
 
 	for _, tc := range testCases {
@@ -119,3 +130,11 @@ func TestSplitCSV(t *testing.T) {
 		}
 	}
 }
+
+func TestTagFlagsBySource(t *testing.T) {
+	f := tagFlags{udemy: "IC1, IC2", pluralsight: ""}
+	want := map[string][]string{"udemy": {"IC1", "IC2"}, "pluralsight": {}}
+	if got := f.bySource(); !reflect.DeepEqual(got, want) {
+		t.Errorf("bySource() = %v, want %v", got, want)
+	}
+}
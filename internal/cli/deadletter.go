@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"course-sync/internal/atomicfile"
+	"course-sync/internal/httpx"
+)
+
+// deadLetterDefault is where sync employees records the employees it could
+// not sync, and where replay reads them back.
+const deadLetterDefault = "out/employees_dead_letter.jsonl"
+
+// Dead-letter stages: where syncing an employee failed.
+const (
+	stageInput       = "input"       // the Eightfold record has no email or id; not replayable
+	stagePluralsight = "pluralsight" // Pluralsight user or progress lookup
+	stageUdemy       = "udemy"       // Udemy user or progress lookup
+	stageUpdate      = "update"      // the PATCH to Eightfold
+)
+
+// errMissingIdentity marks Eightfold records without an email or id.
+var errMissingIdentity = errors.New("missing email or id")
+
+// DeadLetter is one line of the dead-letter file: a failed stage of one
+// employee, with the data needed to retry it. Payload is the employee record
+// from Eightfold for the input and provider stages, and the update request
+// for the update stage.
+type DeadLetter struct {
+	Time      time.Time       `json:"time"`
+	RunID     string          `json:"run_id"`
+	Stage     string          `json:"stage"`
+	Class     string          `json:"class"`
+	Error     string          `json:"error"`
+	ProfileID string          `json:"profile_id,omitempty"`
+	Email     string          `json:"email,omitempty"`
+	Attempts  int             `json:"attempts"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+func newDeadLetter(runID, stage string, err error, profileID, email string, payload any) DeadLetter {
+	dl := DeadLetter{
+		Time:      time.Now().UTC(),
+		RunID:     runID,
+		Stage:     stage,
+		Class:     errorClass(err),
+		Error:     err.Error(),
+		ProfileID: profileID,
+		Email:     email,
+		Attempts:  1,
+	}
+	if payload != nil {
+		if b, merr := json.Marshal(payload); merr == nil && string(b) != "null" {
+			dl.Payload = b
+		}
+	}
+	return dl
+}
+
+// errorClass buckets err for the dead-letter file, so that e.g. rate limits
+// can be told apart from bad data without reading every message.
+func errorClass(err error) string {
+	var (
+		herr *httpx.HTTPError
+		nerr net.Error
+	)
+	switch {
+	case errors.Is(err, errMissingIdentity):
+		return "invalid_input"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &herr):
+		switch {
+		case herr.StatusCode == http.StatusTooManyRequests:
+			return "rate_limited"
+		case herr.StatusCode == http.StatusUnauthorized || herr.StatusCode == http.StatusForbidden:
+			return "auth"
+		case herr.StatusCode == http.StatusNotFound:
+			return "not_found"
+		case herr.StatusCode >= 500:
+			return "server_error"
+		default:
+			return "client_error"
+		}
+	case errors.As(err, &nerr):
+		if nerr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// deadLetterFile appends the dead letters of a run. A nil *deadLetterFile
+// discards them (dry runs, or -dead-letter "").
+type deadLetterFile struct {
+	f   *os.File
+	enc *json.Encoder
+	n   int
+}
+
+// createDeadLetters truncates path, or appends to it when the run resumes an
+// earlier one: the file always holds the failures of the latest sync
+// employees run, plus what replay could not fix since.
+func createDeadLetters(path string, resume bool) (*deadLetterFile, error) {
+	if err := ensureDir(path); err != nil {
+		return nil, err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetterFile{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (d *deadLetterFile) add(dl DeadLetter) error {
+	deadLetters.Inc(dl.Stage)
+	if d == nil {
+		return nil
+	}
+	d.n++
+	return d.enc.Encode(dl)
+}
+
+func (d *deadLetterFile) Close() error {
+	if d == nil {
+		return nil
+	}
+	return d.f.Close()
+}
+
+// readDeadLetters returns the entries in path in file order. A missing file
+// has no entries; lines that do not parse are skipped, as in readHistory.
+func readDeadLetters(path string) ([]DeadLetter, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []DeadLetter
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var dl DeadLetter
+		if err := json.Unmarshal(sc.Bytes(), &dl); err != nil {
+			continue
+		}
+		out = append(out, dl)
+	}
+	return out, sc.Err()
+}
+
+// writeDeadLetters replaces path with entries, atomically so an interrupted
+// replay never loses the entries it has not retried.
+func writeDeadLetters(path string, entries []DeadLetter) error {
+	return atomicfile.Write(path, 0o644, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, dl := range entries {
+			if err := enc.Encode(dl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"course-sync/internal/httpx"
+	"course-sync/internal/providers/eightfold"
+)
+
+func TestErrorClass(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{errMissingIdentity, "invalid_input"},
+		{fmt.Errorf("udemy: %w", context.DeadlineExceeded), "timeout"},
+		{context.Canceled, "canceled"},
+		{&httpx.HTTPError{StatusCode: 429}, "rate_limited"},
+		{fmt.Errorf("eightfold: %w", &httpx.HTTPError{StatusCode: 401}), "auth"},
+		{&httpx.HTTPError{StatusCode: 404}, "not_found"},
+		{&httpx.HTTPError{StatusCode: 422}, "client_error"},
+		{&httpx.HTTPError{StatusCode: 502}, "server_error"},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "network"},
+		{errors.New("pluralsight gql errors: boom"), "other"},
+	}
+
+	for _, tc := range testCases {
+		if got := errorClass(tc.err); got != tc.want {
+			t.Errorf("errorClass(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestDeadLettersRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "dead.jsonl")
+
+	d, err := createDeadLetters(path, false)
+	if err != nil {
+		t.Fatalf("createDeadLetters() error: %v", err)
+	}
+	req := employeeUpdate("jane@example.com", []eightfold.CourseAttendance{{LmsCourseID: "101", Provider: "Udemy"}})
+	d.add(newDeadLetter("run1", stageUpdate, &httpx.HTTPError{StatusCode: 503}, "p1", "jane@example.com", req))
+	d.add(newDeadLetter("run1", stageInput, errMissingIdentity, "", "", map[string]any{"id": ""}))
+	d.add(newDeadLetter("run1", stageUdemy, errors.New("boom"), "p2", "joe@example.com", nil))
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readDeadLetters(path)
+	if err != nil {
+		t.Fatalf("readDeadLetters() error: %v", err)
+	}
+	if len(got) != 3 || got[0].Stage != stageUpdate || got[0].Class != "server_error" || got[0].Attempts != 1 {
+		t.Fatalf("Unexpected entries: %+v", got)
+	}
+	if string(got[0].Payload) == "" || got[2].Payload != nil {
+		t.Errorf("payloads = %s / %s, want the request and none", got[0].Payload, got[2].Payload)
+	}
+
+	// The file is replaced by writeDeadLetters and truncated by the next run.
+	if err := writeDeadLetters(path, got[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readDeadLetters(path); len(got) != 1 || got[0].Stage != stageInput {
+		t.Errorf("after writeDeadLetters: %+v", got)
+	}
+	d, _ = createDeadLetters(path, false)
+	d.Close()
+	if got, _ := readDeadLetters(path); len(got) != 0 {
+		t.Errorf("Expected createDeadLetters to truncate, got %+v", got)
+	}
+
+	var none *deadLetterFile
+	if err := none.add(newDeadLetter("run1", stageUdemy, errors.New("boom"), "p", "e", nil)); err != nil || none.Close() != nil {
+		t.Error("Expected a nil deadLetterFile to discard entries")
+	}
+}
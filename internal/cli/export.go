@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/export"
+	"course-sync/internal/tracing"
+)
+
+// exportCSV writes the merged provider catalog as the Eightfold course CSV.
+func exportCSV(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		outPath = fs.String("out", "out/COURSE-MAIN_ALL.csv", "output csv path")
+		upload  = fs.Bool("upload", false, "upload to SFTP after generating the file")
+		timeout = fs.Duration("timeout", 6*time.Hour, "overall time limit for the run")
+		cat     catalogFlags
+	)
+	cat.register(fs)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := r.config(ctx, uploadSections(*upload)...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	if err := ensureDir(*outPath); err != nil {
+		return err
+	}
+
+	all, totals, _ := fetchCatalogs(ctx, r, cfg, cat)
+	filtered := filterCoursesByLang(all, exportLangs)
+
+	// Eligibility tags are not sent in the CSV feed for now.
+	if err := writeFile(ctx, *outPath, len(filtered), func() error {
+		return export.WriteEightfoldCourseCSV(*outPath, filtered, export.CourseTagConfig{})
+	}); err != nil {
+		return err
+	}
+	if err := archiveFiles(r, cfg, *outPath); err != nil {
+		return err
+	}
+
+	r.Log.Info("wrote courses", "courses", len(filtered), "out", *outPath,
+		"udemy", totals["udemy"], "pluralsight", totals["pluralsight"], "merged", len(all))
+	r.Summary["courses"] = len(filtered)
+	r.Summary["udemy"] = totals["udemy"]
+	r.Summary["pluralsight"] = totals["pluralsight"]
+	r.Summary["out"] = *outPath
+
+	if *upload {
+		// Eightfold picks the CSV feed up by name: DF_COURSE_IMPORT_AAAAMMDD_HHMMSS.csv
+		remoteName := fmt.Sprintf("DF_COURSE_IMPORT_%s.csv", time.Now().Format("20060102_150405"))
+		if err := uploadFile(ctx, r, cfg, *outPath, remoteName); err != nil {
+			return err
+		}
+		r.Summary["uploaded"] = remoteName
+	}
+	return nil
+}
+
+// exportXML writes the merged provider catalog as Eightfold ef_course XML.
+func exportXML(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		outPath = fs.String("out", "out/ef_course_add.xml", "output xml path (Eightfold ef_course_add/update format)")
+		upload  = fs.Bool("upload", false, "upload to SFTP after generating the file")
+		timeout = fs.Duration("timeout", 6*time.Hour, "overall time limit for the run")
+		cat     catalogFlags
+		tags    tagFlags
+	)
+	cat.register(fs)
+	tags.register(fs)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := r.config(ctx, uploadSections(*upload)...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	if err := ensureDir(*outPath); err != nil {
+		return err
+	}
+
+	all, totals, _ := fetchCatalogs(ctx, r, cfg, cat)
+	filtered := filterCoursesByLang(all, exportLangs)
+
+	tagCfg := export.CourseTagConfig{
+		Operation:                strings.TrimSpace(tags.operation),
+		EligibilityTagsFieldName: "eligibility_tags",
+		TagsBySource:             tags.bySource(),
+	}
+	if err := writeFile(ctx, *outPath, len(filtered), func() error {
+		return export.WriteEFCourseXML(*outPath, filtered, tagCfg)
+	}); err != nil {
+		return err
+	}
+	if err := archiveFiles(r, cfg, *outPath); err != nil {
+		return err
+	}
+
+	r.Log.Info("wrote courses", "courses", len(filtered), "out", *outPath,
+		"udemy", totals["udemy"], "pluralsight", totals["pluralsight"], "merged", len(all))
+	r.Summary["courses"] = len(filtered)
+	r.Summary["udemy"] = totals["udemy"]
+	r.Summary["pluralsight"] = totals["pluralsight"]
+	r.Summary["out"] = *outPath
+
+	if *upload {
+		remoteName := filepath.Base(*outPath)
+		if err := uploadFile(ctx, r, cfg, *outPath, remoteName); err != nil {
+			return err
+		}
+		r.Summary["uploaded"] = remoteName
+	}
+	return nil
+}
+
+func uploadSections(upload bool) []string {
+	if upload {
+		return []string{"sftp"}
+	}
+	return nil
+}
+
+// ensureDir creates the parent directory of path.
+func ensureDir(path string) error {
+	if dir := filepath.Dir(path); dir != "." && dir != "" {
+		return os.MkdirAll(dir, 0o755)
+	}
+	return nil
+}
+
+// writeFile runs write, which writes records records to path, in an
+// export.write span.
+func writeFile(ctx context.Context, path string, records int, write func() error) (err error) {
+	_, span := tracer.Start(ctx, "export.write", trace.WithAttributes(
+		attribute.String("file.path", path),
+		attribute.Int("records", records),
+	))
+	defer tracing.End(span, &err)
+	if err = write(); err != nil {
+		return err
+	}
+	if fi, serr := os.Stat(path); serr == nil {
+		span.SetAttributes(attribute.Int64("file.size", fi.Size()))
+	}
+	return nil
+}
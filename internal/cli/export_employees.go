@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"course-sync/internal/domain"
+	"course-sync/internal/export"
+)
+
+// exportEmployees writes every Eightfold employee as EF_Employee_List XML.
+func exportEmployees(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		outPath  = fs.String("out", "out/ef_emp_update.xml", "output xml path (Eightfold EF_Employee_List format)")
+		upload   = fs.Bool("upload", false, "upload to SFTP after generating the file")
+		pageSize = fs.Int("page-size", 100, "page size for Eightfold employees endpoint (max 100)")
+		timeout  = fs.Duration("timeout", 6*time.Hour, "overall time limit for the run")
+
+		fieldName  = fs.String("field", "course_eligibility_tags", "custom_info field_name to set")
+		badgeMerge = fs.String("badge-merge-strategy", "latest", "EF_Employee_List @badge_merge_strategy (empty to omit)")
+	)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *pageSize > 100 {
+		r.Log.Warn("page-size above the Eightfold limit, capping to 100", "page_size", *pageSize)
+		*pageSize = 100
+	}
+
+	cfg, err := r.config(ctx, uploadSections(*upload)...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	if err := ensureDir(*outPath); err != nil {
+		return err
+	}
+
+	ef, err := newEightfold(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+
+	employees, err := ef.ListAllEmployees(ctx, *pageSize)
+	if err != nil {
+		return err
+	}
+
+	emps := make([]domain.UnifiedEmployee, 0, len(employees))
+	missingID := 0
+	for _, e := range employees {
+		eid := e.EmployeeID
+		if eid == "" {
+			// Some tenants only expose user_id as the primary key.
+			// We still keep the row, but count it so it's visible.
+			missingID++
+			eid = e.UserID
+		}
+
+		emps = append(emps, domain.UnifiedEmployee{
+			EmployeeID: eid,
+			UserID:     e.UserID,
+			Level:      e.Level,
+			Emails:     e.Emails,
+		})
+	}
+
+	if missingID > 0 {
+		r.Log.Warn("employees without employee_id, used user_id instead", "count", missingID)
+	}
+
+	xCfg := export.EmployeeTagConfig{
+		BadgeMergeStrategy: strings.TrimSpace(*badgeMerge),
+		FieldName:          strings.TrimSpace(*fieldName),
+	}
+	if err := writeFile(ctx, *outPath, len(emps), func() error {
+		return export.WriteEFEmployeeUpdateXML(*outPath, emps, xCfg)
+	}); err != nil {
+		return err
+	}
+	if err := archiveFiles(r, cfg, *outPath); err != nil {
+		return err
+	}
+
+	r.Log.Info("wrote employees", "employees", len(emps), "out", *outPath)
+	r.Summary["employees"] = len(emps)
+	r.Summary["missing_employee_id"] = missingID
+	r.Summary["out"] = *outPath
+
+	if *upload {
+		remoteName := filepath.Base(*outPath)
+		if err := uploadFile(ctx, r, cfg, *outPath, remoteName); err != nil {
+			return err
+		}
+		r.Summary["uploaded"] = remoteName
+	}
+	return nil
+}
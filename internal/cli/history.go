@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Run history: every sync/export/upload run appends one JSON line to
+// $COURSE_SYNC_HISTORY (default out/history.jsonl).
+const (
+	historyEnv         = "COURSE_SYNC_HISTORY"
+	defaultHistoryPath = "out/history.jsonl"
+)
+
+// HistoryRecord is one line of the run history.
+type HistoryRecord struct {
+	ID       string         `json:"id"`
+	Command  string         `json:"command"`
+	Start    time.Time      `json:"start"`
+	End      time.Time      `json:"end"`
+	Duration string         `json:"duration"`
+	Status   string         `json:"status"` // "ok", "error" or "skipped" (locked by another run)
+	Error    string         `json:"error,omitempty"`
+	Summary  map[string]any `json:"summary,omitempty"`
+}
+
+func historyPath() string {
+	if p := os.Getenv(historyEnv); p != "" {
+		return p
+	}
+	return defaultHistoryPath
+}
+
+func appendHistory(path string, rec HistoryRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readHistory returns the records in path, oldest first. Lines that do not
+// parse are skipped so a torn write does not hide the rest of the history.
+func readHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []HistoryRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var rec HistoryRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, sc.Err()
+}
+
+func history(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		path    = fs.String("file", historyPath(), "history file (default $"+historyEnv+" or "+defaultHistoryPath+")")
+		limit   = fs.Int("n", 20, "number of most recent runs to show (0 = all)")
+		command = fs.String("command", "", `only show runs of this command, e.g. "sync courses"`)
+		asJSON  = fs.Bool("json", false, "print the records as JSON lines")
+	)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	recs, err := readHistory(*path)
+	if err != nil {
+		return err
+	}
+	if *command != "" {
+		filtered := recs[:0]
+		for _, rec := range recs {
+			if rec.Command == *command {
+				filtered = append(filtered, rec)
+			}
+		}
+		recs = filtered
+	}
+	if *limit > 0 && len(recs) > *limit {
+		recs = recs[len(recs)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(r.Stdout)
+		for _, rec := range recs {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(r.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "START\tCOMMAND\tSTATUS\tDURATION\tID\tSUMMARY")
+	for _, rec := range recs {
+		status := rec.Status
+		if rec.Error != "" {
+			status += ": " + rec.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			rec.Start.Local().Format("2006-01-02 15:04:05"), rec.Command, status, rec.Duration, rec.ID, formatSummary(rec.Summary))
+	}
+	return tw.Flush()
+}
+
+func formatSummary(m map[string]any) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	s := ""
+	for i, k := range keys {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("%s=%v", k, m[k])
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errLocked is returned when another run of the same command holds the lock.
+var errLocked = errors.New("another run is in progress")
+
+// lockPath returns the absolute path of the lock named name in dir (the
+// lock.dir setting), so that the lock file a run reports does not depend
+// on its working directory.
+func lockPath(dir, name string) (string, error) {
+	path, err := filepath.Abs(filepath.Join(dir, name+".lock"))
+	if err != nil {
+		return "", fmt.Errorf("lock: %w", err)
+	}
+	return path, nil
+}
+
+// staleLockAge is how old a lock file that does not hold a pid (left by a
+// crash, or by a version that wrote the pid after creating the file) must
+// be before it is taken over.
+const staleLockAge = time.Minute
+
+// acquireLock creates path, recording our pid, so that runs started by
+// `serve`, by cron or by hand never overlap. A lock left behind by a
+// process that no longer exists is taken over.
+func acquireLock(path string) (release func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("lock: %w", err)
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		err := createLock(path)
+		if err == nil {
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+		if err := checkLock(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		// Stale: the holder died without cleaning up.
+		if err := removeStaleLock(path); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w (lock %s)", errLocked, path)
+}
+
+// checkLock returns nil if the lock at path is stale, and errLocked if it
+// is held.
+func checkLock(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	pid, since, ok := readLock(path)
+	switch {
+	case ok && processAlive(pid):
+		return fmt.Errorf("%w (pid %d since %s, lock %s)", errLocked, pid, since, path)
+	case !ok && time.Since(fi.ModTime()) < staleLockAge:
+		return fmt.Errorf("%w (unreadable lock %s)", errLocked, path)
+	}
+	return nil
+}
+
+// createLock writes our pid to a temporary file and links it to path, so
+// that the lock never exists without its content. It fails with
+// os.ErrExist when path exists.
+func createLock(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprintf(tmp, "%d %s\n", os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), path)
+}
+
+// removeStaleLock removes path if it is (still) stale. Takeovers are
+// serialised by a second lock, and the lock is checked again under it, so
+// that a process that judged the same lock stale cannot remove the one
+// that replaced it.
+func removeStaleLock(path string) error {
+	guard := path + ".takeover"
+	if err := createLock(guard); err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("lock: %w", err)
+		}
+		// Another process is taking over; a guard left by a crash is
+		// removed for the next attempt.
+		if gi, err := os.Stat(guard); err == nil && time.Since(gi.ModTime()) >= staleLockAge {
+			os.Remove(guard)
+		}
+		return nil
+	}
+	defer os.Remove(guard)
+
+	if checkLock(path) != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("lock: remove stale %s: %w", path, err)
+	}
+	return nil
+}
+
+func readLock(path string) (pid int, since string, ok bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return 0, "", false
+	}
+	pid, err = strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return 0, "", false
+	}
+	return pid, fields[1], true
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "sync-courses.lock")
+
+	release, err := acquireLock(path)
+	if err != nil {
+		t.Fatalf("acquireLock() error: %v", err)
+	}
+	if _, err := acquireLock(path); !errors.Is(err, errLocked) {
+		t.Errorf("Expected errLocked while held, got %v", err)
+	}
+
+	release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected release to remove the lock file, stat error: %v", err)
+	}
+	release2, err := acquireLock(path)
+	if err != nil {
+		t.Fatalf("acquireLock() after release error: %v", err)
+	}
+	release2()
+}
+
+func TestAcquireLockTakesOverStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * staleLockAge)
+	for name, content := range map[string]string{
+		"dead.lock":    "2147483646 2026-01-01T00:00:00Z\n", // no such process
+		"garbage.lock": "not a lock",
+		"empty.lock":   "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "dead.lock" {
+			// Only old enough to be the leftover of a crash.
+			if _, err := acquireLock(path); !errors.Is(err, errLocked) {
+				t.Errorf("Expected a fresh %s to count as held, got %v", name, err)
+			}
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+		release, err := acquireLock(path)
+		if err != nil {
+			t.Errorf("acquireLock(%s) error: %v", name, err)
+			continue
+		}
+		if pid, _, ok := readLock(path); !ok || pid != os.Getpid() {
+			t.Errorf("Expected our pid in %s, got %d", name, pid)
+		}
+		release()
+	}
+}
+
+// TestRemoveStaleLockKeepsNewLock checks that a lock judged stale is not
+// removed once another process has replaced it.
+func TestRemoveStaleLockKeepsNewLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-courses.lock")
+	if err := os.WriteFile(path, []byte("2147483646 2026-01-01T00:00:00Z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkLock(path); err != nil {
+		t.Fatalf("Expected a stale lock, got %v", err)
+	}
+	// Another process takes the lock over first.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	release, err := acquireLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	if err := removeStaleLock(path); err != nil {
+		t.Fatalf("removeStaleLock() error: %v", err)
+	}
+	if _, err := acquireLock(path); !errors.Is(err, errLocked) {
+		t.Errorf("Expected the new lock to survive, got %v", err)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) != 0 {
+		t.Errorf("Expected no temporary or guard files left, got %v", matches)
+	}
+}
+
+func TestLockedRunIsSkipped(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("COURSE_SYNC_LOCK_DIR", "locks")
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+
+	path, err := lockPath(filepath.Join(dir, "locks"), "sync-courses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := acquireLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// Everything that writes the course feeds or the SFTP inbound
+	// directory shares the sync courses lock.
+	runs := [][]string{
+		{"sync", "courses", "-mock-dir", dir},
+		{"export", "csv"},
+		{"export", "employees"},
+		{"upload", "ef_course_add.xml"},
+		{"sftp", "delete", "ef_course_add.xml"},
+	}
+	for _, args := range runs {
+		cmd, rest, _ := lookup(args)
+		if _, err := execute(t.Context(), cmd, rest, io.Discard, io.Discard); !errors.Is(err, errLocked) || !strings.Contains(err.Error(), path) {
+			t.Errorf("%v: expected errLocked on %s, got %v", args, path, err)
+		}
+	}
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if len(recs) != len(runs) {
+		t.Fatalf("Expected %d history records, got %+v", len(runs), recs)
+	}
+	for _, rec := range recs {
+		if rec.Status != "skipped" {
+			t.Errorf("Expected a skipped history record, got %+v", rec)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"course-sync/internal/logging"
+	"course-sync/internal/metrics"
+)
+
+// metricsDirEnv points at the node_exporter textfile collector directory.
+// When set, every recorded run writes course_sync_<profile>.prom there.
+const metricsDirEnv = "COURSE_SYNC_METRICS_DIR"
+
+var (
+	coursesFetched = metrics.NewCounter("course_sync_courses_fetched_total",
+		"Courses fetched from each provider.", "provider")
+	providerErrors = metrics.NewCounter("course_sync_provider_errors_total",
+		"Provider catalog fetches that failed (partial results are still used).", "provider")
+	diffCourses = metrics.NewCounter("course_sync_diff_courses_total",
+		"Courses to create, update or delete in Eightfold, by action.", "action")
+	employeesTotal = metrics.NewCounter("course_sync_employees_total",
+		"Employees handled by sync employees, by result (processed, updated, skipped, errored).", "result")
+	deadLetters = metrics.NewCounter("course_sync_dead_letters_total",
+		"Employees written to the dead-letter file, by failed stage (input, pluralsight, udemy, update).", "stage")
+	replayEntries = metrics.NewCounter("course_sync_replay_entries_total",
+		"Dead-letter entries handled by replay, by result (resolved, failed, skipped).", "result")
+
+	runsTotal = metrics.NewCounter("course_sync_runs_total",
+		"Command runs by status (ok, error, skipped).", "command", "status")
+	runDuration = metrics.NewGauge("course_sync_last_run_duration_seconds",
+		"Duration of the last run of each command.", "command")
+	lastSuccess = metrics.NewGauge("course_sync_last_success_timestamp_seconds",
+		"Unix time of the last successful run of each command.", "command")
+)
+
+// recordRunMetrics updates the run metrics and, for one-shot runs with
+// $COURSE_SYNC_METRICS_DIR set, writes them out for the textfile collector.
+// The last success of a command is seeded from the run history, so a failed
+// run does not make it disappear from the file.
+func recordRunMetrics(r *Run, rec HistoryRecord) {
+	runsTotal.Inc(rec.Command, rec.Status)
+	runDuration.Set(rec.End.Sub(rec.Start).Seconds(), rec.Command)
+	if rec.Status == "ok" {
+		lastSuccess.Set(float64(rec.End.Unix()), rec.Command)
+	} else if _, ok := lastSuccess.Lookup(rec.Command); !ok {
+		if t := lastSuccessFromHistory(rec.Command); !t.IsZero() {
+			lastSuccess.Set(float64(t.Unix()), rec.Command)
+		}
+	}
+
+	dir := os.Getenv(metricsDirEnv)
+	if dir == "" {
+		return
+	}
+	path := filepath.Join(dir, "course_sync_"+r.Profile+".prom")
+	if err := metrics.Default.WriteFile(path, metrics.Label{Name: "profile", Value: r.Profile}); err != nil {
+		r.Log.Warn("could not write metrics", logging.Err(err))
+	}
+}
+
+func lastSuccessFromHistory(command string) time.Time {
+	recs, err := readHistory(historyPath())
+	if err != nil {
+		return time.Time{}
+	}
+	for i := len(recs) - 1; i >= 0; i-- {
+		if recs[i].Command == command && recs[i].Status == "ok" {
+			return recs[i].End
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/config"
+	"course-sync/internal/pgp"
+	"course-sync/internal/sftpclient"
+	"course-sync/internal/tracing"
+)
+
+func pgpConfig(cfg config.Config) pgp.Config {
+	return pgp.Config{
+		Recipients:        cfg.PGPRecipientKeyFiles(),
+		SigningKey:        cfg.PGPSigningKey,
+		SigningPassphrase: cfg.PGPSigningPassphrase,
+		Armor:             cfg.PGPArmor,
+	}
+}
+
+// encryptFiles encrypts each file of an upload batch to <file>.pgp (or
+// .asc) next to it when pgp.encrypt is set, and returns the batch to send
+// instead, with the suffix added to the remote names too. Files that
+// already have an OpenPGP suffix are sent as they are. The encrypted files
+// are archived with the run, since they are what Eightfold receives.
+func encryptFiles(ctx context.Context, r *Run, cfg config.Config, files []sftpclient.File) (out []sftpclient.File, err error) {
+	if !cfg.PGPEncrypt {
+		return files, nil
+	}
+	pc := pgpConfig(cfg)
+	_, span := tracer.Start(ctx, "pgp.encrypt", trace.WithAttributes(
+		attribute.Int("files", len(files)),
+		attribute.Int("recipients", len(pc.Recipients)),
+		attribute.Bool("signed", pc.SigningKey != ""),
+	))
+	defer tracing.End(span, &err)
+
+	enc, err := pgp.NewEncrypter(pc)
+	if err != nil {
+		return nil, err
+	}
+	out = make([]sftpclient.File, len(files))
+	for i, f := range files {
+		out[i] = f
+		if pgp.Encrypted(f.LocalPath) {
+			continue
+		}
+		out[i] = sftpclient.File{LocalPath: f.LocalPath + pc.Ext(), RemoteName: f.RemoteName + pc.Ext()}
+		if err := enc.EncryptFile(f.LocalPath, out[i].LocalPath); err != nil {
+			return nil, err
+		}
+		if err := archiveFiles(r, cfg, out[i].LocalPath); err != nil {
+			return nil, err
+		}
+		r.Log.Info("encrypted", "component", "pgp", "file", f.LocalPath, "out", out[i].LocalPath, "signed", enc.Signed())
+	}
+	return out, nil
+}
+
+// pgpKeygen writes a new key pair, e.g. to try the encryption end to end
+// without the real Eightfold key.
+func pgpKeygen(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		name  = fs.String("name", "course-sync", "key owner name")
+		email = fs.String("email", "", "key owner email")
+		bits  = fs.Int("bits", 3072, "RSA key size")
+		out   = fs.String("out", "", "write OUT.pub.asc (public key) and OUT.key.asc (private key, unprotected)")
+	)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if *out == "" || *bits < 2048 {
+		fmt.Fprintln(fs.Output(), "-out is required and -bits must be at least 2048")
+		return errUsage
+	}
+	e, err := pgp.GenerateKey(*name, *email, *bits)
+	if err != nil {
+		return err
+	}
+	if err := ensureDir(*out); err != nil {
+		return err
+	}
+	if err := writeKeyFile(*out+".pub.asc", 0o644, func(w io.Writer) error { return pgp.WritePublicKey(w, e) }); err != nil {
+		return err
+	}
+	if err := writeKeyFile(*out+".key.asc", 0o600, func(w io.Writer) error { return pgp.WritePrivateKey(w, e) }); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Stdout, "public key:  %s.pub.asc\nprivate key: %s.key.asc\nfingerprint: %s\n", *out, *out, pgp.Fingerprint(e))
+	return nil
+}
+
+// writeKeyFile creates path, which must not exist yet, with perm.
+func writeKeyFile(path string, perm os.FileMode, write func(io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// pgpEncrypt encrypts a file with the pgp settings, as uploads do.
+func pgpEncrypt(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	out := fs.String("o", "", "output file (default: FILE.pgp, or FILE.asc with pgp.armor)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync pgp encrypt [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	pc := pgpConfig(cfg)
+	enc, err := pgp.NewEncrypter(pc)
+	if err != nil {
+		return err
+	}
+	dst := *out
+	if dst == "" {
+		dst = fs.Arg(0) + pc.Ext()
+	}
+	if err := enc.EncryptFile(fs.Arg(0), dst); err != nil {
+		return err
+	}
+	r.Log.Info("encrypted", "component", "pgp", "file", fs.Arg(0), "out", dst, "signed", enc.Signed())
+	return nil
+}
+
+// pgpDecrypt decrypts an encrypted file for troubleshooting and checks its
+// signature. What it finds is printed to stderr, the content to -o.
+func pgpDecrypt(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		keyFile    = fs.String("key", "", "private key to decrypt with (default: pgp.decryption_key)")
+		verifyKeys = fs.String("verify-key", "", "comma-separated public keys to check the signature with (default: pgp.signing_key)")
+		out        = fs.String("o", "-", `output file ("-" for stdout)`)
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync pgp decrypt [flags] FILE")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		*keyFile = cfg.PGPDecryptionKey
+	}
+	if *keyFile == "" {
+		return errors.New("config: pgp.decryption_key (PGP_DECRYPTION_KEY) or -key is required")
+	}
+	if *verifyKeys == "" {
+		*verifyKeys = cfg.PGPSigningKey
+	}
+
+	keys, err := pgp.ReadKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	if err := pgp.Unlock(keys, cfg.PGPDecryptionPassphrase); err != nil {
+		return fmt.Errorf("pgp: %s: %w", *keyFile, err)
+	}
+	var verify []string
+	for _, k := range strings.Split(*verifyKeys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			verify = append(verify, k)
+		}
+	}
+	if len(verify) > 0 {
+		vk, err := pgp.ReadKeys(verify...)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, vk...)
+	}
+
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	var dst io.Writer = r.Stdout
+	var outFile *os.File
+	if *out != "-" {
+		if outFile, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer outFile.Close()
+		dst = outFile
+	}
+	w := bufio.NewWriter(dst)
+	d, derr := pgp.Decrypt(w, in, keys)
+	if err := w.Flush(); err != nil && derr == nil {
+		derr = err
+	}
+	if outFile != nil {
+		if err := outFile.Close(); err != nil && derr == nil {
+			derr = err
+		}
+	}
+
+	if derr != nil && !d.Signed {
+		return derr
+	}
+	fmt.Fprintf(r.Stderr, "file:         %s\n", d.FileName)
+	fmt.Fprintf(r.Stderr, "encrypted to: %s\n", strings.Join(d.EncryptedTo, ", "))
+	switch {
+	case !d.Signed:
+		fmt.Fprintln(r.Stderr, "signature:    none")
+	case d.Verified:
+		fmt.Fprintf(r.Stderr, "signature:    good, by %s (%s)\n", d.Signer, d.SignedBy)
+	case errors.Is(derr, pgp.ErrUnknownSigner) && len(verify) == 0:
+		fmt.Fprintf(r.Stderr, "signature:    not checked, by key %s (no -verify-key or pgp.signing_key)\n", d.SignedBy)
+		return nil
+	case errors.Is(derr, pgp.ErrUnknownSigner):
+		fmt.Fprintf(r.Stderr, "signature:    by key %s, which is not among the -verify-key keys\n", d.SignedBy)
+	default:
+		fmt.Fprintf(r.Stderr, "signature:    BAD, by key %s\n", d.SignedBy)
+	}
+	return derr
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"course-sync/internal/archive"
+	"course-sync/internal/sftpclient/sftptest"
+)
+
+// TestUploadEncrypted generates keys for Eightfold and for us, uploads a
+// file encrypted and signed, and decrypts what reached the server.
+func TestUploadEncrypted(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	dir := t.TempDir()
+	setSFTPEnv(t, srv)
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+	t.Setenv("ARCHIVE_DIR", filepath.Join(dir, "archive"))
+
+	cmd := func(args ...string) (int, string, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), args, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+	for _, name := range []string{"eightfold", "course-sync"} {
+		if code, out, errOut := cmd("pgp", "keygen", "-bits", "2048", "-email", name+"@example.com", "-out", filepath.Join(dir, "keys", name)); code != 0 {
+			t.Fatalf("pgp keygen exited %d:\n%s%s", code, out, errOut)
+		}
+	}
+	if code, _, _ := cmd("pgp", "keygen", "-out", filepath.Join(dir, "keys", "eightfold")); code == 0 {
+		t.Error("Expected keygen to refuse to overwrite a key")
+	}
+	t.Setenv("PGP_ENCRYPT", "true")
+	t.Setenv("PGP_RECIPIENT_KEY", filepath.Join(dir, "keys", "eightfold.pub.asc"))
+	t.Setenv("PGP_SIGNING_KEY", filepath.Join(dir, "keys", "course-sync.key.asc"))
+
+	local := filepath.Join(dir, "out", "ef_course_add.xml")
+	plain := []byte("<EF_Course_List><EF_Course><title>Go</title><lms_course_id>1</lms_course_id></EF_Course></EF_Course_List>\n")
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, errOut := cmd("upload", local); code != 0 {
+		t.Fatalf("upload exited %d:\n%s", code, errOut)
+	}
+
+	if _, err := srv.Stat("/inbound/ef_course_add.xml"); err == nil {
+		t.Error("Expected no plain text file on the server")
+	}
+	remote, err := srv.ReadFile("/inbound/ef_course_add.xml.pgp")
+	if err != nil || bytes.Contains(remote, []byte("<EF_Course_List>")) {
+		t.Fatalf("Expected the encrypted file on the server, got %v", err)
+	}
+	uploads, _ := archive.ReadUploads(os.Getenv("ARCHIVE_AUDIT_LOG"))
+	if len(uploads) != 1 || uploads[0].RemotePath != "/inbound/ef_course_add.xml.pgp" || !strings.HasSuffix(uploads[0].Archive, "ef_course_add.xml.pgp.gz") {
+		t.Errorf("Expected the encrypted upload in the audit log with its archived copy, got %+v", uploads)
+	}
+
+	fetched := filepath.Join(dir, "fetched.pgp")
+	if err := os.WriteFile(fetched, remote, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	decrypted := filepath.Join(dir, "decrypted.xml")
+	code, _, errOut := cmd("pgp", "decrypt", "-key", filepath.Join(dir, "keys", "eightfold.key.asc"), "-o", decrypted, fetched)
+	if code != 0 || !strings.Contains(errOut, "file:         ef_course_add.xml") || !strings.Contains(errOut, "signature:    good, by course-sync <course-sync@example.com>") {
+		t.Fatalf("pgp decrypt exited %d:\n%s", code, errOut)
+	}
+	if b, _ := os.ReadFile(decrypted); !bytes.Equal(b, plain) {
+		t.Errorf("Decrypted %q, want %q", b, plain)
+	}
+
+	// The signature does not check out against another key.
+	code, _, errOut = cmd("pgp", "decrypt", "-key", filepath.Join(dir, "keys", "eightfold.key.asc"),
+		"-verify-key", filepath.Join(dir, "keys", "eightfold.pub.asc"), "-o", decrypted, fetched)
+	if code != 1 || !strings.Contains(errOut, "which is not among the -verify-key keys") {
+		t.Errorf("Expected an unknown signer to fail, exit %d:\n%s", code, errOut)
+	}
+	// Nor can the file be read without Eightfold's key.
+	if code, _, _ := cmd("pgp", "decrypt", "-key", filepath.Join(dir, "keys", "course-sync.key.asc"), "-o", decrypted, fetched); code != 1 {
+		t.Errorf("Expected decryption with our own key to fail, exit %d", code)
+	}
+}
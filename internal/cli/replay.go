@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"course-sync/internal/providers/eightfold"
+)
+
+// replay retries the employees in the sync employees dead-letter file. An
+// employee that failed a provider lookup is synced again from scratch; one
+// that only failed the update has the recorded request sent again. Entries
+// that still fail stay in the file with their attempts counted; input
+// entries (no email or id in Eightfold) are kept but never retried.
+func replay(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		path    = fs.String("file", deadLetterDefault, "dead-letter file written by sync employees")
+		stages  = fs.String("stage", "", "only replay entries of these stages (comma-separated: pluralsight, udemy, update)")
+		dryRun  = fs.Bool("dry-run", false, "list the entries that would be replayed and exit")
+		timeout = fs.Duration("timeout", time.Hour, "overall time limit for the run")
+	)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	entries, err := readDeadLetters(*path)
+	if err != nil {
+		return fmt.Errorf("dead-letter file: %w", err)
+	}
+	only := splitCSV(*stages)
+	selected := func(dl DeadLetter) bool {
+		return dl.Stage != stageInput && (len(only) == 0 || slices.Contains(only, dl.Stage))
+	}
+
+	// Group the selected entries by employee, in file order, so an employee
+	// that failed several stages is synced once.
+	var (
+		order     []string
+		byProfile = map[string][]DeadLetter{}
+		remaining []DeadLetter
+		skipped   int
+	)
+	for _, dl := range entries {
+		if !selected(dl) {
+			remaining = append(remaining, dl)
+			if dl.Stage == stageInput {
+				skipped++
+				replayEntries.Inc("skipped")
+			}
+			continue
+		}
+		if _, ok := byProfile[dl.ProfileID]; !ok {
+			order = append(order, dl.ProfileID)
+		}
+		byProfile[dl.ProfileID] = append(byProfile[dl.ProfileID], dl)
+	}
+	r.Summary["entries"] = len(entries)
+	r.Summary["employees"] = len(order)
+	r.Summary["skipped"] = skipped
+
+	if *dryRun {
+		r.Summary["dry_run"] = true
+		tw := tabwriter.NewWriter(r.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PROFILE\tEMAIL\tSTAGE\tCLASS\tATTEMPTS\tERROR")
+		for _, id := range order {
+			for _, dl := range byProfile[id] {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", dl.ProfileID, dl.Email, dl.Stage, dl.Class, dl.Attempts, dl.Error)
+			}
+		}
+		return tw.Flush()
+	}
+	if len(order) == 0 {
+		r.Log.Info("nothing to replay", "file", *path, "entries", len(entries), "skipped", skipped)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	clients, err := initializeClients(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+
+	resolved, failed := 0, 0
+	for i, id := range order {
+		group := byProfile[id]
+		elog := r.Log.With("employee", i+1, "of", len(order), "profile_id", id, "email", group[0].Email)
+		// Stop at the deadline, but keep what was not retried.
+		if ctx.Err() != nil {
+			remaining = append(remaining, group...)
+			failed++
+			continue
+		}
+		still := replayEmployee(ctx, r, clients, group)
+		if len(still) == 0 {
+			elog.Info("employee replayed")
+			resolved++
+			replayEntries.Add(float64(len(group)), "resolved")
+			continue
+		}
+		for _, dl := range still {
+			elog.Warn("employee still failing", "stage", dl.Stage, "class", dl.Class, "attempts", dl.Attempts)
+		}
+		failed++
+		replayEntries.Add(float64(len(group)), "failed")
+		remaining = append(remaining, still...)
+	}
+
+	if err := writeDeadLetters(*path, remaining); err != nil {
+		return fmt.Errorf("dead-letter file: %w", err)
+	}
+	r.Log.Info("replay summary", "employees", len(order), "resolved", resolved, "failed", failed,
+		"skipped", skipped, "remaining", len(remaining))
+	r.Summary["resolved"] = resolved
+	r.Summary["failed"] = failed
+	r.Summary["remaining"] = len(remaining)
+	return nil
+}
+
+// replayEmployee retries the dead letters of one employee and returns the
+// ones that still fail, with their attempts incremented.
+func replayEmployee(ctx context.Context, r *Run, c *clients, group []DeadLetter) []DeadLetter {
+	first := group[0]
+	attempts := 0
+	var (
+		req      *eightfold.UpdateEmployeeRequest
+		lookup   bool
+		employee json.RawMessage
+	)
+	for _, dl := range group {
+		attempts = max(attempts, dl.Attempts)
+		if dl.Stage != stageUpdate {
+			lookup = true
+			employee = dl.Payload
+			continue
+		}
+		var recorded eightfold.UpdateEmployeeRequest
+		if err := json.Unmarshal(dl.Payload, &recorded); err != nil {
+			// No usable request: rebuild it from the providers.
+			lookup = true
+			continue
+		}
+		req = &recorded
+	}
+
+	var still []DeadLetter
+	if lookup {
+		attendance, failures := collectAttendance(ctx, c, first.Email)
+		for _, f := range failures {
+			still = append(still, newDeadLetter(r.ID, f.stage, f.err, first.ProfileID, first.Email, employee))
+		}
+		if len(attendance) > 0 {
+			update := employeeUpdate(first.Email, attendance)
+			req = &update
+		}
+	}
+
+	if req != nil {
+		if err := c.eightfold.UpdateEmployee(ctx, first.ProfileID, *req); err != nil {
+			still = append(still, newDeadLetter(r.ID, stageUpdate, err, first.ProfileID, first.Email, req))
+		}
+	}
+
+	for i := range still {
+		still[i].Attempts = attempts + 1
+	}
+	return still
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"course-sync/internal/httpx"
+	"course-sync/internal/providers/eightfold"
+)
+
+func TestReplay(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		patched = map[string]eightfold.UpdateEmployeeRequest{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/core/employees/")
+		if r.Method != http.MethodPatch || id == "bad" {
+			http.Error(w, `{"message":"invalid course"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		var req eightfold.UpdateEmployeeRequest
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &req)
+		mu.Lock()
+		patched[id] = req
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("EIGHTFOLD_BASE_URL", srv.URL)
+	t.Setenv("EIGHTFOLD_BEARER_TOKEN", "tok")
+	t.Setenv("PLURALSIGHT_TOKEN", "")
+	t.Setenv("UDEMY_CLIENT_ID", "")
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+
+	path := filepath.Join(dir, "dead.jsonl")
+	req := employeeUpdate("jane@example.com", []eightfold.CourseAttendance{{LmsCourseID: "101", Provider: "Udemy"}})
+	failed := &httpx.HTTPError{StatusCode: 503}
+	bad := newDeadLetter("run1", stageUpdate, failed, "bad", "bad@example.com", req)
+	bad.Attempts = 2
+	if err := writeDeadLetters(path, []DeadLetter{
+		newDeadLetter("run1", stageInput, errMissingIdentity, "", "", map[string]any{"username": ""}),
+		newDeadLetter("run1", stageUpdate, failed, "p1", "jane@example.com", req),
+		bad,
+		// Pluralsight is no longer configured, so there is nothing left to fetch.
+		newDeadLetter("run1", stagePluralsight, errors.New("boom"), "p2", "joe@example.com", nil),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"replay", "-file", path, "-dry-run"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("replay -dry-run exited %d:\n%s", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "p1") || !strings.Contains(out, "server_error") || strings.Contains(out, "invalid_input") {
+		t.Errorf("Unexpected dry run listing:\n%s", out)
+	}
+	if len(patched) != 0 {
+		t.Fatalf("Expected a dry run not to call Eightfold, got %v", patched)
+	}
+
+	if code := run(context.Background(), []string{"replay", "-file", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("replay exited %d:\n%s", code, stderr.String())
+	}
+	if got := patched["p1"]; got.Email != "jane@example.com" || len(got.CandidateData.CourseAttendance) != 1 {
+		t.Errorf("Expected the recorded request to be sent again for p1, got %+v", got)
+	}
+
+	left, err := readDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 {
+		t.Fatalf("Expected the input entry and bad to remain, got %+v", left)
+	}
+	if left[0].Stage != stageInput || left[0].Attempts != 1 {
+		t.Errorf("input entry = %+v, want it kept untouched", left[0])
+	}
+	if left[1].ProfileID != "bad" || left[1].Class != "client_error" || left[1].Attempts != 3 || left[1].RunID == "run1" {
+		t.Errorf("bad entry = %+v, want a client_error on attempt 3 from the replay run", left[1])
+	}
+
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if n := len(recs); n != 2 || recs[n-1].Command != "replay" || recs[n-1].Summary["resolved"] != float64(2) {
+		t.Errorf("Unexpected history: %+v", recs)
+	}
+}
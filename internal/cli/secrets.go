@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"course-sync/internal/config"
+)
+
+// Secrets commands manage the encrypted secrets file read by `enc:` references.
+//
+//	course-sync secrets keygen                         print a new COURSE_SYNC_SECRETS_KEY
+//	course-sync secrets seal -in secrets.env -out .secrets.enc
+//	course-sync secrets list -in .secrets.enc          print the names stored in the file
+
+func secretsKeygen(ctx context.Context, r *Run, args []string) error {
+	if err := r.parse(r.flags(), args); err != nil {
+		return err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	fmt.Fprintln(r.Stdout, base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// secretsSeal reads NAME=value lines (blank lines and # comments ignored)
+// and writes them encrypted with COURSE_SYNC_SECRETS_KEY.
+func secretsSeal(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	in := fs.String("in", "-", "NAME=value input file (- for stdin)")
+	out := fs.String("out", ".secrets.enc", "encrypted output file")
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	key, err := config.SecretsKeyFromEnv()
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	values := map[string]string{}
+	sc := bufio.NewScanner(src)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%s:%d: expected NAME=value", *in, n)
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	sealed, err := config.SealSecrets(key, values)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, sealed, 0o600); err != nil {
+		return err
+	}
+	r.Log.Info("sealed secrets", "count", len(values), "file", *out)
+	return nil
+}
+
+func secretsList(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	in := fs.String("in", ".secrets.enc", "encrypted secrets file")
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+
+	key, err := config.SecretsKeyFromEnv()
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	values, err := config.OpenSecrets(key, b)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(r.Stdout, name)
+	}
+	return nil
+}
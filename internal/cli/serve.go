@@ -0,0 +1,391 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"course-sync/internal/config"
+	"course-sync/internal/logging"
+	"course-sync/internal/metrics"
+	"course-sync/internal/schedule"
+)
+
+// serve runs `sync courses` and `sync employees` on the serve.*_schedule
+// cron specs until SIGTERM/SIGINT. Each run goes through execute, so it
+// takes the same lock as a run started by hand or by an external cron and
+// shows up in the history.
+//
+// A local HTTP endpoint reports status and triggers runs on demand:
+//
+//	GET  /healthz
+//	GET  /metrics   (Prometheus)
+//	GET  /status
+//	POST /run/{courses|employees}
+//
+// On shutdown no new runs start; running ones get serve.shutdown_grace to
+// finish before their context is cancelled. Uploads already in progress
+// are not cancelled at all (see uploadFile).
+func serve(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	listen := fs.String("listen", "", "address for the status/trigger endpoint (default serve.listen)")
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(r.Stderr, "course-sync serve: unexpected arguments %q\n", fs.Args())
+		return errUsage
+	}
+
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	if *listen == "" {
+		*listen = cfg.ServeListen
+	}
+	loc := time.Local
+	if cfg.ServeTimezone != "" {
+		if loc, err = time.LoadLocation(cfg.ServeTimezone); err != nil {
+			return err
+		}
+	}
+
+	s := newScheduler(ctx, r.Log.With("component", "scheduler"), loc, cfg.ServeJitter)
+	base := configArgs(r.cfgOpts)
+	for _, j := range []struct{ name, command, spec, args string }{
+		{"courses", "sync courses", cfg.ServeCoursesSchedule, cfg.ServeCoursesArgs},
+		{"employees", "sync employees", cfg.ServeEmployeesSchedule, cfg.ServeEmployeesArgs},
+	} {
+		var sched schedule.Schedule
+		if j.spec != "" {
+			if sched, err = schedule.Parse(j.spec); err != nil {
+				return err
+			}
+		}
+		cmd, _, _ := lookup(strings.Fields(j.command))
+		jobArgs := append(append([]string{}, base...), strings.Fields(j.args)...)
+		s.add(j.name, j.spec, sched, func(ctx context.Context) error {
+			_, err := execute(ctx, cmd, jobArgs, r.Stdout, r.Stderr)
+			return err
+		})
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.Log.Error("http server failed", logging.Err(err))
+		}
+	}()
+	r.Log.Info("listening", "addr", ln.Addr().String())
+
+	s.start()
+	<-ctx.Done()
+
+	r.Log.Info("shutting down, waiting for running jobs", "grace", cfg.ServeShutdownGrace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		r.Log.Warn("http shutdown", logging.Err(err))
+	}
+	return s.shutdown(cfg.ServeShutdownGrace)
+}
+
+// configArgs turns the serve command's -config/-env/-set flags back into
+// arguments for the jobs, so they load the same file and environment.
+func configArgs(opts *config.Options) []string {
+	if opts == nil {
+		return nil
+	}
+	var args []string
+	if opts.Path != "" {
+		args = append(args, "-config", opts.Path)
+	}
+	if opts.Environment != "" {
+		args = append(args, "-env", opts.Environment)
+	}
+	keys := make([]string, 0, len(opts.Overrides))
+	for k := range opts.Overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-set", k+"="+opts.Overrides[k])
+	}
+	return args
+}
+
+var (
+	errJobRunning   = errors.New("job is already running")
+	errShuttingDown = errors.New("shutting down")
+)
+
+// job is one named, optionally scheduled, task of the scheduler.
+type job struct {
+	name     string
+	spec     string
+	schedule schedule.Schedule // nil: manual trigger only
+	run      func(ctx context.Context) error
+
+	mu        sync.Mutex
+	running   bool
+	next      time.Time
+	lastStart time.Time
+	lastEnd   time.Time
+	lastErr   error
+	runs      int
+}
+
+// JobStatus is a job's state as reported by GET /status.
+type JobStatus struct {
+	Name       string     `json:"name"`
+	Schedule   string     `json:"schedule,omitempty"`
+	Running    bool       `json:"running"`
+	Next       *time.Time `json:"next,omitempty"`
+	Runs       int        `json:"runs"`
+	LastStart  *time.Time `json:"last_start,omitempty"`
+	LastEnd    *time.Time `json:"last_end,omitempty"`
+	LastStatus string     `json:"last_status,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+func (j *job) status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := JobStatus{Name: j.name, Schedule: j.spec, Running: j.running, Runs: j.runs}
+	opt := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	st.Next, st.LastStart, st.LastEnd = opt(j.next), opt(j.lastStart), opt(j.lastEnd)
+	if !j.lastEnd.IsZero() {
+		switch {
+		case j.lastErr == nil:
+			st.LastStatus = "ok"
+		case errors.Is(j.lastErr, errLocked):
+			st.LastStatus = "skipped"
+			st.LastError = j.lastErr.Error()
+		default:
+			st.LastStatus = "error"
+			st.LastError = j.lastErr.Error()
+		}
+	}
+	return st
+}
+
+// scheduler fires jobs on their schedules (plus a random jitter) and on
+// demand, never running the same job twice at once.
+type scheduler struct {
+	log    *slog.Logger
+	loc    *time.Location
+	jitter time.Duration
+
+	jobs  map[string]*job
+	order []string
+
+	// ctx stops the schedule loops. Runs use runCtx instead, which is not
+	// cancelled by the shutdown signal but by cancelRuns once the grace
+	// period is over.
+	ctx        context.Context
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	loops      sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+func newScheduler(ctx context.Context, logger *slog.Logger, loc *time.Location, jitter time.Duration) *scheduler {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	return &scheduler{
+		log:        logger,
+		loc:        loc,
+		jitter:     jitter,
+		jobs:       map[string]*job{},
+		ctx:        ctx,
+		runCtx:     runCtx,
+		cancelRuns: cancel,
+	}
+}
+
+func (s *scheduler) add(name, spec string, sched schedule.Schedule, run func(ctx context.Context) error) {
+	s.jobs[name] = &job{name: name, spec: spec, schedule: sched, run: run}
+	s.order = append(s.order, name)
+}
+
+// start launches a loop for every scheduled job.
+func (s *scheduler) start() {
+	for _, name := range s.order {
+		j := s.jobs[name]
+		if j.schedule == nil {
+			s.log.Info("no schedule, manual trigger only", "job", name)
+			continue
+		}
+		s.loops.Add(1)
+		go func() {
+			defer s.loops.Done()
+			s.loop(j)
+		}()
+	}
+}
+
+func (s *scheduler) loop(j *job) {
+	for {
+		next := j.schedule.Next(time.Now().In(s.loc))
+		if next.IsZero() {
+			s.log.Warn("schedule never fires again", "job", j.name, "schedule", j.spec)
+			return
+		}
+		if s.jitter > 0 {
+			next = next.Add(rand.N(s.jitter))
+		}
+		j.mu.Lock()
+		j.next = next
+		j.mu.Unlock()
+		s.log.Info("next run scheduled", "job", j.name, "at", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.trigger(j.name, "schedule"); err != nil {
+			s.log.Warn("job not started", "job", j.name, logging.Err(err))
+		}
+	}
+}
+
+// trigger starts the named job in the background.
+func (s *scheduler) trigger(name, reason string) error {
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("unknown job %q", name)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errShuttingDown
+	}
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		s.mu.Unlock()
+		return errJobRunning
+	}
+	j.running = true
+	j.lastStart = time.Now()
+	j.mu.Unlock()
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	s.log.Info("job starting", "job", name, "reason", reason)
+	go func() {
+		defer s.running.Done()
+		err := j.run(s.runCtx)
+
+		j.mu.Lock()
+		j.running = false
+		j.lastEnd = time.Now()
+		j.lastErr = err
+		j.runs++
+		j.mu.Unlock()
+
+		if err != nil {
+			s.log.Warn("job finished", "job", name, logging.Err(err))
+		} else {
+			s.log.Info("job finished", "job", name)
+		}
+	}()
+	return nil
+}
+
+// shutdown stops new runs and waits for the running ones, cancelling them
+// after grace.
+func (s *scheduler) shutdown(grace time.Duration) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		s.cancelRuns()
+		return nil
+	case <-timer.C:
+	}
+	s.log.Warn("grace period over, cancelling running jobs", "grace", grace)
+	s.cancelRuns()
+	<-done
+	return fmt.Errorf("serve: running jobs cancelled after %s", grace)
+}
+
+func (s *scheduler) statuses() []JobStatus {
+	out := make([]JobStatus, 0, len(s.order))
+	for _, name := range s.order {
+		out = append(out, s.jobs[name].status())
+	}
+	return out
+}
+
+func (s *scheduler) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"jobs": s.statuses()})
+	})
+	mux.HandleFunc("POST /run/{job}", func(w http.ResponseWriter, req *http.Request) {
+		name := req.PathValue("job")
+		if _, ok := s.jobs[name]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("unknown job %q", name)})
+			return
+		}
+		err := s.trigger(name, "http "+req.RemoteAddr)
+		switch {
+		case err == nil:
+			writeJSON(w, http.StatusAccepted, map[string]string{"job": name, "status": "started"})
+		case errors.Is(err, errJobRunning):
+			writeJSON(w, http.StatusConflict, map[string]string{"job": name, "error": err.Error()})
+		case errors.Is(err, errShuttingDown):
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"job": name, "error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"job": name, "error": err.Error()})
+		}
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
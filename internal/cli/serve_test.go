@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"course-sync/internal/config"
+	"course-sync/internal/logging"
+	"course-sync/internal/schedule"
+)
+
+// blockingJob returns a job func that runs until release is closed (or its
+// context is cancelled), signalling on started.
+func blockingJob(started chan<- struct{}, release <-chan struct{}) func(context.Context) error {
+	return func(ctx context.Context) error {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func newTestScheduler(ctx context.Context) *scheduler {
+	return newScheduler(ctx, logging.New(io.Discard, logging.Options{}), time.UTC, 0)
+}
+
+func TestSchedulerHTTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newTestScheduler(ctx)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s.add("courses", "", nil, blockingJob(started, release))
+
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+	post := func(path string) int {
+		resp, err := http.Post(srv.URL+path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/run/courses"); code != http.StatusAccepted {
+		t.Fatalf("POST /run/courses = %d, want 202", code)
+	}
+	<-started
+	if code := post("/run/courses"); code != http.StatusConflict {
+		t.Errorf("POST /run/courses while running = %d, want 409", code)
+	}
+	if code := post("/run/nope"); code != http.StatusNotFound {
+		t.Errorf("POST /run/nope = %d, want 404", code)
+	}
+
+	resp, err := http.Get(srv.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status struct{ Jobs []JobStatus }
+	json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if len(status.Jobs) != 1 || !status.Jobs[0].Running {
+		t.Errorf("Expected courses to be running, got %+v", status.Jobs)
+	}
+
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("GET /metrics = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	close(release)
+	cancel()
+	if err := s.shutdown(time.Second); err != nil {
+		t.Errorf("shutdown() error: %v", err)
+	}
+	if st := s.jobs["courses"].status(); st.Running || st.Runs != 1 || st.LastStatus != "ok" {
+		t.Errorf("Unexpected status after shutdown: %+v", st)
+	}
+	if code := post("/run/courses"); code != http.StatusServiceUnavailable {
+		t.Errorf("POST /run/courses after shutdown = %d, want 503", code)
+	}
+}
+
+func TestSchedulerShutdownLetsRunsFinish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newTestScheduler(ctx)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s.add("employees", "", nil, blockingJob(started, release))
+
+	if err := s.trigger("employees", "test"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	// The shutdown signal must not reach the running job.
+	cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if err := s.shutdown(5 * time.Second); err != nil {
+		t.Errorf("shutdown() error: %v", err)
+	}
+	if st := s.jobs["employees"].status(); st.LastStatus != "ok" {
+		t.Errorf("Expected the run to finish normally, got %+v", st)
+	}
+}
+
+func TestSchedulerShutdownCancelsAfterGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newTestScheduler(ctx)
+	started := make(chan struct{}, 1)
+	s.add("courses", "", nil, blockingJob(started, nil))
+
+	if err := s.trigger("courses", "test"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+	if err := s.shutdown(20 * time.Millisecond); err == nil {
+		t.Error("Expected an error when running jobs had to be cancelled")
+	}
+	if st := s.jobs["courses"].status(); st.LastStatus != "error" || !strings.Contains(st.LastError, "canceled") {
+		t.Errorf("Expected a cancelled run, got %+v", st)
+	}
+}
+
+func TestSchedulerLoopSetsNext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newTestScheduler(ctx)
+	sched, _ := schedule.Parse("@yearly")
+	s.add("courses", "@yearly", sched, func(context.Context) error { return nil })
+	s.start()
+
+	deadline := time.Now().Add(time.Second)
+	for s.jobs["courses"].status().Next == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	next := s.jobs["courses"].status().Next
+	if next == nil || next.Month() != time.January || next.Day() != 1 {
+		t.Errorf("Expected the next run on January 1st, got %v", next)
+	}
+
+	cancel()
+	if err := s.shutdown(time.Second); err != nil {
+		t.Errorf("shutdown() error: %v", err)
+	}
+}
+
+func TestConfigArgs(t *testing.T) {
+	got := configArgs(&config.Options{
+		Path:        "prod.yaml",
+		Environment: "prod",
+		Overrides:   map[string]string{"sftp.port": "2222", "eightfold.base_url": "https://x"},
+	})
+	want := []string{"-config", "prod.yaml", "-env", "prod", "-set", "eightfold.base_url=https://x", "-set", "sftp.port=2222"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("configArgs() = %q, want %q", got, want)
+	}
+}
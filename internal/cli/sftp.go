@@ -0,0 +1,287 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"course-sync/internal/config"
+	"course-sync/internal/sftpclient"
+)
+
+// The sftp commands inspect and tidy the SFTP inbound directory with the
+// upload settings. Relative names are taken in sftp.dir.
+//
+//	course-sync sftp list [-json] [DIR]
+//	course-sync sftp stat [-json] NAME...
+//	course-sync sftp download [-o PATH] NAME
+//	course-sync sftp delete NAME... | -older-than 72h -match GLOB [-dry-run]
+//	course-sync sftp wait [-timeout 30m] [-interval 30s] NAME...
+
+// dialSFTP connects to the configured SFTP server.
+func dialSFTP(ctx context.Context, r *Run, cfg config.Config) (*sftpclient.Session, error) {
+	sc := sftpConfig(cfg)
+	r.Log.Info("connecting", "component", "sftp", "host", sc.Host)
+	return sftpclient.Dial(ctx, sc)
+}
+
+func sftpList(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	asJSON := fs.Bool("json", false, "print the entries as JSON lines")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync sftp list [flags] [DIR]")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return errUsage
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := dialSFTP(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	files, err := s.List(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printRemoteFiles(r, files, *asJSON)
+}
+
+func sftpStat(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	asJSON := fs.Bool("json", false, "print the entries as JSON lines")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync sftp stat [flags] NAME...")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := dialSFTP(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var files []sftpclient.RemoteFile
+	var errs []error
+	for _, name := range fs.Args() {
+		f, err := s.Stat(ctx, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		files = append(files, f)
+	}
+	if err := printRemoteFiles(r, files, *asJSON); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+func printRemoteFiles(r *Run, files []sftpclient.RemoteFile, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(r.Stdout)
+		for _, f := range files {
+			if err := enc.Encode(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(r.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODIFIED\tSIZE\tNAME")
+	for _, f := range files {
+		name := f.Path
+		if f.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", f.ModTime.Local().Format("2006-01-02 15:04:05"), f.Size, name)
+	}
+	return tw.Flush()
+}
+
+func sftpDownload(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	out := fs.String("o", "", "local path (default: the remote base name in the current directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync sftp download [flags] NAME")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	name := fs.Arg(0)
+	local := *out
+	if local == "" {
+		local = path.Base(name)
+	}
+	if err := ensureDir(local); err != nil {
+		return err
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := dialSFTP(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	n, err := s.Download(ctx, name, local)
+	if err != nil {
+		return err
+	}
+	r.Log.Info("downloaded", "component", "sftp", "remote", s.Path(name), "file", local, "bytes", n)
+	return nil
+}
+
+// sftpDelete removes the named files, or with -older-than the files in
+// sftp.dir (optionally matching -match) not modified for that long.
+// Directories are never removed.
+func sftpDelete(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		olderThan = fs.Duration("older-than", 0, "delete the files in sftp.dir last modified longer ago than this, instead of NAME...")
+		match     = fs.String("match", "", "with -older-than, only delete names matching this glob (required, so that in-progress "+sftpclient.PartSuffix+" uploads and "+sftpclient.ChecksumSuffix+" files are only deleted when asked for)")
+		dryRun    = fs.Bool("dry-run", false, "only print what would be deleted")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync sftp delete [flags] NAME... | -older-than DURATION -match GLOB")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if (*olderThan > 0) == (fs.NArg() > 0) {
+		fs.Usage()
+		return errUsage
+	}
+	if *olderThan > 0 && *match == "" {
+		fmt.Fprintln(fs.Output(), "-older-than needs -match, e.g. -match '*.xml'")
+		return errUsage
+	}
+	if _, err := path.Match(*match, ""); err != nil {
+		fmt.Fprintf(fs.Output(), "-match: %v\n", err)
+		return errUsage
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := dialSFTP(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	names := fs.Args()
+	if *olderThan > 0 {
+		files, err := s.List(ctx, "")
+		if err != nil {
+			return err
+		}
+		cutoff := time.Now().Add(-*olderThan)
+		for _, f := range files {
+			if ok, _ := path.Match(*match, f.Name); ok && !f.IsDir && f.ModTime.Before(cutoff) {
+				names = append(names, f.Path)
+			}
+		}
+	}
+
+	deleted := []string{}
+	var errs []error
+	for _, name := range names {
+		if *dryRun {
+			fmt.Fprintf(r.Stdout, "would delete %s\n", s.Path(name))
+			continue
+		}
+		if err := s.Remove(ctx, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(r.Stdout, "deleted %s\n", s.Path(name))
+		deleted = append(deleted, s.Path(name))
+	}
+	r.Summary["deleted"] = deleted
+	if *dryRun {
+		r.Summary["dry_run"] = true
+	}
+	return errors.Join(errs...)
+}
+
+// sftpWait waits until Eightfold has consumed the named files.
+func sftpWait(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		timeout  = fs.Duration("timeout", 30*time.Minute, "give up after this long")
+		interval = fs.Duration("interval", 30*time.Second, "time between checks")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync sftp wait [flags] NAME...")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 || *interval <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	return waitConsumed(ctx, r, cfg, fs.Args(), *timeout, *interval)
+}
+
+// waitConsumed polls until every remote file in names is gone, or timeout.
+func waitConsumed(ctx context.Context, r *Run, cfg config.Config, names []string, timeout, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	s, err := dialSFTP(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	start := time.Now()
+	consumed := []string{}
+	defer func() { r.Summary["consumed"] = consumed }()
+	for _, name := range names {
+		log := r.Log.With("component", "sftp", "remote", s.Path(name))
+		log.Info("waiting for the file to be consumed", "timeout", timeout)
+		if err := s.WaitConsumed(ctx, name, interval); err != nil {
+			return err
+		}
+		log.Info("file consumed", "after", time.Since(start).Round(time.Second))
+		consumed = append(consumed, path.Base(name))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"course-sync/internal/config"
+	"course-sync/internal/sftpclient"
+)
+
+// sftpHostKey connects to the SFTP server, prints its host key and
+// fingerprint, and says whether the configured check accepts it. With
+// -trust it records the key in the first sftp.known_hosts file. It uses the
+// upload profile's settings but does not need credentials or a host key,
+// since it is how the host key is set up in the first place.
+func sftpHostKey(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	trust := fs.Bool("trust", false, "add the key to the first sftp.known_hosts file if the host is not in it yet")
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	cfg, err := config.LoadContext(ctx, *r.cfgOpts)
+	if err != nil {
+		return err
+	}
+	if cfg.SFTPHost == "" {
+		return errors.New("config: sftp.host (SFTP_HOST) is required")
+	}
+	if *trust && len(cfg.SFTPKnownHostsFiles()) == 0 {
+		return errors.New("config: -trust needs sftp.known_hosts (SFTP_KNOWN_HOSTS), the file to record the key in")
+	}
+
+	sc := sftpConfig(cfg)
+	key, err := sftpclient.HostKey(ctx, sc)
+	if err != nil {
+		return err
+	}
+	hostport := net.JoinHostPort(sc.Host, strconv.Itoa(sc.Port))
+
+	sc.TrustOnFirstUse = *trust
+	status := "accepted by " + hostKeyCheck(cfg)
+	checkErr := sftpclient.CheckHostKey(ctx, sc, key)
+	switch {
+	case checkErr == nil && cfg.SFTPInsecureIgnoreHostKey:
+		status = "not checked (sftp.insecure_ignore_host_key is set)"
+	case checkErr != nil:
+		status = checkErr.Error()
+	}
+
+	tw := tabwriter.NewWriter(r.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "host\t%s\n", hostport)
+	fmt.Fprintf(tw, "key\t%s", ssh.MarshalAuthorizedKey(key))
+	fmt.Fprintf(tw, "fingerprint\t%s\n", sftpclient.Fingerprint(key))
+	fmt.Fprintf(tw, "known_hosts\t%s\n", knownhosts.Line([]string{knownhosts.Normalize(hostport)}, key))
+	fmt.Fprintf(tw, "status\t%s\n", status)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if errors.Is(checkErr, sftpclient.ErrHostKeyMismatch) {
+		return checkErr
+	}
+	return nil
+}
+
+// hostKeyCheck names the setting the host key is checked against.
+func hostKeyCheck(cfg config.Config) string {
+	if strings.TrimSpace(cfg.SFTPHostKey) != "" {
+		return "sftp.host_key"
+	}
+	return "sftp.known_hosts"
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"course-sync/internal/sftpclient"
+	"course-sync/internal/sftpclient/sftptest"
+)
+
+func TestSFTPHostKey(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(srv.HostKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("SFTP_HOST", srv.Host)
+	t.Setenv("SFTP_PORT", strconv.Itoa(srv.Port))
+	t.Setenv("SFTP_HOST_KEY", "")
+	t.Setenv("SFTP_INSECURE_IGNORE_HOSTKEY", "")
+	t.Setenv("SFTP_KNOWN_HOSTS", knownHosts)
+
+	hostKey := func(args ...string) (int, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), append([]string{"sftp", "hostkey"}, args...), &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	code, out := hostKey()
+	if code != 0 || !strings.Contains(out, sftpclient.Fingerprint(key)) || !strings.Contains(out, "no such file") {
+		t.Fatalf("Expected the fingerprint and a missing known_hosts file, got exit %d:\n%s", code, out)
+	}
+	if code, out := hostKey("-trust"); code != 0 {
+		t.Fatalf("sftp hostkey -trust exited %d:\n%s", code, out)
+	}
+	if b, _ := os.ReadFile(knownHosts); !strings.Contains(string(b), srv.HostKey) {
+		t.Errorf("Expected -trust to record the key, got %q", b)
+	}
+	if code, out := hostKey(); code != 0 || !strings.Contains(out, "accepted by sftp.known_hosts") {
+		t.Errorf("Expected the recorded key to be accepted, got exit %d:\n%s", code, out)
+	}
+
+	other := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	t.Setenv("SFTP_HOST_KEY", other)
+	if code, out := hostKey(); code == 0 || !strings.Contains(out, "host key mismatch") {
+		t.Errorf("Expected a mismatch with a different pinned key, got exit %d:\n%s", code, out)
+	}
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"course-sync/internal/sftpclient/sftptest"
+)
+
+// setSFTPEnv points the SFTP settings at srv, with its host key pinned.
+func setSFTPEnv(t *testing.T, srv *sftptest.Server) {
+	t.Helper()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("SFTP_HOST", srv.Host)
+	t.Setenv("SFTP_PORT", strconv.Itoa(srv.Port))
+	t.Setenv("SFTP_USER", srv.User)
+	t.Setenv("SFTP_PASS", srv.Password)
+	t.Setenv("SFTP_HOST_KEY", srv.HostKey)
+	t.Setenv("SFTP_KNOWN_HOSTS", "")
+	t.Setenv("SFTP_INSECURE_IGNORE_HOSTKEY", "false")
+	t.Setenv("ARCHIVE_AUDIT_LOG", filepath.Join(t.TempDir(), "uploads.jsonl"))
+}
+
+func TestSFTPCommands(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	for name, content := range map[string]string{
+		"/inbound/ef_course_add.xml":         "<courses/>",
+		"/inbound/ef_course_add.xml.sha256":  "abc  ef_course_add.xml\n",
+		"/inbound/DF_COURSE_IMPORT_0101.csv": "a,b\n",
+		"/inbound/archive/old.xml":           "<old/>",
+	} {
+		if err := srv.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	setSFTPEnv(t, srv)
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+
+	sftp := func(args ...string) (int, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), append([]string{"sftp"}, args...), &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	code, out := sftp("list")
+	if code != 0 || !strings.Contains(out, "/inbound/ef_course_add.xml\n") || !strings.Contains(out, "/inbound/archive/\n") {
+		t.Errorf("Unexpected list, exit %d:\n%s", code, out)
+	}
+	code, out = sftp("stat", "-json", "ef_course_add.xml")
+	if code != 0 || !strings.Contains(out, `"path":"/inbound/ef_course_add.xml","name":"ef_course_add.xml","size":10`) {
+		t.Errorf("Unexpected stat, exit %d:\n%s", code, out)
+	}
+	if code, out := sftp("stat", "missing.xml"); code == 0 {
+		t.Errorf("Expected stat of a missing file to fail:\n%s", out)
+	}
+
+	local := filepath.Join(dir, "dl", "old.xml")
+	if code, out := sftp("download", "-o", local, "archive/old.xml"); code != 0 {
+		t.Fatalf("download exited %d:\n%s", code, out)
+	}
+	if b, _ := os.ReadFile(local); string(b) != "<old/>" {
+		t.Errorf("Downloaded %q", b)
+	}
+
+	code, out = sftp("delete", "-older-than", "1ns", "-match", "*.xml*", "-dry-run")
+	if code != 0 || !strings.Contains(out, "would delete /inbound/ef_course_add.xml.sha256") || strings.Contains(out, ".csv") {
+		t.Errorf("Unexpected dry run, exit %d:\n%s", code, out)
+	}
+	if _, err := srv.Stat("/inbound/ef_course_add.xml"); err != nil {
+		t.Fatal("Expected a dry run to keep the files")
+	}
+	if code, out := sftp("delete", "-older-than", "1ns", "-match", "*.xml*"); code != 0 {
+		t.Fatalf("delete exited %d:\n%s", code, out)
+	}
+	names, _ := srv.List("/inbound")
+	if strings.Join(names, ",") != "DF_COURSE_IMPORT_0101.csv,archive" {
+		t.Errorf("Expected only the CSV and the directory left, got %v", names)
+	}
+	if code, _ := sftp("delete", "archive"); code == 0 {
+		t.Error("Expected delete to refuse a directory")
+	}
+	if code, _ := sftp("delete"); code != 2 {
+		t.Errorf("Expected a usage error without names or -older-than, got %d", code)
+	}
+	if code, out := sftp("delete", "-older-than", "1ns"); code != 2 || !strings.Contains(out, "-older-than needs -match") {
+		t.Errorf("Expected a usage error for -older-than without -match, got %d:\n%s", code, out)
+	}
+
+	code, out = sftp("wait", "-timeout", "50ms", "-interval", "10ms", "DF_COURSE_IMPORT_0101.csv")
+	if code == 0 || !strings.Contains(out, "not consumed") {
+		t.Errorf("Expected wait to time out, exit %d:\n%s", code, out)
+	}
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.Remove("/inbound/DF_COURSE_IMPORT_0101.csv")
+	}()
+	if code, out := sftp("wait", "-timeout", "5s", "-interval", "10ms", "DF_COURSE_IMPORT_0101.csv"); code != 0 {
+		t.Errorf("Expected wait to see the file consumed, exit %d:\n%s", code, out)
+	}
+}
+
+func TestUploadWait(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	dir := t.TempDir()
+	setSFTPEnv(t, srv)
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+	local := filepath.Join(dir, "ef_course_add.xml")
+	if err := os.WriteFile(local, []byte("<EF_Course_List/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for range 500 {
+			if _, err := srv.Stat("/inbound/ef_course_add.xml"); err == nil {
+				srv.Remove("/inbound/ef_course_add.xml")
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"upload", "-wait", "5s", "-wait-interval", "10ms", local}, &stdout, &stderr); code != 0 {
+		t.Fatalf("upload -wait exited %d:\n%s", code, stderr.String())
+	}
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if got := recs[len(recs)-1].Summary["consumed"]; len(got.([]any)) != 1 {
+		t.Errorf("Expected the file consumed in the summary, got %v", got)
+	}
+}
+
+// TestUploadValidatesFeeds checks that a feed with problems is not sent
+// unless sftp.validate_feeds is turned off.
+func TestUploadValidatesFeeds(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	dir := t.TempDir()
+	setSFTPEnv(t, srv)
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+	local := filepath.Join(dir, "ef_course_add.xml")
+	feed := `<EF_Course_List><EF_Course><title>Go</title><lms_course_id></lms_course_id><course_type>Course</course_type></EF_Course></EF_Course_List>`
+	if err := os.WriteFile(local, []byte(feed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"upload", local}, &stdout, &stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "EF_Course #1: lms_course_id is empty") {
+		t.Errorf("Expected the upload to be refused, exit %d:\n%s", code, stderr.String())
+	}
+	if srv.Sessions() != 0 {
+		t.Errorf("Expected no SFTP session, got %d", srv.Sessions())
+	}
+
+	t.Setenv("SFTP_VALIDATE_FEEDS", "false")
+	if code := run(context.Background(), []string{"upload", local}, &stdout, &stderr); code != 0 {
+		t.Fatalf("upload with sftp.validate_feeds off exited %d:\n%s", code, stderr.String())
+	}
+	if _, err := srv.Stat("/inbound/ef_course_add.xml"); err != nil {
+		t.Errorf("Expected the file on the server: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/config"
+	"course-sync/internal/domain"
+	"course-sync/internal/export"
+	"course-sync/internal/sftpclient"
+	syncx "course-sync/internal/sync"
+)
+
+// syncCourses:
+// - Fetch Udemy + Pluralsight catalog
+// - Fetch Eightfold existing courses
+// - Diff -> add/update/delete XML
+// - Optional mock-dir for deterministic runs
+// - Optional upload of the three files to SFTP
+func syncCourses(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		// Backward compatible combined file (create+update). If provided, we also write it.
+		outUpsert = fs.String("out-upsert", "", "(deprecated) output xml path for combined upserts (creates+updates). If set, we also write this file")
+
+		outAdd    = fs.String("out-add", "out/ef_course_add.xml", "output xml path for creates (Eightfold ef_course_add format)")
+		outUpdate = fs.String("out-update", "out/ef_course_update.xml", "output xml path for updates (Eightfold ef_course_update format)")
+		outDelete = fs.String("out-delete", "out/ef_course_delete.xml", "output xml path for deletes (Eightfold ef_course_delete format)")
+
+		systemID = fs.String("system-id", "successfactors", "value to write into <system_id>. Use empty string to keep legacy prefixed ids")
+
+		mockDir     = fs.String("mock-dir", "", "read catalogs from JSON snapshots in this directory (udemy.json, pluralsight.json, eightfold.json) instead of calling APIs")
+		snapshotDir = fs.String("snapshot-dir", "", "if set, write JSON snapshots (udemy.json, pluralsight.json, eightfold.json) to this directory")
+		dryRun      = fs.Bool("dry-run", false, "do not write XML files; only print counts")
+		upload      = fs.Bool("upload", false, "upload the add, update and delete files to SFTP, in that order, over one connection; the delete file only when every provider was listed in full (no errors, -udemy-max-pages and -ps-max-pages 0)")
+		timeout     = fs.Duration("timeout", 6*time.Hour, "overall time limit for the run")
+
+		cat  catalogFlags
+		tags tagFlags
+	)
+	cat.register(fs)
+	tags.register(fs)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	// Mock catalogs must never reach the Eightfold inbound directory.
+	if *upload && (*dryRun || strings.TrimSpace(*mockDir) != "") {
+		fmt.Fprintln(fs.Output(), "-upload cannot be combined with -dry-run or -mock-dir")
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	var (
+		providerCourses []domain.UnifiedCourse
+		efCourses       []syncx.EFCourse
+		incomplete      []string
+		cfg             config.Config
+		err             error
+	)
+
+	if strings.TrimSpace(*mockDir) != "" {
+		// Only for the lock: mock runs use no other settings.
+		if _, err := r.settings(ctx); err != nil {
+			return err
+		}
+		providerCourses, efCourses, err = loadFromMocks(*mockDir)
+		if err != nil {
+			return err
+		}
+	} else {
+		cfg, err = r.config(ctx, uploadSections(*upload)...)
+		if err != nil {
+			return err
+		}
+		ef, err := newEightfold(ctx, r, cfg)
+		if err != nil {
+			return err
+		}
+
+		providerCourses, _, incomplete = fetchCatalogs(ctx, r, cfg, cat)
+
+		efCourses, err = syncx.FetchEightfoldCourses(ctx, ef, 100, 0) // limit=100; maxPages=0 means auto until done (best effort)
+		if err != nil {
+			return fmt.Errorf("eightfold list: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(*snapshotDir) != "" {
+		if err := writeSnapshots(*snapshotDir, providerCourses, efCourses); err != nil {
+			return fmt.Errorf("write snapshots: %w", err)
+		}
+	}
+
+	create, update, del := diff(ctx, providerCourses, efCourses)
+
+	r.Log.Info("diff", "create", len(create), "update", len(update), "delete", len(del),
+		"providers", len(providerCourses), "eightfold", len(efCourses))
+	r.Summary["providers"] = len(providerCourses)
+	r.Summary["eightfold"] = len(efCourses)
+	r.Summary["create"] = len(create)
+	r.Summary["update"] = len(update)
+	r.Summary["delete"] = len(del)
+	diffCourses.Add(float64(len(create)), "create")
+	diffCourses.Add(float64(len(update)), "update")
+	diffCourses.Add(float64(len(del)), "delete")
+
+	if *dryRun {
+		r.Summary["dry_run"] = true
+		return nil
+	}
+
+	tagCfg := export.CourseTagConfig{
+		Operation:                strings.TrimSpace(tags.operation),
+		SystemID:                 strings.TrimSpace(*systemID),
+		EligibilityTagsFieldName: "eligibility_tags",
+		TagsBySource:             tags.bySource(),
+	}
+
+	for _, p := range []string{*outAdd, *outUpdate, *outDelete, *outUpsert} {
+		if err := ensureDir(p); err != nil {
+			return err
+		}
+	}
+
+	// Separate files (recommended)
+	if err := writeFile(ctx, *outAdd, len(create), func() error {
+		return export.WriteEFCourseXML(*outAdd, create, tagCfg)
+	}); err != nil {
+		return err
+	}
+	if err := writeFile(ctx, *outUpdate, len(update), func() error {
+		return export.WriteEFCourseXML(*outUpdate, update, tagCfg)
+	}); err != nil {
+		return err
+	}
+	if err := writeFile(ctx, *outDelete, len(del), func() error {
+		return export.WriteEFCourseDeleteXML(*outDelete, del)
+	}); err != nil {
+		return err
+	}
+
+	// Optional combined file for backward compatibility
+	if strings.TrimSpace(*outUpsert) != "" {
+		upserts := append(create, update...)
+		if err := writeFile(ctx, *outUpsert, len(upserts), func() error {
+			return export.WriteEFCourseXML(*outUpsert, upserts, tagCfg)
+		}); err != nil {
+			return err
+		}
+	}
+
+	archived := []string{*outAdd, *outUpdate, *outDelete}
+	if strings.TrimSpace(*outUpsert) != "" {
+		archived = append(archived, *outUpsert)
+	}
+	if err := archiveFiles(r, cfg, archived...); err != nil {
+		return err
+	}
+
+	if *upload {
+		// Eightfold processes the files in the order they arrive; deletes
+		// go last so a course being replaced is never missing in between.
+		files := []sftpclient.File{
+			{LocalPath: *outAdd, RemoteName: filepath.Base(*outAdd)},
+			{LocalPath: *outUpdate, RemoteName: filepath.Base(*outUpdate)},
+		}
+		// A course missing from an incomplete catalog is not gone: uploading
+		// the deletes would remove it from Eightfold.
+		if len(incomplete) > 0 {
+			r.Log.Warn("not uploading the delete file: provider catalogs are incomplete", "providers", incomplete, "delete", len(del))
+			r.Summary["delete_skipped"] = incomplete
+		} else {
+			files = append(files, sftpclient.File{LocalPath: *outDelete, RemoteName: filepath.Base(*outDelete)})
+		}
+		results, err := uploadFiles(ctx, r, cfg, files)
+		r.Summary["uploaded"] = uploadedNames(results)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diff runs syncx.DiffContext under a diff span.
+func diff(ctx context.Context, provider []domain.UnifiedCourse, eightfold []syncx.EFCourse) ([]domain.UnifiedCourse, []domain.UnifiedCourse, []export.DeleteCourse) {
+	ctx, span := tracer.Start(ctx, "diff", trace.WithAttributes(
+		attribute.Int("providers", len(provider)),
+		attribute.Int("eightfold", len(eightfold)),
+	))
+	defer span.End()
+	return syncx.DiffContext(ctx, provider, eightfold)
+}
+
+func loadFromMocks(dir string) ([]domain.UnifiedCourse, []syncx.EFCourse, error) {
+	read := func(name string, v any) error {
+		p := filepath.Join(dir, name)
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("mock: read %s: %w", p, err)
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			return fmt.Errorf("mock: decode %s: %w", p, err)
+		}
+		return nil
+	}
+
+	var ud []domain.UnifiedCourse
+	var ps []domain.UnifiedCourse
+	var ef []syncx.EFCourse
+	if err := read("udemy.json", &ud); err != nil {
+		return nil, nil, err
+	}
+	if err := read("pluralsight.json", &ps); err != nil {
+		return nil, nil, err
+	}
+	if err := read("eightfold.json", &ef); err != nil {
+		return nil, nil, err
+	}
+	all := append(ud, ps...)
+	return all, ef, nil
+}
+
+func writeSnapshots(dir string, prov []domain.UnifiedCourse, ef []syncx.EFCourse) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Split provider snapshots by source for convenience.
+	var ud, ps []domain.UnifiedCourse
+	for _, c := range prov {
+		switch strings.ToLower(strings.TrimSpace(c.Source)) {
+		case "udemy":
+			ud = append(ud, c)
+		case "pluralsight":
+			ps = append(ps, c)
+		}
+	}
+
+	write := func(name string, v any) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, name), b, 0o644)
+	}
+
+	if err := write("udemy.json", ud); err != nil {
+		return err
+	}
+	if err := write("pluralsight.json", ps); err != nil {
+		return err
+	}
+	if err := write("eightfold.json", ef); err != nil {
+		return err
+	}
+	return nil
+}
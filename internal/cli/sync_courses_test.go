@@ -0,0 +1,454 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"course-sync/internal/archive"
+	"course-sync/internal/domain"
+	"course-sync/internal/providers/eightfold/eightfoldtest"
+	"course-sync/internal/providers/pluralsight/pluralsighttest"
+	"course-sync/internal/providers/udemy/udemytest"
+	"course-sync/internal/sftpclient"
+	"course-sync/internal/sftpclient/sftptest"
+	syncx "course-sync/internal/sync"
+)
+
+// Constants for test data
+const (
+	udemyCourse1        = "Udemy Course 1"
+	pluralSightCourse1  = "PluralSight Course 1"
+	udemyJSONFile       = "udemy.json"
+	pluralSightJSONFile = "pluralsight.json"
+	eightfoldJSONFile   = "eightfold.json"
+)
+
+func TestLoadFromMocks(t *testing.T) {
+	// Create temporary directory for test files
+	tempDir := t.TempDir()
+
+	// Create test data
+	udemyCourses := []domain.UnifiedCourse{
+		{
+			Source:   "udemy",
+			SourceID: "1",
+			Title:    "Udemy Course 1",
+		},
+		{
+			Source:   "udemy",
+			SourceID: "2",
+			Title:    "Udemy Course 2",
+		},
+	}
+
+	pluralSightCourses := []domain.UnifiedCourse{
+		{
+			Source:   "pluralsight",
+			SourceID: "3",
+			Title:    pluralSightCourse1,
+		},
+		{
+			Source:   "pluralsight",
+			SourceID: "4",
+			Title:    "PluralSight Course 2",
+		},
+	}
+
+	efCourses := []syncx.EFCourse{
+		{
+			SystemID: "UDM+1",
+			Title:    "Udemy Course 1",
+		},
+		{
+			SystemID: "PLS+3",
+			Title:    pluralSightCourse1,
+		},
+	}
+
+	// Write test files
+	writeTestJSON(t, filepath.Join(tempDir, udemyJSONFile), udemyCourses)
+	writeTestJSON(t, filepath.Join(tempDir, pluralSightJSONFile), pluralSightCourses)
+	writeTestJSON(t, filepath.Join(tempDir, eightfoldJSONFile), efCourses)
+
+	// Test loadFromMocks
+	providerCourses, efCoursesResult, err := loadFromMocks(tempDir)
+	if err != nil {
+		t.Fatalf("loadFromMocks returned error: %v", err)
+	}
+
+	// Check provider courses
+	expectedProviderCount := len(udemyCourses) + len(pluralSightCourses)
+	if len(providerCourses) != expectedProviderCount {
+		t.Errorf("Expected %d provider courses, got %d", expectedProviderCount, len(providerCourses))
+	}
+
+	// Check Eightfold courses
+	if len(efCoursesResult) != len(efCourses) {
+		t.Errorf("Expected %d Eightfold courses, got %d", len(efCourses), len(efCoursesResult))
+	}
+
+	// Test error case - non-existent directory
+	_, _, err = loadFromMocks("/non/existent/directory")
+	if err == nil {
+		t.Error("Expected error for non-existent directory, got nil")
+	}
+}
+
+func TestWriteSnapshots(t *testing.T) {
+	// Create temporary directory for test files
+	tempDir := t.TempDir()
+
+	// Create test data
+	providerCourses := []domain.UnifiedCourse{
+		{
+			Source:   "udemy",
+			SourceID: "1",
+			Title:    "Udemy Course 1",
+		},
+		{
+			Source:   "pluralsight",
+			SourceID: "2",
+			Title:    pluralSightCourse1,
+		},
+		{
+			Source:   "unknown",
+			SourceID: "3",
+			Title:    "Unknown Course",
+		},
+	}
+
+	efCourses := []syncx.EFCourse{
+		{
+			SystemID: "UDM+1",
+			Title:    "Udemy Course 1",
+		},
+	}
+
+	// Test writeSnapshots
+	err := writeSnapshots(tempDir, providerCourses, efCourses)
+	if err != nil {
+		t.Fatalf("writeSnapshots returned error: %v", err)
+	}
+
+	// Verify files were created
+	files := []string{"udemy.json", "pluralsight.json", "eightfold.json"}
+	for _, file := range files {
+		path := filepath.Join(tempDir, file)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			t.Errorf("Expected file %s to exist", path)
+		}
+	}
+
+	// Verify file contents
+	var udemyCourses []domain.UnifiedCourse
+	readTestJSON(t, filepath.Join(tempDir, udemyJSONFile), &udemyCourses)
+	if len(udemyCourses) != 1 || udemyCourses[0].SourceID != "1" {
+		t.Errorf("Unexpected udemy.json content: %+v", udemyCourses)
+	}
+
+	var psCourses []domain.UnifiedCourse
+	readTestJSON(t, filepath.Join(tempDir, pluralSightJSONFile), &psCourses)
+	if len(psCourses) != 1 || psCourses[0].SourceID != "2" {
+		t.Errorf("Unexpected pluralsight.json content: %+v", psCourses)
+	}
+
+	var efCoursesResult []syncx.EFCourse
+	readTestJSON(t, filepath.Join(tempDir, eightfoldJSONFile), &efCoursesResult)
+	if len(efCoursesResult) != 1 || efCoursesResult[0].SystemID != "UDM+1" {
+		t.Errorf("Unexpected eightfold.json content: %+v", efCoursesResult)
+	}
+}
+
+// TestFetchProviders is commented out because it requires mocking the Provider interfaces
+// which would be complex. In a real-world scenario, you would use a mocking framework
+// or create mock implementations of the Provider interfaces.
+//
+// func TestFetchProviders(t *testing.T) {
+// 	// This would require proper mocking of the Provider interfaces
+// }
+
+// Helper functions
+func writeTestJSON(t *testing.T, path string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to marshal test data: %v", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("Failed to write test file %s: %v", path, err)
+	}
+}
+
+func readTestJSON(t *testing.T, path string, v interface{}) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read test file %s: %v", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatalf("Failed to unmarshal test data: %v", err)
+	}
+}
+
+// setEmulatorEnv points the configuration at the API emulators and keeps
+// the run history and lock in dir.
+func setEmulatorEnv(t *testing.T, dir string, ef *eightfoldtest.Server, ud *udemytest.Server, ps *pluralsighttest.Server) {
+	t.Helper()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("EIGHTFOLD_BASE_URL", ef.URL)
+	t.Setenv("EIGHTFOLD_BEARER_TOKEN", "")
+	t.Setenv("EIGHTFOLD_BASIC_AUTH", ef.BasicAuth)
+	t.Setenv("EIGHTFOLD_USERNAME", ef.Username)
+	t.Setenv("EIGHTFOLD_PASSWORD", ef.Password)
+	t.Setenv("UDEMY_BASE_URL", ud.URL)
+	t.Setenv("UDEMY_CLIENT_ID", ud.ClientID)
+	t.Setenv("UDEMY_CLIENT_SECRET", ud.ClientSecret)
+	t.Setenv("UDEMY_ORG_ID", ud.OrgID)
+	t.Setenv("PLURALSIGHT_GQL_URL", ps.GraphQLURL())
+	t.Setenv("PLURALSIGHT_TOKEN", ps.Token)
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+	t.Setenv("ARCHIVE_DIR", filepath.Join(dir, "archive"))
+	t.Setenv("ARCHIVE_AUDIT_LOG", filepath.Join(dir, "uploads.jsonl"))
+}
+
+// TestSyncCoursesEmulator runs sync courses against the Eightfold emulator,
+// through the password grant and a failed first page, with both course
+// providers down.
+func TestSyncCoursesEmulator(t *testing.T) {
+	ef := eightfoldtest.NewServer()
+	defer ef.Close()
+	ef.AddCourses(
+		map[string]any{"lmsCourseId": "UDM+1", "systemId": "UDM+1", "provider": "Udemy", "title": "Go"},
+		map[string]any{"lmsCourseId": "PLS+2", "systemId": "PLS+2", "provider": "Pluralsight", "title": "Rust"},
+		map[string]any{"lmsCourseId": "IN-3", "provider": "Internal", "title": "Onboarding"},
+	)
+	ef.Inject(eightfoldtest.Fault{Path: "/api/v2/core/courses", Status: http.StatusServiceUnavailable, RetryAfter: "1", Times: 1})
+	ud := udemytest.NewServer()
+	defer ud.Close()
+	ps := pluralsighttest.NewServer()
+	defer ps.Close()
+	if err := ud.LoadMocks("../../mocks/udemy.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.LoadMocks("../../mocks/pluralsight.json"); err != nil {
+		t.Fatal(err)
+	}
+	ps.Inject(pluralsighttest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+
+	dir := t.TempDir()
+	setEmulatorEnv(t, dir, ef, ud, ps)
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"sync", "courses", "-dry-run", "-udemy-max-pages", "1", "-ps-max-pages", "1"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("sync courses exited %d:\n%s", code, stderr.String())
+	}
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if len(recs) != 1 || recs[0].Summary["providers"] != float64(200) || recs[0].Summary["eightfold"] != float64(2) ||
+		recs[0].Summary["create"] != float64(200) || recs[0].Summary["delete"] != float64(2) {
+		t.Errorf("Expected the 200 mock courses created and the 2 managed Eightfold courses deleted, got %+v", recs)
+	}
+}
+
+func TestSyncCoursesCassette(t *testing.T) {
+	ef := eightfoldtest.NewServer()
+	ef.Password = "ef-password-8271"
+	ef.AddCourses(map[string]any{"lmsCourseId": "UDM+1", "systemId": "UDM+1", "provider": "Udemy", "title": "Go"})
+	ud := udemytest.NewServer()
+	ps := pluralsighttest.NewServer()
+	if err := ud.LoadMocks("../../mocks/udemy.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.LoadMocks("../../mocks/pluralsight.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	setEmulatorEnv(t, dir, ef, ud, ps)
+
+	cassette := filepath.Join(dir, "sync.cassette.json")
+	syncCourses := func(flags ...string) map[string]any {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"sync", "courses", "-dry-run"}, flags...)
+		if code := run(context.Background(), args, &stdout, &stderr); code != 0 {
+			t.Fatalf("sync courses %v exited %d:\n%s", flags, code, stderr.String())
+		}
+		recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+		return recs[len(recs)-1].Summary
+	}
+
+	recorded := syncCourses("-http-record", cassette)
+	ef.Close()
+	ud.Close()
+	ps.Close()
+
+	b, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{ef.Password, ef.BasicAuth, ud.ClientSecret, ps.Token} {
+		if bytes.Contains(b, []byte(secret)) {
+			t.Errorf("Cassette leaks %q", secret)
+		}
+	}
+
+	replayed := syncCourses("-http-replay", cassette)
+	for _, k := range []string{"providers", "eightfold", "create", "update", "delete"} {
+		if recorded[k] != replayed[k] {
+			t.Errorf("Summary %s: recorded %v, replayed %v", k, recorded[k], replayed[k])
+		}
+	}
+	if recorded["providers"] != float64(200) {
+		t.Errorf("Expected the 200 mock courses, got %v", recorded)
+	}
+}
+
+// TestSyncCoursesUpload delivers the three files over one SFTP session, with
+// deletes last.
+func TestSyncCoursesUpload(t *testing.T) {
+	ef := eightfoldtest.NewServer()
+	defer ef.Close()
+	ef.AddCourses(map[string]any{"lmsCourseId": "UDM+1", "systemId": "UDM+1", "provider": "Udemy", "title": "Go"})
+	ud := udemytest.NewServer()
+	defer ud.Close()
+	ps := pluralsighttest.NewServer()
+	defer ps.Close()
+	if err := ud.LoadMocks("../../mocks/udemy.json"); err != nil {
+		t.Fatal(err)
+	}
+	srv := sftptest.NewServer(t)
+
+	dir := t.TempDir()
+	setEmulatorEnv(t, dir, ef, ud, ps)
+	setSFTPEnv(t, srv)
+
+	out := func(name string) string { return filepath.Join(dir, "out", name) }
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"sync", "courses", "-upload", "-udemy-max-pages", "0", "-ps-max-pages", "0",
+		"-out-add", out("ef_course_add.xml"), "-out-update", out("ef_course_update.xml"), "-out-delete", out("ef_course_delete.xml")}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("sync courses exited %d:\n%s", code, stderr.String())
+	}
+
+	want := []string{"/inbound/ef_course_add.xml", "/inbound/ef_course_update.xml", "/inbound/ef_course_delete.xml"}
+	if got := srv.Renamed(); !slices.Equal(got, withChecksums(want)) {
+		t.Errorf("Expected uploads in order %v, got %v", want, got)
+	}
+	if n := srv.Sessions(); n != 1 {
+		t.Errorf("Expected 1 SFTP session, got %d", n)
+	}
+	for _, name := range []string{"ef_course_add.xml", "ef_course_update.xml", "ef_course_delete.xml"} {
+		local, _ := os.ReadFile(out(name))
+		if remote, err := srv.ReadFile("/inbound/" + name); err != nil || !bytes.Equal(remote, local) {
+			t.Errorf("Expected %s uploaded as written, got %v", name, err)
+		}
+	}
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if got := recs[len(recs)-1].Summary["uploaded"]; len(got.([]any)) != 3 {
+		t.Errorf("Expected 3 uploaded files in the summary, got %v", got)
+	}
+
+	// Each upload is in the audit log, pointing at an archived copy that
+	// matches what was sent.
+	archived, _ := recs[len(recs)-1].Summary["archive"].(string)
+	if m, err := archive.Verify(archived); err != nil || len(m.Files) != 3 {
+		t.Fatalf("Expected the run's three files archived, got %+v, %v", m, err)
+	}
+	uploads, err := archive.ReadUploads(os.Getenv("ARCHIVE_AUDIT_LOG"))
+	if err != nil || len(uploads) != 3 {
+		t.Fatalf("Expected 3 audit records, got %d, %v", len(uploads), err)
+	}
+	for i, u := range uploads {
+		remote, _ := srv.ReadFile(u.RemotePath)
+		if u.RemotePath != want[i] || u.SHA256 != fmt.Sprintf("%x", sha256.Sum256(remote)) || u.Size != int64(len(remote)) ||
+			u.RunID != recs[len(recs)-1].ID || u.Archive != filepath.Join(archived, path.Base(u.RemotePath)+".gz") {
+			t.Errorf("Unexpected audit record %+v", u)
+		}
+	}
+}
+
+// withChecksums lists the renames an upload of remote makes: each file's
+// checksum sidecar, then the file.
+func withChecksums(remote []string) []string {
+	var out []string
+	for _, p := range remote {
+		out = append(out, p+sftpclient.ChecksumSuffix, p)
+	}
+	return out
+}
+
+// TestSyncCoursesUploadIncompleteCatalogs checks that the deletes are not
+// uploaded when a provider failed or a page limit cut its listing short:
+// the courses missing from it would be deleted from Eightfold.
+func TestSyncCoursesUploadIncompleteCatalogs(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		flags []string
+		fault bool
+		want  string
+	}{
+		{"provider error", []string{"-udemy-max-pages", "0", "-ps-max-pages", "0"}, true, "pluralsight"},
+		{"page limit", []string{"-udemy-max-pages", "1", "-ps-max-pages", "0"}, false, "udemy"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ef := eightfoldtest.NewServer()
+			defer ef.Close()
+			ef.AddCourses(
+				map[string]any{"lmsCourseId": "UDM+1", "systemId": "UDM+1", "provider": "Udemy", "title": "Go"},
+				map[string]any{"lmsCourseId": "PLS+2", "systemId": "PLS+2", "provider": "Pluralsight", "title": "Rust"},
+			)
+			ud := udemytest.NewServer()
+			defer ud.Close()
+			ps := pluralsighttest.NewServer()
+			defer ps.Close()
+			if err := ud.LoadMocks("../../mocks/udemy.json"); err != nil {
+				t.Fatal(err)
+			}
+			if tc.fault {
+				ps.Inject(pluralsighttest.Fault{Status: http.StatusBadRequest})
+			}
+			srv := sftptest.NewServer(t)
+
+			dir := t.TempDir()
+			setEmulatorEnv(t, dir, ef, ud, ps)
+			setSFTPEnv(t, srv)
+
+			out := func(name string) string { return filepath.Join(dir, "out", name) }
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"sync", "courses", "-upload",
+				"-out-add", out("ef_course_add.xml"), "-out-update", out("ef_course_update.xml"), "-out-delete", out("ef_course_delete.xml")}, tc.flags...)
+			if code := run(context.Background(), args, &stdout, &stderr); code != 0 {
+				t.Fatalf("sync courses exited %d:\n%s", code, stderr.String())
+			}
+
+			want := []string{"/inbound/ef_course_add.xml", "/inbound/ef_course_update.xml"}
+			if got := srv.Renamed(); !slices.Equal(got, withChecksums(want)) {
+				t.Errorf("Expected only %v uploaded, got %v", want, got)
+			}
+			recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+			sum := recs[len(recs)-1].Summary
+			if sum["delete"] == float64(0) || fmt.Sprint(sum["delete_skipped"]) != "["+tc.want+"]" {
+				t.Errorf("Expected the deletes skipped for %s, got %v", tc.want, sum)
+			}
+			if _, err := os.Stat(out("ef_course_delete.xml")); err != nil {
+				t.Errorf("Expected the delete file still written locally: %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncCoursesUploadRejectsMocks(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"sync", "courses", "-upload", "-mock-dir", "../../mocks"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected a usage error, got exit %d:\n%s", code, stderr.String())
+	}
+}
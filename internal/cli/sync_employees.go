@@ -0,0 +1,544 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"course-sync/internal/config"
+	"course-sync/internal/logging"
+	"course-sync/internal/providers/eightfold"
+	"course-sync/internal/providers/pluralsight"
+	"course-sync/internal/providers/udemy"
+)
+
+// Estructura para mantener los clientes inicializados
+type clients struct {
+	eightfold   *eightfold.Client
+	pluralsight *pluralsight.Client
+	udemy       *udemy.Client
+}
+
+// Inicializa todos los clientes necesarios. Pluralsight y Udemy son
+// opcionales: sin credenciales se omiten.
+func initializeClients(ctx context.Context, r *Run, cfg config.Config) (*clients, error) {
+	ef, err := newEightfold(ctx, r, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var psClient *pluralsight.Client
+	if cfg.Require("pluralsight") == nil {
+		psClient = newPluralsight(r, cfg)
+		r.Log.Info("provider enabled", "provider", "pluralsight")
+	} else {
+		r.Log.Info("provider not configured, skipping", "provider", "pluralsight")
+	}
+
+	var udemyClient *udemy.Client
+	if cfg.Require("udemy") == nil {
+		udemyClient = newUdemy(r, cfg)
+		r.Log.Info("provider enabled", "provider", "udemy")
+	} else {
+		r.Log.Info("provider not configured, skipping", "provider", "udemy")
+	}
+
+	return &clients{
+		eightfold:   ef,
+		pluralsight: psClient,
+		udemy:       udemyClient,
+	}, nil
+}
+
+// Helper function to process Pluralsight courses for a user
+func processPluralsightCourses(ctx context.Context, ps *pluralsight.Client, psUser *pluralsight.UserNode) ([]eightfold.CourseAttendance, error) {
+	progressList, err := ps.GetCourseProgress(ctx, psUser.PsUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(progressList) == 0 {
+		return nil, nil
+	}
+
+	var attendance []eightfold.CourseAttendance
+	for _, p := range progressList {
+		status := "in_progress"
+		if p.PercentComplete >= 100.0 {
+			status = "completed"
+		}
+
+		var startTs int64
+		if p.FirstViewedClipOn != "" {
+			if t, err := time.Parse(time.RFC3339, p.FirstViewedClipOn); err == nil {
+				startTs = t.Unix()
+			}
+		}
+
+		// Convert seconds to hours for durationHours
+		durationHours := p.CourseSeconds / 3600.0
+
+		attendance = append(attendance, eightfold.CourseAttendance{
+			LmsCourseID:          p.CourseID,
+			Title:                p.Course.Title,
+			PercentageCompletion: p.PercentComplete,
+			Status:               status,
+			StartTs:              startTs,
+			DurationHours:        durationHours,
+			Provider:             "Pluralsight",
+		})
+	}
+
+	return attendance, nil
+}
+
+// Helper function to process Udemy courses for a user
+func processUdemyCourses(ctx context.Context, uClient *udemy.Client, email string) ([]eightfold.CourseAttendance, error) {
+	// 1. Look up the user in Udemy by email
+	udemyUser, err := uClient.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("udemy user lookup failed: %w", err)
+	}
+	if udemyUser == nil {
+		// User not found in Udemy
+		return []eightfold.CourseAttendance{}, nil
+	}
+
+	// 2. Get the user's course progress
+	progressList, err := uClient.GetCourseProgress(ctx, udemyUser.UdemyUserID)
+	if err != nil {
+		return nil, fmt.Errorf("udemy course progress fetch failed: %w", err)
+	}
+	if len(progressList) == 0 {
+		return []eightfold.CourseAttendance{}, nil
+	}
+
+	// 3. Convert to eightfold.CourseAttendance format
+	var attendance []eightfold.CourseAttendance
+	for _, p := range progressList {
+		status := "in_progress"
+		if p.IsCourseCompleted || p.PercentComplete >= 100.0 {
+			status = "completed"
+		}
+
+		var startTs int64
+		if p.FirstViewedLectureOn != "" {
+			if t, err := time.Parse(time.RFC3339, p.FirstViewedLectureOn); err == nil {
+				startTs = t.Unix()
+			}
+		}
+
+		// Convert seconds to hours for durationHours
+		durationHours := p.CourseSeconds / 3600.0
+
+		attendance = append(attendance, eightfold.CourseAttendance{
+			LmsCourseID:          p.CourseID,
+			Title:                p.Course.Title,
+			PercentageCompletion: p.PercentComplete,
+			Status:               status,
+			StartTs:              startTs,
+			DurationHours:        durationHours,
+			Provider:             "Udemy",
+		})
+	}
+
+	return attendance, nil
+}
+
+// stageError is a failed stage of syncing one employee.
+type stageError struct {
+	stage string
+	err   error
+}
+
+// collectAttendance gathers the course progress of email from every
+// configured provider. A provider that fails is reported in failures and
+// the others still count, so one outage does not blank an employee.
+func collectAttendance(ctx context.Context, c *clients, email string) (attendance []eightfold.CourseAttendance, failures []stageError) {
+	if c.pluralsight != nil {
+		psUser, err := c.pluralsight.GetUserByEmail(ctx, email)
+		switch {
+		case err != nil:
+			failures = append(failures, stageError{stagePluralsight, fmt.Errorf("pluralsight user lookup failed: %w", err)})
+		case psUser != nil:
+			psAttendance, err := processPluralsightCourses(ctx, c.pluralsight, psUser)
+			if err != nil {
+				failures = append(failures, stageError{stagePluralsight, fmt.Errorf("pluralsight course progress fetch failed: %w", err)})
+			} else {
+				attendance = append(attendance, psAttendance...)
+			}
+		}
+	}
+
+	if c.udemy != nil {
+		udemyAttendance, err := processUdemyCourses(ctx, c.udemy, email)
+		if err != nil {
+			failures = append(failures, stageError{stageUdemy, err})
+		} else {
+			attendance = append(attendance, udemyAttendance...)
+		}
+	}
+	return attendance, failures
+}
+
+// employeeIdentity returns the profile id and email of an Eightfold
+// employee record.
+func employeeIdentity(e eightfold.Employee) (profileID, email string) {
+	profileID, email = e.ID, e.Email()
+
+	// Temporary patch: Remove "-sandbox" from email addresses
+	email = strings.Replace(email, "-sandbox", "", 1)
+	return profileID, email
+}
+
+func employeeUpdate(email string, attendance []eightfold.CourseAttendance) eightfold.UpdateEmployeeRequest {
+	return eightfold.UpdateEmployeeRequest{
+		Email: email,
+		CandidateData: eightfold.CandidateData{
+			CourseAttendance: attendance,
+		},
+	}
+}
+
+// employeeFields are the raw fields of an Eightfold employee that sync
+// employees keeps (for the dead-letter file); the rest of each record is
+// dropped as soon as it is fetched.
+var employeeFields = []string{"id", "employeeId", "employee_id", "email", "emails", "email_list", "emailList", "username"}
+
+// parseSince reads a -modified-since value: an RFC 3339 time, a date, or a
+// duration before now. Empty means no limit (the zero time).
+func parseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time, a date or a duration", s)
+}
+
+func syncEmployees(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	var (
+		limit      = fs.Int("limit", 100, "limit page size hint (default 100 = max)")
+		dryRun     = fs.Bool("dry-run", false, "fetch data but do not update Eightfold")
+		timeout    = fs.Duration("timeout", 2*time.Hour, "overall time limit for the run")
+		deadLetter = fs.String("dead-letter", deadLetterDefault, "file that failed employees are recorded in for replay (empty to disable; not written on dry runs)")
+		cpPath     = fs.String("checkpoint", checkpointDefault, "file that finished employees are recorded in for -resume (empty to disable; not written on dry runs)")
+		resume     = fs.Bool("resume", false, "skip the employees finished by the interrupted run in -checkpoint")
+		cpMaxAge   = fs.Duration("checkpoint-max-age", 24*time.Hour, "with -resume, start over when the checkpointed run began longer ago than this (0 = never)")
+		workers    = fs.Int("workers", 10, "employees looked up in the providers in parallel")
+		department = fs.String("department", "", "only sync the employees of this department")
+		location   = fs.String("location", "", "only sync the employees of this location")
+		modSince   = fs.String("modified-since", "", "only sync the employees modified since this time (RFC 3339, 2006-01-02, or a duration ago such as 24h)")
+	)
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		fmt.Fprintln(r.Stderr, "-workers must be at least 1")
+		return errUsage
+	}
+	since, err := parseSince(*modSince, time.Now())
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "-modified-since: %v\n", err)
+		return errUsage
+	}
+	query := eightfold.EmployeeQuery{
+		PageSize:      *limit,
+		Fields:        employeeFields,
+		Department:    strings.TrimSpace(*department),
+		Location:      strings.TrimSpace(*location),
+		ModifiedSince: since,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	initStart := time.Now()
+
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+	if query.Department != "" || query.Location != "" || !query.ModifiedSince.IsZero() {
+		r.Log.Info("restricting employees", "department", query.Department, "location", query.Location,
+			"modified_since", query.ModifiedSince)
+	}
+
+	// 1. Inicializar clientes
+	clients, err := initializeClients(ctx, r, cfg)
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("clients initialized", "took", time.Since(initStart))
+
+	// With -resume, skip the employees an interrupted run already finished.
+	var cp *checkpoint
+	if *resume && *cpPath != "" {
+		cp, err = loadCheckpoint(*cpPath, *cpMaxAge)
+		switch {
+		case errors.Is(err, errCheckpointExpired):
+			r.Log.Warn("starting over", logging.Err(err))
+		case err != nil:
+			return err
+		case cp == nil:
+			r.Log.Info("no checkpoint to resume, starting over", "checkpoint", *cpPath)
+		default:
+			r.Log.Info("resuming", "checkpoint", *cpPath, "from_run", cp.file.RunID,
+				"started", cp.file.Started, "finished", cp.len())
+		}
+	}
+	resumed := cp != nil
+	if cp == nil && *cpPath != "" && !*dryRun {
+		cp = newCheckpoint(*cpPath, r.ID)
+	}
+	markFinished := func(log *slog.Logger, profileID string) {
+		if *dryRun {
+			return
+		}
+		if err := cp.mark(profileID); err != nil {
+			log.Warn("could not save checkpoint", logging.Err(err))
+		}
+	}
+
+	var letters *deadLetterFile
+	if *deadLetter != "" && !*dryRun {
+		if letters, err = createDeadLetters(*deadLetter, resumed); err != nil {
+			return fmt.Errorf("dead-letter file: %w", err)
+		}
+		defer letters.Close()
+	}
+	record := func(log *slog.Logger, dl DeadLetter) {
+		if err := letters.add(dl); err != nil {
+			log.Error("could not write dead letter", logging.Err(err))
+		}
+	}
+
+	// 2. Pipeline: one goroutine pages the employees from Eightfold, a pool
+	// of workers looks up their courses in the providers, and this goroutine
+	// writes the updates to Eightfold. The channels between the stages are
+	// small, so a slow stage holds back the ones before it (down to the
+	// paging) and memory stays flat whatever the size of the workforce.
+	type employeeJob struct {
+		index    int
+		employee eightfold.Employee
+	}
+	type userProcessResult struct {
+		index       int
+		email       string
+		profileID   string
+		employee    eightfold.Employee
+		attendance  []eightfold.CourseAttendance
+		failures    []stageError
+		processTime time.Duration
+		err         error
+	}
+
+	syncStart := time.Now()
+	jobs := make(chan employeeJob, *workers)
+	resultsCh := make(chan userProcessResult, *workers)
+
+	// Stage 1: page the employees, keeping only the fields we need and, with
+	// -department, -location or -modified-since, only the matching ones.
+	var (
+		fetchErr  error
+		total     int
+		skippedCP int
+	)
+	go func() {
+		defer close(jobs)
+		r.Log.Info("fetching employees", "component", "eightfold")
+		for u, err := range clients.eightfold.ListEmployees(ctx, query) {
+			if err != nil {
+				fetchErr = err
+				return
+			}
+			total++
+			if cp.has(u.ID) {
+				skippedCP++
+				continue
+			}
+			select {
+			case jobs <- employeeJob{index: total, employee: u}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		r.Log.Info("fetched employees", "component", "eightfold", "employees", total, "took", time.Since(syncStart))
+	}()
+
+	// Stage 2: look up the courses of each employee in the providers.
+	processUser := func(job employeeJob) userProcessResult {
+		// Medir tiempo de procesamiento por usuario
+		userStart := time.Now()
+		u := job.employee
+
+		profileID, email := employeeIdentity(u)
+		if email == "" || profileID == "" {
+			return userProcessResult{
+				index:     job.index,
+				email:     email,
+				profileID: profileID,
+				employee:  u,
+				err:       errMissingIdentity,
+			}
+		}
+
+		// Crear un contexto específico para este usuario
+		userCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+
+		attendance, failures := collectAttendance(userCtx, clients, email)
+		return userProcessResult{
+			index:       job.index,
+			email:       email,
+			profileID:   profileID,
+			employee:    u,
+			attendance:  attendance,
+			failures:    failures,
+			processTime: time.Since(userStart),
+		}
+	}
+	var wg sync.WaitGroup
+	for range *workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				resultsCh <- processUser(job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	// Stage 3: write the updates to Eightfold, one at a time.
+	processed := 0
+	skipped := 0
+	updated := 0
+	errorCount := 0
+	for result := range resultsCh {
+		email := result.email
+		profileID := result.profileID
+		attendance := result.attendance
+
+		elog := r.Log.With("employee", result.index, "profile_id", profileID, "email", email)
+
+		if result.err != nil {
+			elog.Warn("employee skipped", logging.Err(result.err))
+			skipped++
+			employeesTotal.Inc("skipped")
+			record(elog, newDeadLetter(r.ID, stageInput, result.err, profileID, email, result.employee))
+			continue
+		}
+
+		for _, f := range result.failures {
+			elog.Warn("provider lookup failed", "stage", f.stage, logging.Err(f.err))
+			record(elog, newDeadLetter(r.ID, f.stage, f.err, profileID, email, result.employee))
+		}
+
+		processed++
+		employeesTotal.Inc("processed")
+
+		// Mostrar resultados de cursos
+		psCount := 0
+		udemyCount := 0
+		for _, course := range attendance {
+			if course.Provider == "Pluralsight" {
+				psCount++
+			} else if course.Provider == "Udemy" {
+				udemyCount++
+			}
+		}
+
+		elog = elog.With("courses", len(attendance), "pluralsight", psCount, "udemy", udemyCount, "took", result.processTime)
+
+		// Patch EF User with combined courses
+		if len(attendance) > 0 {
+			req := employeeUpdate(email, attendance)
+
+			if *dryRun {
+				elog.Info("dry run: would update employee courses")
+			} else {
+				if err := clients.eightfold.UpdateEmployee(ctx, profileID, req); err != nil {
+					elog.Error("update employee failed", logging.Err(err))
+					errorCount++
+					employeesTotal.Inc("errored")
+					record(elog, newDeadLetter(r.ID, stageUpdate, err, profileID, email, req))
+				} else {
+					elog.Info("employee courses updated")
+					updated++
+					employeesTotal.Inc("updated")
+					if len(result.failures) == 0 {
+						markFinished(elog, profileID)
+					}
+				}
+			}
+		} else {
+			elog.Debug("no courses to sync")
+			if len(result.failures) == 0 {
+				markFinished(elog, profileID)
+			}
+		}
+	}
+
+	// Resumen final
+	totalTime := time.Since(syncStart)
+	r.Log.Info("sync summary", "employees", total, "processed", processed, "updated", updated, "skipped", skipped,
+		"errors", errorCount, "took", totalTime)
+	r.Summary["employees"] = total
+	if resumed {
+		r.Summary["resumed"] = skippedCP
+	}
+	r.Summary["processed"] = processed
+	r.Summary["updated"] = updated
+	r.Summary["skipped"] = skipped
+	r.Summary["errors"] = errorCount
+	if letters != nil && letters.n > 0 {
+		r.Summary["dead_letters"] = letters.n
+		r.Summary["dead_letter_file"] = *deadLetter
+		r.Log.Warn("failed employees recorded; retry them with course-sync replay", "dead_letters", letters.n, "file", *deadLetter)
+	}
+	if *dryRun {
+		r.Summary["dry_run"] = true
+		if fetchErr != nil {
+			return fmt.Errorf("fetch employees error: %w", fetchErr)
+		}
+		return nil
+	}
+
+	// A run that went through every employee leaves nothing to resume; an
+	// interrupted one saves where it got to.
+	interrupted := ctx.Err()
+	if fetchErr != nil {
+		interrupted = fmt.Errorf("fetch employees error: %w", fetchErr)
+	}
+	if interrupted != nil {
+		if serr := cp.save(); serr != nil {
+			r.Log.Warn("could not save checkpoint", logging.Err(serr))
+		} else if cp != nil {
+			r.Log.Warn("run interrupted; rerun with -resume to skip finished employees",
+				"checkpoint", *cpPath, "finished", cp.len())
+		}
+		return fmt.Errorf("sync interrupted: %w", interrupted)
+	}
+	if err := cp.remove(); err != nil {
+		r.Log.Warn("could not remove checkpoint", logging.Err(err))
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"course-sync/internal/providers/eightfold/eightfoldtest"
+)
+
+// TestSyncEmployeesDeadLetters checks that skipped employees, provider
+// failures and failed updates all end up in the dead-letter file.
+func TestSyncEmployeesDeadLetters(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/core/employees", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[
+			{"id":"p1","email":"jane@example.com"},
+			{"id":"","email":"nobody@example.com"},
+			{"id":"bad","email":"joe@example.com"}
+		],"meta":{}}`))
+	})
+	mux.HandleFunc("PATCH /api/v2/core/employees/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "bad" {
+			http.Error(w, `{"message":"invalid course"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("EIGHTFOLD_BASE_URL", srv.URL)
+	t.Setenv("EIGHTFOLD_BEARER_TOKEN", "tok")
+	t.Setenv("PLURALSIGHT_GQL_URL", srv.URL+"/graphql")
+	t.Setenv("PLURALSIGHT_TOKEN", "tok")
+	t.Setenv("UDEMY_BASE_URL", srv.URL)
+	t.Setenv("UDEMY_CLIENT_ID", "id")
+	t.Setenv("UDEMY_CLIENT_SECRET", "secret")
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+
+	path := filepath.Join(dir, "dead.jsonl")
+	cpPath := filepath.Join(dir, "cp.json")
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"sync", "employees", "-dead-letter", path, "-checkpoint", cpPath}, &stdout, &stderr); code != 0 {
+		t.Fatalf("sync employees exited %d:\n%s", code, stderr.String())
+	}
+
+	letters, err := readDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, dl := range letters {
+		got[dl.ProfileID+"/"+dl.Stage] = dl.Class
+		if dl.Payload == nil {
+			t.Errorf("Expected a payload for %s/%s", dl.ProfileID, dl.Stage)
+		}
+	}
+	want := map[string]string{
+		"/input":          "invalid_input",
+		"p1/pluralsight":  "auth",
+		"bad/pluralsight": "auth",
+		"bad/update":      "client_error",
+	}
+	if len(got) != len(want) {
+		t.Errorf("dead letters = %v, want %v", got, want)
+	}
+	for k, class := range want {
+		if got[k] != class {
+			t.Errorf("dead letter %s class = %q, want %q (all: %v)", k, got[k], class, got)
+		}
+	}
+
+	// A dry run leaves the file alone.
+	if code := run(context.Background(), []string{"sync", "employees", "-dead-letter", path, "-checkpoint", cpPath, "-dry-run"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("sync employees -dry-run exited %d", code)
+	}
+	if again, _ := readDeadLetters(path); len(again) != len(letters) {
+		t.Errorf("Expected a dry run not to rewrite the dead-letter file, got %d entries", len(again))
+	}
+}
+
+// TestSyncEmployeesResume checks that -resume skips the employees in the
+// checkpoint, keeps the dead letters of the interrupted run and removes the
+// checkpoint once every employee went through.
+func TestSyncEmployeesResume(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		patched []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/core/employees", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[
+			{"id":"p1","email":"jane@example.com"},
+			{"id":"p2","email":"joe@example.com"}
+		],"meta":{}}`))
+	})
+	mux.HandleFunc("PATCH /api/v2/core/employees/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		patched = append(patched, r.PathValue("id"))
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("EIGHTFOLD_BASE_URL", srv.URL)
+	t.Setenv("EIGHTFOLD_BEARER_TOKEN", "tok")
+	t.Setenv("PLURALSIGHT_TOKEN", "")
+	t.Setenv("UDEMY_BASE_URL", srv.URL)
+	t.Setenv("UDEMY_CLIENT_ID", "id")
+	t.Setenv("UDEMY_CLIENT_SECRET", "secret")
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+
+	cpPath := filepath.Join(dir, "cp.json")
+	cp := newCheckpoint(cpPath, "run1")
+	cp.mark("p1")
+	if err := cp.save(); err != nil {
+		t.Fatal(err)
+	}
+	dlPath := filepath.Join(dir, "dead.jsonl")
+	earlier := newDeadLetter("run1", stageUpdate, errors.New("boom"), "p0", "old@example.com", nil)
+	if err := writeDeadLetters(dlPath, []DeadLetter{earlier}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"sync", "employees", "-resume", "-checkpoint", cpPath, "-dead-letter", dlPath}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("sync employees -resume exited %d:\n%s", code, stderr.String())
+	}
+
+	if len(patched) != 1 || patched[0] != "p2" {
+		t.Errorf("patched = %v, want only p2", patched)
+	}
+	if _, err := os.Stat(cpPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed after a complete run, got %v", err)
+	}
+	if letters, _ := readDeadLetters(dlPath); len(letters) != 1 || letters[0].ProfileID != "p0" {
+		t.Errorf("Expected the dead letters of the interrupted run to be kept, got %+v", letters)
+	}
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if len(recs) != 1 || recs[0].Summary["resumed"] != float64(1) || recs[0].Summary["employees"] != float64(2) {
+		t.Errorf("Unexpected history: %+v", recs)
+	}
+}
+
+// TestSyncEmployeesPages checks that employees are streamed across pages
+// through the worker pool, and that a page that fails to load stops the run
+// with the checkpoint saved.
+func TestSyncEmployeesPages(t *testing.T) {
+	const total = 250
+	failAt := -1
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/core/employees", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		if start == failAt {
+			http.Error(w, "boom", http.StatusBadRequest)
+			return
+		}
+		end := min(start+100, total)
+		var rows []string
+		for i := start; i < end; i++ {
+			rows = append(rows, fmt.Sprintf(`{"id":"p%d","email":"e%d@example.com","title":"Engineer"}`, i, i))
+		}
+		fmt.Fprintf(w, `{"data":[%s],"meta":{"pageStartIndex":%d,"pageTotalCount":%d,"totalCount":%d}}`,
+			strings.Join(rows, ","), start, end-start, total)
+	})
+	mux.HandleFunc("PATCH /api/v2/core/employees/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("EIGHTFOLD_BASE_URL", srv.URL)
+	t.Setenv("EIGHTFOLD_BEARER_TOKEN", "tok")
+	t.Setenv("PLURALSIGHT_TOKEN", "")
+	t.Setenv("UDEMY_BASE_URL", srv.URL)
+	t.Setenv("UDEMY_CLIENT_ID", "id")
+	t.Setenv("UDEMY_CLIENT_SECRET", "secret")
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+
+	cpPath := filepath.Join(dir, "cp.json")
+	args := []string{"sync", "employees", "-workers", "3", "-checkpoint", cpPath, "-dead-letter", filepath.Join(dir, "dead.jsonl")}
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), args, &stdout, &stderr); code != 0 {
+		t.Fatalf("sync employees exited %d:\n%s", code, stderr.String())
+	}
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if len(recs) != 1 || recs[0].Summary["employees"] != float64(total) || recs[0].Summary["processed"] != float64(total) {
+		t.Fatalf("Unexpected history: %+v", recs)
+	}
+
+	failAt = 200
+	stderr.Reset()
+	if code := run(context.Background(), args, &stdout, &stderr); code == 0 {
+		t.Fatal("Expected a failed page to fail the run")
+	}
+	cp, err := loadCheckpoint(cpPath, 0)
+	if err != nil || cp.len() != 200 {
+		t.Errorf("Expected a checkpoint of the first 200 employees, got %v (%v)", cp.len(), err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"":                     {},
+		"2026-03-01T08:00:00Z": time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+		"2026-03-01":           time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+		"36h":                  time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"yesterday", "-1h"} {
+		if _, err := parseSince(in, now); err == nil {
+			t.Errorf("Expected parseSince(%q) to fail", in)
+		}
+	}
+}
+
+// TestSyncEmployeesEmulator runs sync employees end to end against the
+// Eightfold emulator, with a rate-limited update, a failed page and a
+// slow one along the way.
+func TestSyncEmployeesEmulator(t *testing.T) {
+	ef := eightfoldtest.NewServer()
+	defer ef.Close()
+	ef.PageSize = 40
+	for i := range 90 {
+		ef.AddEmployees(map[string]any{"id": fmt.Sprintf("p%d", i), "email": fmt.Sprintf("user.%d@example.com", i)})
+	}
+	ef.Inject(eightfoldtest.Fault{Method: http.MethodPatch, Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+	ef.Inject(eightfoldtest.Fault{Method: http.MethodGet, Status: http.StatusBadGateway, RetryAfter: "1", Times: 1})
+	ef.Inject(eightfoldtest.Fault{Method: http.MethodGet, Delay: 50 * time.Millisecond, Times: 1})
+
+	dir := t.TempDir()
+	t.Setenv("COURSE_SYNC_CONFIG", "")
+	t.Setenv("EIGHTFOLD_BASE_URL", ef.URL)
+	t.Setenv("EIGHTFOLD_BEARER_TOKEN", ef.Token)
+	t.Setenv("PLURALSIGHT_TOKEN", "")
+	t.Setenv("UDEMY_BASE_URL", ef.URL)
+	t.Setenv("UDEMY_CLIENT_ID", "id")
+	t.Setenv("UDEMY_CLIENT_SECRET", "secret")
+	t.Setenv(historyEnv, filepath.Join(dir, "history.jsonl"))
+	t.Setenv("COURSE_SYNC_LOCK_DIR", dir)
+
+	dlPath := filepath.Join(dir, "dead.jsonl")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"sync", "employees", "-workers", "4",
+		"-checkpoint", filepath.Join(dir, "cp.json"), "-dead-letter", dlPath}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("sync employees exited %d:\n%s", code, stderr.String())
+	}
+
+	recs, _ := readHistory(filepath.Join(dir, "history.jsonl"))
+	if len(recs) != 1 || recs[0].Summary["employees"] != float64(90) || recs[0].Summary["errors"] != float64(0) {
+		t.Fatalf("Unexpected history: %+v", recs)
+	}
+	updated := 0
+	for i := range 90 {
+		if u := ef.Updates(fmt.Sprintf("p%d", i)); len(u) > 0 {
+			updated++
+			if u[0].Email != fmt.Sprintf("user.%d@example.com", i) || len(u[0].CandidateData.CourseAttendance) == 0 {
+				t.Errorf("Unexpected update for p%d: %+v", i, u[0])
+			}
+		}
+	}
+	if updated == 0 || recs[0].Summary["updated"] != float64(updated) {
+		t.Errorf("Expected %v updates in the emulator, got %d", recs[0].Summary["updated"], updated)
+	}
+	if letters, _ := readDeadLetters(dlPath); len(letters) != 0 {
+		t.Errorf("Expected the injected faults to be retried away, got dead letters %+v", letters)
+	}
+}
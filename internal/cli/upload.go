@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/config"
+	"course-sync/internal/export"
+	"course-sync/internal/sftpclient"
+	"course-sync/internal/tracing"
+)
+
+// upload sends already generated files to the SFTP inbound directory, in
+// the order given and over one connection; it stops at the first failure.
+func upload(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	name := fs.String("name", "", "remote file name (single file only; default: the local base name)")
+	wait := fs.Duration("wait", 0, "after uploading, wait up to this long for Eightfold to consume the files (0 = do not wait)")
+	waitInterval := fs.Duration("wait-interval", 30*time.Second, "with -wait, time between checks")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync upload [flags] FILE...")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	files := fs.Args()
+	if len(files) == 0 || (*name != "" && len(files) > 1) || *waitInterval <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, err := r.config(ctx)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]sftpclient.File, len(files))
+	for i, f := range files {
+		batch[i] = sftpclient.File{LocalPath: f, RemoteName: filepath.Base(f)}
+		if *name != "" {
+			batch[i].RemoteName = *name
+		}
+	}
+	results, err := uploadFiles(ctx, r, cfg, batch)
+	r.Summary["uploaded"] = uploadedNames(results)
+	if err != nil || *wait <= 0 {
+		return err
+	}
+	return waitConsumed(ctx, r, cfg, uploadedNames(results), *wait, *waitInterval)
+}
+
+// uploadedNames lists the remote names of the files that were uploaded.
+func uploadedNames(results []sftpclient.Result) []string {
+	names := []string{}
+	for _, res := range results {
+		if res.Err == nil {
+			names = append(names, res.RemoteName)
+		}
+	}
+	return names
+}
+
+// validate checks generated files against the Eightfold feed formats (see
+// export.ValidateFile) and lists every problem found, with its line.
+func validate(ctx context.Context, r *Run, args []string) error {
+	fs := r.flags()
+	asDelete := fs.Bool("delete", false, "check the XML files as course delete feeds, whatever their names (by default only ef_course_delete* files are)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: course-sync validate [flags] FILE...")
+		fs.PrintDefaults()
+	}
+	if err := r.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		var feed export.Feed
+		if *asDelete && strings.EqualFold(filepath.Ext(path), ".xml") {
+			feed = export.FeedDeleteXML
+		}
+		rep, err := export.ValidateFileAs(path, feed)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(r.Stdout, "FAIL %s: %v\n", path, err)
+		case len(rep.Issues) > 0:
+			failed++
+			fmt.Fprintf(r.Stdout, "FAIL %s (%s, %d records): %d problems\n", path, rep.Feed, rep.Records, len(rep.Issues))
+			for _, is := range rep.Issues {
+				fmt.Fprintf(r.Stdout, "     %s\n", is)
+			}
+		default:
+			fmt.Fprintf(r.Stdout, "ok   %s (%s, %d records)\n", path, rep.Feed, rep.Records)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed validation", failed, fs.NArg())
+	}
+	return nil
+}
+
+// validateFeeds checks the .xml and .csv files of an upload batch before
+// they are sent, when sftp.validate_feeds is set, so that a broken feed is
+// caught here rather than when Eightfold rejects the ingestion. Every
+// problem is logged; the error lists the first few of each file.
+func validateFeeds(ctx context.Context, r *Run, cfg config.Config, files []sftpclient.File) (err error) {
+	if !cfg.SFTPValidateFeeds {
+		return nil
+	}
+	_, span := tracer.Start(ctx, "export.validate", trace.WithAttributes(attribute.Int("files", len(files))))
+	defer tracing.End(span, &err)
+
+	var errs []error
+	for _, f := range files {
+		if !export.CanValidate(f.LocalPath) {
+			continue
+		}
+		// Eightfold goes by the name the file is uploaded under.
+		rep, verr := export.ValidateFileAs(f.LocalPath, export.FeedOf(f.RemoteName))
+		if verr == nil {
+			verr = rep.Err()
+		}
+		log := r.Log.With("component", "validate", "file", f.LocalPath)
+		for _, is := range rep.Issues {
+			log.Error("invalid feed record", "line", is.Line, "record", is.Record, "problem", strings.TrimSpace(is.Field+" "+is.Msg))
+		}
+		if verr != nil {
+			errs = append(errs, fmt.Errorf("validate %s: %w", f.LocalPath, verr))
+			continue
+		}
+		log.Info("validated", "feed", rep.Feed, "records", rep.Records)
+	}
+	return errors.Join(errs...)
+}
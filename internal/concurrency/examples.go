@@ -3,8 +3,9 @@ package concurrency
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
+
+	"course-sync/internal/logging"
 )
 
 // Este archivo contiene ejemplos de cómo usar las utilidades de procesamiento paralelo
@@ -87,13 +88,13 @@ func EjemploSincronizacionEmpleados(
 	// Procesar y registrar resultados
 	for i, result := range results {
 		if i < len(errors) && errors[i] != nil {
-			log.Printf("[%d/%d] Error processing user %s: %v", 
-				i+1, len(users), result.Email, errors[i])
+			logging.FromContext(ctx).Warn("error processing user",
+				"user", i+1, "of", len(users), "email", result.Email, logging.Err(errors[i]))
 			continue
 		}
 
-		log.Printf("[%d/%d] Processed user %s in %s with %d courses", 
-			i+1, len(users), result.Email, result.ProcessTime, len(result.Courses))
+		logging.FromContext(ctx).Info("processed user",
+			"user", i+1, "of", len(users), "email", result.Email, "took", result.ProcessTime, "courses", len(result.Courses))
 	}
 
 	return results, errors
@@ -137,11 +138,11 @@ func EjemploSincronizacionCursos(
 			
 			// Registrar resultado
 			if err != nil {
-				log.Printf("[%d/%d] Error processing course %s: %v", 
-					i+1, len(courses), title, err)
+				logging.FromContext(ctx).Warn("error processing course",
+					"course", i+1, "of", len(courses), "title", title, logging.Err(err))
 			} else {
-				log.Printf("[%d/%d] Processed course %s in %s", 
-					i+1, len(courses), title, time.Since(courseStart))
+				logging.FromContext(ctx).Info("processed course",
+					"course", i+1, "of", len(courses), "title", title, "took", time.Since(courseStart))
 			}
 			
 			return err
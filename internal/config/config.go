@@ -1,98 +1,410 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// Config holds every setting the commands need.
+//
+// Each field is addressed by a dotted `key` (as used in the config file and
+// in -set overrides) and by an `env` variable. Fields tagged `secret:"true"`
+// may be given as secret references (see secrets.go); they are resolved by
+// Load and masked by Redacted.
 type Config struct {
 	// Eightfold
-	EightfoldBaseURL     string
-	EightfoldBasicAuth   string
-	EightfoldUser        string
-	EightfoldPass        string
-	EightfoldBearerToken string
+	EightfoldBaseURL     string `key:"eightfold.base_url" env:"EIGHTFOLD_BASE_URL"`
+	EightfoldBasicAuth   string `key:"eightfold.basic_auth" env:"EIGHTFOLD_BASIC_AUTH" secret:"true"`
+	EightfoldUser        string `key:"eightfold.username" env:"EIGHTFOLD_USERNAME"`
+	EightfoldPass        string `key:"eightfold.password" env:"EIGHTFOLD_PASSWORD" secret:"true"`
+	EightfoldBearerToken string `key:"eightfold.bearer_token" env:"EIGHTFOLD_BEARER_TOKEN" secret:"true"`
+	EightfoldMaxPageSize int    `key:"eightfold.max_page_size" env:"EIGHTFOLD_MAX_PAGE_SIZE" default:"100"`
 
 	// Udemy
-	UdemyBaseURL      string
-	UdemyClientID     string
-	UdemyClientSecret string
+	UdemyBaseURL      string `key:"udemy.base_url" env:"UDEMY_BASE_URL"`
+	UdemyClientID     string `key:"udemy.client_id" env:"UDEMY_CLIENT_ID"`
+	UdemyClientSecret string `key:"udemy.client_secret" env:"UDEMY_CLIENT_SECRET" secret:"true"`
 
 	// Pluralsight
-	PluralsightBaseURL string
-	PluralsightToken   string
-
-	// SFTP
-	SFTPHost                  string
-	SFTPPort                  int
-	SFTPUser                  string
-	SFTPPass                  string
-	SFTPDir                   string
-	SFTPInsecureIgnoreHostKey bool
-	SFTPHostKey               string
-	SFTPKeyPath               string
-	SFTPKeyPassphrase         string
-}
-
-func Load() Config {
-	return Config{
-		// Eightfold
-		EightfoldBaseURL:     os.Getenv("EIGHTFOLD_BASE_URL"),
-		EightfoldBasicAuth:   os.Getenv("EIGHTFOLD_BASIC_AUTH"),
-		EightfoldUser:        os.Getenv("EIGHTFOLD_USERNAME"),
-		EightfoldPass:        os.Getenv("EIGHTFOLD_PASSWORD"),
-		EightfoldBearerToken: os.Getenv("EIGHTFOLD_BEARER_TOKEN"),
-
-		// Udemy
-		UdemyBaseURL:      os.Getenv("UDEMY_BASE_URL"),
-		UdemyClientID:     os.Getenv("UDEMY_CLIENT_ID"),
-		UdemyClientSecret: os.Getenv("UDEMY_CLIENT_SECRET"),
-
-		// Pluralsight
-		PluralsightBaseURL: os.Getenv("PLURALSIGHT_GQL_URL"),
-		PluralsightToken:   os.Getenv("PLURALSIGHT_TOKEN"),
-
-		// SFTP
-		SFTPHost:                  getenv("SFTP_HOST", ""),
-		SFTPPort:                  getenvInt("SFTP_PORT", 22),
-		SFTPUser:                  getenv("SFTP_USER", ""),
-		SFTPPass:                  getenv("SFTP_PASS", ""),
-		SFTPDir:                   getenv("SFTP_DIR", "/inbound"),
-		SFTPInsecureIgnoreHostKey: getenvBool("SFTP_INSECURE_IGNORE_HOSTKEY", true),
-		SFTPHostKey:               os.Getenv("SFTP_HOST_KEY"),
-		SFTPKeyPath:               os.Getenv("SFTP_KEY_PATH"),
-		SFTPKeyPassphrase:         os.Getenv("SFTP_KEY_PASSPHRASE"),
+	PluralsightBaseURL string `key:"pluralsight.gql_url" env:"PLURALSIGHT_GQL_URL"`
+	PluralsightToken   string `key:"pluralsight.token" env:"PLURALSIGHT_TOKEN" secret:"true"`
+
+	// SFTP. With sftp.validate_feeds, .xml and .csv files are checked
+	// against the Eightfold feed formats before they are uploaded (see
+	// `course-sync validate`). Server host keys are checked against
+	// sftp.known_hosts, and with sftp.trust_on_first_use the key of a host
+	// seen for the first time is recorded there.
+	SFTPHost                  string `key:"sftp.host" env:"SFTP_HOST"`
+	SFTPPort                  int    `key:"sftp.port" env:"SFTP_PORT" default:"22"`
+	SFTPUser                  string `key:"sftp.user" env:"SFTP_USER"`
+	SFTPPass                  string `key:"sftp.pass" env:"SFTP_PASS" secret:"true"`
+	SFTPDir                   string `key:"sftp.dir" env:"SFTP_DIR" default:"/inbound"`
+	SFTPInsecureIgnoreHostKey bool   `key:"sftp.insecure_ignore_host_key" env:"SFTP_INSECURE_IGNORE_HOSTKEY"`
+	SFTPHostKey               string `key:"sftp.host_key" env:"SFTP_HOST_KEY"`
+	SFTPKnownHosts            string `key:"sftp.known_hosts" env:"SFTP_KNOWN_HOSTS" default:"out/known_hosts"`
+	SFTPTrustOnFirstUse       bool   `key:"sftp.trust_on_first_use" env:"SFTP_TRUST_ON_FIRST_USE" default:"true"`
+	SFTPKeyPath               string `key:"sftp.key_path" env:"SFTP_KEY_PATH"`
+	SFTPKeyPassphrase         string `key:"sftp.key_passphrase" env:"SFTP_KEY_PASSPHRASE" secret:"true"`
+	SFTPChecksum              bool   `key:"sftp.checksum" env:"SFTP_CHECKSUM" default:"true"`
+	SFTPVerify                bool   `key:"sftp.verify" env:"SFTP_VERIFY"`
+	SFTPAttempts              int    `key:"sftp.attempts" env:"SFTP_ATTEMPTS" default:"3"`
+	SFTPValidateFeeds         bool   `key:"sftp.validate_feeds" env:"SFTP_VALIDATE_FEEDS" default:"true"`
+
+	// PGP: with pgp.encrypt, files are encrypted to the pgp.recipient_key
+	// public keys (comma-separated files) before upload and sent as
+	// <name>.pgp, or <name>.asc with pgp.armor; pgp.signing_key, a private
+	// key, also signs them. pgp.decryption_key is only read by
+	// `course-sync pgp decrypt`.
+	PGPEncrypt              bool   `key:"pgp.encrypt" env:"PGP_ENCRYPT"`
+	PGPRecipientKey         string `key:"pgp.recipient_key" env:"PGP_RECIPIENT_KEY"`
+	PGPSigningKey           string `key:"pgp.signing_key" env:"PGP_SIGNING_KEY"`
+	PGPSigningPassphrase    string `key:"pgp.signing_passphrase" env:"PGP_SIGNING_PASSPHRASE" secret:"true"`
+	PGPArmor                bool   `key:"pgp.armor" env:"PGP_ARMOR"`
+	PGPDecryptionKey        string `key:"pgp.decryption_key" env:"PGP_DECRYPTION_KEY"`
+	PGPDecryptionPassphrase string `key:"pgp.decryption_passphrase" env:"PGP_DECRYPTION_PASSPHRASE" secret:"true"`
+
+	// Archive: a gzip copy of every generated file, with a SHA-256
+	// manifest, under archive.dir/<run>/ (empty disables it); run
+	// directories older than archive.retention are removed (0 keeps them).
+	// Every upload is appended to archive.audit_log.
+	ArchiveDir       string        `key:"archive.dir" env:"ARCHIVE_DIR" default:"out/archive"`
+	ArchiveRetention time.Duration `key:"archive.retention" env:"ARCHIVE_RETENTION" default:"2160h"`
+	ArchiveAuditLog  string        `key:"archive.audit_log" env:"ARCHIVE_AUDIT_LOG" default:"out/uploads.jsonl"`
+
+	// Runs that write the same files hold a lock file in lock.dir (see the
+	// command table in internal/cli), so runs started by serve, by cron and
+	// by hand never overlap. A relative lock.dir is resolved against the
+	// working directory; runs started from different directories need an
+	// absolute one to share their locks.
+	LockDir string `key:"lock.dir" env:"COURSE_SYNC_LOCK_DIR" default:"out"`
+
+	// Serve (daemon mode). Schedules are cron specs (see internal/schedule);
+	// an empty schedule leaves the job to manual triggers. *Args are extra
+	// flags for the job, e.g. "-udemy-max-pages 0 -ps-max-pages 0".
+	ServeListen            string        `key:"serve.listen" env:"SERVE_LISTEN" default:"127.0.0.1:8089"`
+	ServeCoursesSchedule   string        `key:"serve.courses_schedule" env:"SERVE_COURSES_SCHEDULE"`
+	ServeCoursesArgs       string        `key:"serve.courses_args" env:"SERVE_COURSES_ARGS"`
+	ServeEmployeesSchedule string        `key:"serve.employees_schedule" env:"SERVE_EMPLOYEES_SCHEDULE"`
+	ServeEmployeesArgs     string        `key:"serve.employees_args" env:"SERVE_EMPLOYEES_ARGS"`
+	ServeTimezone          string        `key:"serve.timezone" env:"SERVE_TIMEZONE"`
+	ServeJitter            time.Duration `key:"serve.jitter" env:"SERVE_JITTER" default:"1m"`
+	ServeShutdownGrace     time.Duration `key:"serve.shutdown_grace" env:"SERVE_SHUTDOWN_GRACE" default:"15m"`
+
+	// sources records where each key's value came from, for `config check`
+	// and error messages.
+	sources map[string]string
+}
+
+// Options selects the layers Load reads. Later layers win:
+//
+//	defaults < file < file commands.<cmd> < environments.<env> < environments.<env>.commands.<cmd> < env vars < Overrides
+type Options struct {
+	// Path of a YAML or JSON config file. Empty means $COURSE_SYNC_CONFIG;
+	// with neither set no file is read.
+	Path string
+	// Environment selects environments.<name> from the file. Empty means
+	// $COURSE_SYNC_ENV, then the file's top-level `environment`.
+	Environment string
+	// Command selects the commands.<name> profile, e.g. "export-csv".
+	Command string
+	// Profiles are the valid commands.<name> profile names; other names in
+	// the file are rejected. Empty accepts any name.
+	Profiles []string
+	// Overrides are key=value settings from flags (-set).
+	Overrides map[string]string
+}
+
+const (
+	configPathEnv = "COURSE_SYNC_CONFIG"
+	configEnvEnv  = "COURSE_SYNC_ENV"
+
+	sourceDefault = "default"
+	sourceFlag    = "flag -set"
+)
+
+// field describes one Config field, taken from its struct tags.
+type field struct {
+	index  int
+	name   string
+	key    string
+	env    string
+	def    string
+	secret bool
+	typ    reflect.Type
+}
+
+var fields = parseFields()
+
+func parseFields() []field {
+	t := reflect.TypeOf(Config{})
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("key")
+		if key == "" {
+			continue
+		}
+		out = append(out, field{
+			index:  i,
+			name:   sf.Name,
+			key:    key,
+			env:    sf.Tag.Get("env"),
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret") == "true",
+			typ:    sf.Type,
+		})
 	}
+	return out
 }
 
-func getenv(k, def string) string {
-	v := os.Getenv(k)
-	if v == "" {
-		return def
+func lookupField(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
 	}
-	return v
+	return field{}, false
 }
 
-func getenvInt(key string, def int) int {
-	v := os.Getenv(key)
-	if v == "" {
-		return def
+// Keys returns every setting key, sorted.
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.key)
 	}
-	i, err := strconv.Atoi(v)
-	if err != nil {
-		return def
+	sort.Strings(keys)
+	return keys
+}
+
+// Load reads the configuration from $COURSE_SYNC_CONFIG (if set) and the
+// environment. See LoadWith.
+func Load() (Config, error) {
+	return LoadWith(Options{})
+}
+
+// LoadWith is LoadContext without a deadline for secret backends.
+func LoadWith(opts Options) (Config, error) {
+	return LoadContext(context.Background(), opts)
+}
+
+// LoadContext layers defaults, the config file, environment variables and
+// overrides, parses every value strictly, resolves secret references and
+// validates the result. All problems found are reported together. ctx
+// bounds the secret backends (an `exec:` helper is killed when it is done).
+func LoadContext(ctx context.Context, opts Options) (Config, error) {
+	type value struct{ v, source string }
+	raw := map[string]value{}
+	for _, f := range fields {
+		if f.def != "" {
+			raw[f.key] = value{f.def, sourceDefault}
+		}
 	}
-	return i
+
+	path := opts.Path
+	if path == "" {
+		path = os.Getenv(configPathEnv)
+	}
+	env := opts.Environment
+	if env == "" {
+		env = os.Getenv(configEnvEnv)
+	}
+
+	if path != "" {
+		layers, err := readFile(path, env, opts.Command, opts.Profiles)
+		if err != nil {
+			return Config{}, err
+		}
+		for _, l := range layers {
+			for k, v := range l.values {
+				raw[k] = value{v, l.source}
+			}
+		}
+	} else if env != "" {
+		return Config{}, fmt.Errorf("config: environment %q selected but no config file given (-config or %s)", env, configPathEnv)
+	}
+
+	for _, f := range fields {
+		if v := os.Getenv(f.env); v != "" {
+			raw[f.key] = value{v, "env " + f.env}
+		}
+	}
+
+	var errs []error
+	for k, v := range opts.Overrides {
+		if _, ok := lookupField(k); !ok {
+			errs = append(errs, fmt.Errorf("config: %s: unknown setting (from %s)", k, sourceFlag))
+			continue
+		}
+		raw[k] = value{v, sourceFlag}
+	}
+
+	cfg := Config{sources: map[string]string{}}
+	rv := reflect.ValueOf(&cfg).Elem()
+	for _, f := range fields {
+		val, ok := raw[f.key]
+		if !ok {
+			continue
+		}
+		cfg.sources[f.key] = val.source
+		if err := setField(rv.Field(f.index), f.typ, val.v); err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %v (from %s)", f.key, err, val.source))
+		}
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	if err := cfg.resolveSecrets(ctx); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
 }
 
-func getenvBool(key string, def bool) bool {
-	v := os.Getenv(key)
-	if v == "" {
-		return def
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setField(v reflect.Value, typ reflect.Type, s string) error {
+	switch {
+	case typ == durationType:
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+	case typ.Kind() == reflect.String:
+		v.SetString(s)
+	case typ.Kind() == reflect.Int:
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(int64(i))
+	case typ.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", typ)
+	}
+	return nil
+}
+
+// resolveSecrets replaces secret references in the secret fields with their values.
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	v := reflect.ValueOf(c).Elem()
+	var errs []error
+	for _, f := range fields {
+		if !f.secret {
+			continue
+		}
+		fv := v.Field(f.index)
+		resolved, err := ResolveSecret(ctx, fv.String())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %w%s", f.key, err, c.from(f.key)))
+			continue
+		}
+		fv.SetString(resolved)
 	}
-	b, err := strconv.ParseBool(v)
-	if err != nil {
+	return errors.Join(errs...)
+}
+
+// from returns " (from <source>)" for key, or "" when the source is unknown.
+func (c Config) from(key string) string {
+	if s := c.sources[key]; s != "" {
+		return " (from " + s + ")"
+	}
+	return ""
+}
+
+// SFTPKnownHostsFiles splits sftp.known_hosts, a comma-separated list of
+// known_hosts files.
+func (c Config) SFTPKnownHostsFiles() []string {
+	return splitList(c.SFTPKnownHosts)
+}
+
+// PGPRecipientKeyFiles splits pgp.recipient_key into file names.
+func (c Config) PGPRecipientKeyFiles() []string {
+	return splitList(c.PGPRecipientKey)
+}
+
+// splitList splits a comma-separated setting, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of c with every secret field masked, for logging.
+// Empty secrets stay empty so it is still visible whether one was set.
+func (c Config) Redacted() Config {
+	v := reflect.ValueOf(&c).Elem()
+	for _, f := range fields {
+		if f.secret && v.Field(f.index).String() != "" {
+			v.Field(f.index).SetString(redacted)
+		}
+	}
+	return c
+}
+
+// Setting is one resolved, redacted configuration value.
+type Setting struct {
+	Key    string
+	Env    string
+	Value  string
+	Source string // "default", "file ...", "env NAME", "flag -set" or "" when unset
+}
+
+// Settings lists every setting of the redacted config in key order.
+func (c Config) Settings() []Setting {
+	r := reflect.ValueOf(c.Redacted())
+	out := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, Setting{
+			Key:    f.key,
+			Env:    f.env,
+			Value:  fmt.Sprint(r.Field(f.index).Interface()),
+			Source: c.sources[f.key],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// String renders the redacted config, so printing a Config never leaks secrets.
+func (c Config) String() string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, s := range c.Settings() {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%s=%s", s.Key, s.Value)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func getenv(k, def string) string {
+	v := os.Getenv(k)
+	if v == "" {
 		return def
 	}
-	return b
+	return v
 }
@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetenv(t *testing.T) {
@@ -24,63 +27,130 @@ func TestGetenv(t *testing.T) {
 	os.Unsetenv("TEST_GETENV")
 }
 
-func TestGetenvInt(t *testing.T) {
-	// Test with empty environment variable
-	os.Unsetenv("TEST_GETENV_INT")
-	result := getenvInt("TEST_GETENV_INT", 42)
-	if result != 42 {
-		t.Errorf("Expected default value 42, got %d", result)
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SFTP_PORT", "not-an-int")
+	t.Setenv("SFTP_INSECURE_IGNORE_HOSTKEY", "maybe")
+	t.Setenv("UDEMY_BASE_URL", "udemy.test")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected invalid values to be rejected")
+	}
+	for _, want := range []string{
+		`sftp.port: invalid integer "not-an-int" (from env SFTP_PORT)`,
+		`sftp.insecure_ignore_host_key: invalid boolean "maybe"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got:\n%v", want, err)
+		}
 	}
 
-	// Test with valid integer
-	os.Setenv("TEST_GETENV_INT", "100")
-	result = getenvInt("TEST_GETENV_INT", 42)
-	if result != 100 {
-		t.Errorf("Expected 100, got %d", result)
+	t.Setenv("SFTP_PORT", "70000")
+	t.Setenv("SFTP_INSECURE_IGNORE_HOSTKEY", "")
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "sftp.port: must be between 1 and 65535") ||
+		!strings.Contains(err.Error(), "udemy.base_url: expected an http(s) URL") {
+		t.Errorf("Expected validation errors, got %v", err)
 	}
 
-	// Test with invalid integer
-	os.Setenv("TEST_GETENV_INT", "not-an-int")
-	result = getenvInt("TEST_GETENV_INT", 42)
-	if result != 42 {
-		t.Errorf("Expected default value 42, got %d", result)
+	t.Setenv("EIGHTFOLD_BASE_URL", "eightfold.test")
+	t.Setenv("PLURALSIGHT_GQL_URL", "pluralsight.test")
+	_, first := Load()
+	for range 20 {
+		if _, err := Load(); err == nil || err.Error() != first.Error() {
+			t.Fatalf("Expected the errors in the same order every time, got\n%v\nthen\n%v", first, err)
+		}
+	}
+	eightfold := strings.Index(first.Error(), "eightfold.base_url")
+	udemy := strings.Index(first.Error(), "udemy.base_url")
+	pluralsight := strings.Index(first.Error(), "pluralsight.gql_url")
+	if eightfold < 0 || eightfold > udemy || udemy > pluralsight {
+		t.Errorf("Expected the URL errors in key order, got %v", first)
 	}
+}
 
-	// Clean up
-	os.Unsetenv("TEST_GETENV_INT")
+func TestLoadServeSettings(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.ServeJitter != time.Minute || cfg.ServeShutdownGrace != 15*time.Minute {
+		t.Errorf("Unexpected serve defaults: jitter %v, grace %v", cfg.ServeJitter, cfg.ServeShutdownGrace)
+	}
+
+	t.Setenv("SERVE_JITTER", "soon")
+	t.Setenv("SERVE_COURSES_SCHEDULE", "0 25 * * *")
+	t.Setenv("SERVE_TIMEZONE", "Mars/Olympus")
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), `serve.jitter: invalid duration "soon"`) {
+		t.Errorf("Expected an invalid duration, got %v", err)
+	}
+
+	t.Setenv("SERVE_JITTER", "30s")
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "serve.courses_schedule: schedule:") ||
+		!strings.Contains(err.Error(), "serve.timezone:") {
+		t.Errorf("Expected schedule and timezone errors, got %v", err)
+	}
 }
 
-func TestGetenvBool(t *testing.T) {
-	// Test with empty environment variable
-	os.Unsetenv("TEST_GETENV_BOOL")
-	result := getenvBool("TEST_GETENV_BOOL", true)
-	if result != true {
-		t.Errorf("Expected default value true, got %v", result)
+func TestRequire(t *testing.T) {
+	cfg := Config{
+		EightfoldBaseURL:     "https://ef.test",
+		EightfoldBearerToken: "tok",
+		SFTPHost:             "sftp.test",
+	}
+	if err := cfg.Require("eightfold"); err != nil {
+		t.Errorf("Expected bearer token to satisfy eightfold, got %v", err)
 	}
 
-	// Test with valid boolean (true)
-	os.Setenv("TEST_GETENV_BOOL", "true")
-	result = getenvBool("TEST_GETENV_BOOL", false)
-	if result != true {
-		t.Errorf("Expected true, got %v", result)
+	err := cfg.Require("udemy", "sftp")
+	if err == nil {
+		t.Fatal("Expected missing settings")
+	}
+	for _, want := range []string{"udemy.base_url", "udemy.client_id", "sftp.host and sftp.user", "sftp.pass or sftp.key_path", "sftp.known_hosts or sftp.host_key"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got:\n%v", want, err)
+		}
 	}
 
-	// Test with valid boolean (false)
-	os.Setenv("TEST_GETENV_BOOL", "false")
-	result = getenvBool("TEST_GETENV_BOOL", true)
-	if result != false {
-		t.Errorf("Expected false, got %v", result)
+	for cmd, sections := range Requirements {
+		if err := (Config{}).Require(sections...); err == nil || strings.Contains(err.Error(), "unknown section") {
+			t.Errorf("%s: unexpected requirement result %v", cmd, err)
+		}
 	}
+}
 
-	// Test with invalid boolean
-	os.Setenv("TEST_GETENV_BOOL", "not-a-bool")
-	result = getenvBool("TEST_GETENV_BOOL", true)
-	if result != true {
-		t.Errorf("Expected default value true, got %v", result)
+func TestSFTPHostKeySettings(t *testing.T) {
+	cfg := Config{SFTPHost: "sftp.test", SFTPUser: "u", SFTPPass: "p", SFTPKnownHosts: " a/known_hosts, ,b/known_hosts"}
+	if got := cfg.SFTPKnownHostsFiles(); len(got) != 2 || got[0] != "a/known_hosts" || got[1] != "b/known_hosts" {
+		t.Errorf("SFTPKnownHostsFiles() = %q", got)
+	}
+	if err := cfg.Require("sftp"); err != nil {
+		t.Errorf("Expected known_hosts to satisfy sftp, got %v", err)
 	}
 
-	// Clean up
-	os.Unsetenv("TEST_GETENV_BOOL")
+	clearEnv(t)
+	t.Setenv("SFTP_HOST", "sftp.test")
+	t.Setenv("SFTP_USER", "u")
+	t.Setenv("SFTP_PASS", "p")
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.SFTPKnownHosts != "out/known_hosts" || !loaded.SFTPTrustOnFirstUse {
+		t.Errorf("Expected out/known_hosts with trust on first use by default, got %q, %v", loaded.SFTPKnownHosts, loaded.SFTPTrustOnFirstUse)
+	}
+	if err := loaded.Require("sftp"); err != nil {
+		t.Errorf("Expected the default known_hosts to satisfy sftp, got %v", err)
+	}
+
+	err = Config{SFTPTrustOnFirstUse: true}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "sftp.trust_on_first_use: needs sftp.known_hosts") {
+		t.Errorf("Expected trust on first use to need a known_hosts file, got %v", err)
+	}
 }
 
 func TestLoad(t *testing.T) {
@@ -118,7 +188,10 @@ func TestLoad(t *testing.T) {
 	os.Setenv("SFTP_INSECURE_IGNORE_HOSTKEY", "false")
 
 	// Test Load function
-	cfg := Load()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
 
 	// Verify loaded values
 	if cfg.EightfoldBaseURL != "https://eightfold.test" {
@@ -139,15 +212,18 @@ func TestLoad(t *testing.T) {
 	os.Unsetenv("SFTP_DIR")
 	os.Unsetenv("SFTP_INSECURE_IGNORE_HOSTKEY")
 
-	cfg = Load()
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
 	if cfg.SFTPPort != 22 {
 		t.Errorf("Expected default SFTPPort to be 22, got %d", cfg.SFTPPort)
 	}
 	if cfg.SFTPDir != "/inbound" {
 		t.Errorf("Expected default SFTPDir to be '/inbound', got '%s'", cfg.SFTPDir)
 	}
-	if cfg.SFTPInsecureIgnoreHostKey != true {
-		t.Errorf("Expected default SFTPInsecureIgnoreHostKey to be true, got %v", cfg.SFTPInsecureIgnoreHostKey)
+	if cfg.SFTPInsecureIgnoreHostKey != false {
+		t.Errorf("Expected default SFTPInsecureIgnoreHostKey to be false, got %v", cfg.SFTPInsecureIgnoreHostKey)
 	}
 
 	// Restore original environment
@@ -159,3 +235,56 @@ func TestLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestArchiveSettings(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.ArchiveDir != "out/archive" || cfg.ArchiveRetention != 90*24*time.Hour || cfg.ArchiveAuditLog != "out/uploads.jsonl" {
+		t.Errorf("Unexpected archive defaults: %q, %v, %q", cfg.ArchiveDir, cfg.ArchiveRetention, cfg.ArchiveAuditLog)
+	}
+
+	cfg, err = LoadWith(Options{Overrides: map[string]string{"archive.dir": "", "archive.retention": "0s"}})
+	if err != nil {
+		t.Fatalf("LoadWith() error: %v", err)
+	}
+	if cfg.ArchiveDir != "" || cfg.ArchiveRetention != 0 {
+		t.Errorf("Expected archiving off and no retention, got %q, %v", cfg.ArchiveDir, cfg.ArchiveRetention)
+	}
+
+	t.Setenv("ARCHIVE_RETENTION", "-24h")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "archive.retention: must not be negative") {
+		t.Errorf("Expected a negative retention to be rejected, got %v", err)
+	}
+}
+
+func TestPGPSettings(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PGP_ENCRYPT", "true")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "pgp.encrypt: needs pgp.recipient_key") {
+		t.Errorf("Expected encryption without a recipient to be rejected, got %v", err)
+	}
+
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.asc"), filepath.Join(dir, "b.asc")
+	for _, f := range []string{a, b} {
+		if err := os.WriteFile(f, []byte("key"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PGP_RECIPIENT_KEY", a+", "+b)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cfg.PGPRecipientKeyFiles(); len(got) != 2 || got[1] != b {
+		t.Errorf("PGPRecipientKeyFiles() = %v", got)
+	}
+
+	t.Setenv("PGP_SIGNING_KEY", filepath.Join(dir, "missing.asc"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "pgp.signing_key:") {
+		t.Errorf("Expected a missing signing key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Config file
+
+A YAML (or JSON, by .json extension) file with the same keys as Config,
+grouped by section, plus named environments and per-command profiles:
+
+	environment: sandbox          # default when -env / COURSE_SYNC_ENV is not given
+	eightfold:
+	  base_url: https://apiv2.eightfold.ai
+	  password: file:/run/secrets/eightfold_password
+	sftp:
+	  host: sftp.eightfold.ai
+	commands:
+	  export-csv:
+	    sftp:
+	      dir: /inbound/courses
+	environments:
+	  sandbox:
+	    sftp:
+	      dir: /ef-sftp/femsa-sandbox/home/inbound
+	  prod:
+	    sftp:
+	      known_hosts: /etc/course-sync/known_hosts
+
+Unknown keys and command profiles are rejected so typos do not fall back to
+defaults silently.
+*/
+
+// layer is the flattened key -> value set read from one part of the file.
+type layer struct {
+	source string
+	values map[string]string
+}
+
+// readFile parses path and returns its layers in precedence order for the
+// given environment and command. profiles, if any, are the command profile
+// names the file may use.
+func readFile(path, env, command string, profiles []string) ([]layer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		err = dec.Decode(&doc)
+	} else {
+		err = yaml.Unmarshal(b, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	f := &fileParser{path: path, profiles: profiles}
+	base := f.profile(doc, "")
+
+	if env == "" {
+		if v, ok := doc["environment"]; ok {
+			s, ok := v.(string)
+			if !ok {
+				f.fail("environment", "expected a string")
+			}
+			env = s
+		}
+	}
+	envs := map[string]map[string]any{}
+	if v, ok := doc["environments"]; ok {
+		m, ok := v.(map[string]any)
+		if !ok {
+			f.fail("environments", "expected a mapping of environment names")
+		}
+		for _, name := range sortedKeys(m) {
+			pm, ok := m[name].(map[string]any)
+			if !ok {
+				f.fail("environments."+name, "expected a mapping")
+				continue
+			}
+			envs[name] = pm
+		}
+	}
+
+	layers := []layer{base.settings}
+	if cmd, ok := base.commands[command]; ok && command != "" {
+		layers = append(layers, cmd)
+	}
+	if env != "" {
+		p, ok := envs[env]
+		if !ok {
+			f.fail("environments", fmt.Sprintf("unknown environment %q (defined: %s)", env, strings.Join(sortedKeys(envs), ", ")))
+		} else {
+			ep := f.profile(p, "environments."+env)
+			layers = append(layers, ep.settings)
+			if cmd, ok := ep.commands[command]; ok && command != "" {
+				layers = append(layers, cmd)
+			}
+		}
+	}
+	// Validate the other environments too, so a typo in prod is caught while testing sandbox.
+	for _, name := range sortedKeys(envs) {
+		if name != env {
+			f.profile(envs[name], "environments."+name)
+		}
+	}
+
+	if len(f.errs) > 0 {
+		return nil, fmt.Errorf("config: %s:\n  %s", path, strings.Join(f.errs, "\n  "))
+	}
+	return layers, nil
+}
+
+type fileParser struct {
+	path     string
+	profiles []string
+	errs     []string
+}
+
+func (f *fileParser) fail(at, msg string) {
+	f.errs = append(f.errs, at+": "+msg)
+}
+
+type profile struct {
+	settings layer
+	commands map[string]layer
+}
+
+// profile reads the sections and commands of a top-level document or an
+// environment. prefix is the document path used in messages and sources.
+func (f *fileParser) profile(m map[string]any, prefix string) profile {
+	p := profile{
+		settings: f.settings(m, prefix, true),
+		commands: map[string]layer{},
+	}
+	at := join(prefix, "commands")
+	if v, ok := m["commands"]; ok {
+		cm, ok := v.(map[string]any)
+		if !ok {
+			f.fail(at, "expected a mapping of command names")
+			return p
+		}
+		for _, name := range sortedKeys(cm) {
+			if len(f.profiles) > 0 && !slices.Contains(f.profiles, name) {
+				f.fail(at+"."+name, fmt.Sprintf("unknown command profile (known: %s)", strings.Join(f.profiles, ", ")))
+				continue
+			}
+			sm, ok := cm[name].(map[string]any)
+			if !ok {
+				f.fail(at+"."+name, "expected a mapping")
+				continue
+			}
+			p.commands[name] = f.settings(sm, at+"."+name, false)
+		}
+	}
+	return p
+}
+
+// settings flattens section.key values. At the top of a profile the
+// reserved keys (environment, environments, commands) are skipped.
+func (f *fileParser) settings(m map[string]any, prefix string, top bool) layer {
+	source := "file " + f.path
+	if prefix != "" {
+		source += " [" + prefix + "]"
+	}
+	l := layer{source: source, values: map[string]string{}}
+
+	for _, section := range sortedKeys(m) {
+		v := m[section]
+		if top && (section == "commands" || (prefix == "" && (section == "environment" || section == "environments"))) {
+			continue
+		}
+		at := join(prefix, section)
+		sm, ok := v.(map[string]any)
+		if !ok {
+			f.fail(at, "unknown section")
+			continue
+		}
+		for _, name := range sortedKeys(sm) {
+			val := sm[name]
+			key := section + "." + name
+			if _, ok := lookupField(key); !ok {
+				f.fail(join(prefix, key), "unknown setting")
+				continue
+			}
+			s, ok := scalar(val)
+			if !ok {
+				f.fail(join(prefix, key), "expected a single value")
+				continue
+			}
+			if val != nil {
+				l.values[key] = s
+			}
+		}
+	}
+	return l
+}
+
+func scalar(v any) (string, bool) {
+	switch x := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return x, true
+	case bool, int, int64, uint64, float64, json.Number:
+		return fmt.Sprint(x), true
+	}
+	return "", false
+}
+
+// sortedKeys returns the keys of m in order, so that problems are reported
+// in the same order every run.
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
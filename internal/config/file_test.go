@@ -0,0 +1,288 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigYAML = `
+environment: sandbox
+eightfold:
+  base_url: https://apiv2.eightfold.ai
+  username: svc-sync
+sftp:
+  host: sftp.eightfold.ai
+  port: 22
+  dir: /inbound
+commands:
+  export-csv:
+    sftp:
+      dir: /inbound/courses
+environments:
+  sandbox:
+    sftp:
+      dir: /ef-sftp/femsa-sandbox/home/inbound
+      insecure_ignore_host_key: true
+    commands:
+      export-xml:
+        sftp:
+          dir: /ef-sftp/femsa-sandbox/home/xml
+  prod:
+    eightfold:
+      base_url: https://api.eightfold.ai
+    sftp:
+      insecure_ignore_host_key: false
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// clearEnv unsets every config variable for the duration of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, f := range fields {
+		t.Setenv(f.env, "")
+	}
+	t.Setenv(configPathEnv, "")
+	t.Setenv(configEnvEnv, "")
+}
+
+func TestLoadWithFileLayers(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, "course-sync.yaml", testConfigYAML)
+
+	testCases := []struct {
+		name    string
+		opts    Options
+		env     map[string]string
+		wantDir string
+		wantURL string
+		wantIns bool
+		dirSrc  string
+	}{
+		{
+			name:    "default environment from file",
+			opts:    Options{Path: path},
+			wantDir: "/ef-sftp/femsa-sandbox/home/inbound",
+			wantURL: "https://apiv2.eightfold.ai",
+			wantIns: true,
+			dirSrc:  "[environments.sandbox]",
+		},
+		{
+			name:    "environment command profile wins over environment",
+			opts:    Options{Path: path, Command: "export-xml"},
+			wantDir: "/ef-sftp/femsa-sandbox/home/xml",
+			wantURL: "https://apiv2.eightfold.ai",
+			wantIns: true,
+			dirSrc:  "[environments.sandbox.commands.export-xml]",
+		},
+		{
+			name:    "prod keeps the base command profile",
+			opts:    Options{Path: path, Environment: "prod", Command: "export-csv"},
+			wantDir: "/inbound/courses",
+			wantURL: "https://api.eightfold.ai",
+			wantIns: false,
+			dirSrc:  "[commands.export-csv]",
+		},
+		{
+			name:    "environment variable wins over file",
+			opts:    Options{Path: path, Environment: "prod"},
+			env:     map[string]string{"SFTP_DIR": "/from-env"},
+			wantDir: "/from-env",
+			wantURL: "https://api.eightfold.ai",
+			dirSrc:  "env SFTP_DIR",
+		},
+		{
+			name:    "flag wins over environment variable",
+			opts:    Options{Path: path, Overrides: map[string]string{"sftp.dir": "/from-flag"}},
+			env:     map[string]string{"SFTP_DIR": "/from-env"},
+			wantDir: "/from-flag",
+			wantURL: "https://apiv2.eightfold.ai",
+			wantIns: true,
+			dirSrc:  "flag -set",
+		},
+		{
+			name:    "environment selected through COURSE_SYNC_ENV",
+			opts:    Options{Path: path},
+			env:     map[string]string{configEnvEnv: "prod"},
+			wantDir: "/inbound",
+			wantURL: "https://api.eightfold.ai",
+			dirSrc:  "file " + path,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := LoadWith(tc.opts)
+			if err != nil {
+				t.Fatalf("LoadWith() error: %v", err)
+			}
+			if cfg.SFTPDir != tc.wantDir {
+				t.Errorf("SFTPDir = %q, want %q", cfg.SFTPDir, tc.wantDir)
+			}
+			if cfg.EightfoldBaseURL != tc.wantURL {
+				t.Errorf("EightfoldBaseURL = %q, want %q", cfg.EightfoldBaseURL, tc.wantURL)
+			}
+			if cfg.SFTPInsecureIgnoreHostKey != tc.wantIns {
+				t.Errorf("SFTPInsecureIgnoreHostKey = %v, want %v", cfg.SFTPInsecureIgnoreHostKey, tc.wantIns)
+			}
+			if src := cfg.sources["sftp.dir"]; !strings.HasSuffix(src, tc.dirSrc) {
+				t.Errorf("sftp.dir source = %q, want suffix %q", src, tc.dirSrc)
+			}
+		})
+	}
+}
+
+func TestLoadWithJSONFile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, "course-sync.json", `{
+		"sftp": {"host": "sftp.test", "port": 2222, "insecure_ignore_host_key": false},
+		"udemy": {"client_secret": "literal-secret"}
+	}`)
+
+	cfg, err := LoadWith(Options{Path: path})
+	if err != nil {
+		t.Fatalf("LoadWith() error: %v", err)
+	}
+	if cfg.SFTPHost != "sftp.test" || cfg.SFTPPort != 2222 || cfg.SFTPInsecureIgnoreHostKey {
+		t.Errorf("Unexpected config: %v", cfg)
+	}
+	if cfg.UdemyClientSecret != "literal-secret" {
+		t.Errorf("Expected client secret from file, got %q", cfg.UdemyClientSecret)
+	}
+}
+
+func TestLoadWithFileErrors(t *testing.T) {
+	clearEnv(t)
+
+	testCases := []struct {
+		name    string
+		file    string
+		opts    Options
+		wantErr []string
+	}{
+		{
+			name: "unknown keys are reported with their path",
+			file: `
+sftp:
+  prot: 22
+ftp:
+  host: x
+environments:
+  prod:
+    udemy:
+      clientid: x
+`,
+			wantErr: []string{"sftp.prot: unknown setting", "ftp.host: unknown setting", "environments.prod.udemy.clientid: unknown setting"},
+		},
+		{
+			name:    "unknown environment",
+			file:    "environments:\n  sandbox: {}\n  prod: {}\n",
+			opts:    Options{Environment: "staging"},
+			wantErr: []string{`unknown environment "staging" (defined: prod, sandbox)`},
+		},
+		{
+			name:    "invalid value names the file layer",
+			file:    "environments:\n  prod:\n    sftp:\n      port: twenty-two\n",
+			opts:    Options{Environment: "prod"},
+			wantErr: []string{`sftp.port: invalid integer "twenty-two" (from file`, "[environments.prod]"},
+		},
+		{
+			name: "unknown command profiles",
+			file: `
+commands:
+  export-csv: {}
+  export-cvs:
+    sftp:
+      dir: /x
+environments:
+  prod:
+    commands:
+      sync-course: {}
+`,
+			opts: Options{Profiles: []string{"export-csv", "sync-courses"}},
+			wantErr: []string{
+				"commands.export-cvs: unknown command profile (known: export-csv, sync-courses)",
+				"environments.prod.commands.sync-course: unknown command profile",
+			},
+		},
+		{
+			name:    "lists are not settings",
+			file:    "sftp:\n  host: [a, b]\n",
+			wantErr: []string{"sftp.host: expected a single value"},
+		},
+		{
+			name:    "malformed yaml",
+			file:    "sftp: [",
+			wantErr: []string{"course-sync.yaml"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Path = writeConfig(t, "course-sync.yaml", tc.file)
+			_, err := LoadWith(tc.opts)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tc.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWithUnknownOverride(t *testing.T) {
+	clearEnv(t)
+	_, err := LoadWith(Options{Overrides: map[string]string{"sftp.hots": "x"}})
+	if err == nil || !strings.Contains(err.Error(), "sftp.hots: unknown setting") {
+		t.Errorf("Expected unknown override to be rejected, got %v", err)
+	}
+}
+
+func TestLoadWithEnvironmentWithoutFile(t *testing.T) {
+	clearEnv(t)
+	if _, err := LoadWith(Options{Environment: "prod"}); err == nil {
+		t.Error("Expected an error when selecting an environment without a config file")
+	}
+}
+
+func TestSettings(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SFTP_PASS", "hunter2")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	byKey := map[string]Setting{}
+	for _, s := range cfg.Settings() {
+		byKey[s.Key] = s
+	}
+	if s := byKey["sftp.pass"]; s.Value != redacted || s.Source != "env SFTP_PASS" || s.Env != "SFTP_PASS" {
+		t.Errorf("Unexpected sftp.pass setting: %+v", s)
+	}
+	if s := byKey["sftp.port"]; s.Value != "22" || s.Source != "default" {
+		t.Errorf("Unexpected sftp.port setting: %+v", s)
+	}
+	if s := byKey["udemy.base_url"]; s.Value != "" || s.Source != "" {
+		t.Errorf("Expected unset udemy.base_url, got %+v", s)
+	}
+	if len(byKey) != len(Keys()) {
+		t.Errorf("Expected a setting per key, got %d of %d", len(byKey), len(Keys()))
+	}
+}
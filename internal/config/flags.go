@@ -0,0 +1,36 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// RegisterFlags adds -config, -env and -set to fs and returns the Options
+// they fill once fs is parsed. command selects the commands.<name> profile.
+func RegisterFlags(fs *flag.FlagSet, command string) *Options {
+	opts := &Options{Command: command, Overrides: map[string]string{}}
+	fs.StringVar(&opts.Path, "config", "", "config file (YAML or JSON; default $"+configPathEnv+")")
+	fs.StringVar(&opts.Environment, "env", "", "config environment, e.g. sandbox or prod (default $"+configEnvEnv+")")
+	fs.Var(overrides(opts.Overrides), "set", "override a setting, key=value (repeatable), e.g. -set sftp.dir=/inbound")
+	return opts
+}
+
+type overrides map[string]string
+
+func (o overrides) String() string {
+	parts := make([]string, 0, len(o))
+	for k, v := range o {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (o overrides) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(k) == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	o[strings.TrimSpace(k)] = v
+	return nil
+}
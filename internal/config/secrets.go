@@ -0,0 +1,244 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Secret references
+
+Any credential setting (see the `secret:"true"` fields of Config) may hold a
+reference instead of the literal value:
+
+	EIGHTFOLD_PASSWORD=file:/run/secrets/eightfold_password   mounted secret file
+	UDEMY_CLIENT_SECRET=enc:udemy_client_secret                entry of the encrypted secrets file
+	SFTP_PASS=exec:pass show eightfold/sftp                    stdout of a helper command
+
+Values without a registered "<scheme>:" prefix are used literally, so plain
+environment variables keep working. A literal value that does start with one
+is written with the "literal:" prefix, which is removed:
+
+	SFTP_PASS=literal:file:not-a-path                         the password "file:not-a-path"
+*/
+
+// literalPrefix marks a secret value to be used as is, without the prefix.
+const literalPrefix = "literal:"
+
+// SecretBackend resolves the part of a secret reference after "<scheme>:".
+type SecretBackend interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SecretBackendFunc adapts a function to SecretBackend.
+type SecretBackendFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretBackendFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]SecretBackend{
+		"file": SecretBackendFunc(resolveFileSecret),
+		"enc":  SecretBackendFunc(resolveEncryptedSecret),
+		"exec": SecretBackendFunc(resolveExecSecret),
+	}
+)
+
+// RegisterSecretBackend makes a backend available under "<scheme>:" references.
+// Registering an existing scheme replaces it. "literal" is reserved: such
+// values are never passed to a backend.
+func RegisterSecretBackend(scheme string, b SecretBackend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[scheme] = b
+}
+
+// ResolveSecret resolves v if it is a reference to a registered backend,
+// strips the "literal:" prefix, and returns other values unchanged.
+func ResolveSecret(ctx context.Context, v string) (string, error) {
+	if lit, ok := strings.CutPrefix(v, literalPrefix); ok {
+		return lit, nil
+	}
+	scheme, ref, ok := strings.Cut(v, ":")
+	if !ok {
+		return v, nil
+	}
+
+	backendsMu.RLock()
+	b, ok := backends[scheme]
+	backendsMu.RUnlock()
+	if !ok {
+		return v, nil
+	}
+
+	out, err := b.Resolve(ctx, strings.TrimSpace(ref))
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", scheme, err)
+	}
+	return out, nil
+}
+
+func resolveFileSecret(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+const execSecretTimeout = 30 * time.Second
+
+// resolveExecSecret runs a credential helper (no shell) and returns its stdout.
+func resolveExecSecret(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execSecretTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// Encrypted secrets file.
+//
+// The file at COURSE_SYNC_SECRETS_FILE (default ".secrets.enc") holds a JSON
+// object of name -> value sealed with AES-256-GCM. The key is read, base64
+// encoded, from COURSE_SYNC_SECRETS_KEY. It is decrypted once per process.
+const (
+	secretsFileEnv     = "COURSE_SYNC_SECRETS_FILE"
+	secretsKeyEnv      = "COURSE_SYNC_SECRETS_KEY"
+	defaultSecretsFile = ".secrets.enc"
+	sealedPrefix       = "course-sync-secrets/v1\n"
+)
+
+var (
+	sealedMu    sync.Mutex
+	sealedCache map[string]map[string]string // path -> decrypted values
+)
+
+func resolveEncryptedSecret(_ context.Context, name string) (string, error) {
+	path := getenv(secretsFileEnv, defaultSecretsFile)
+
+	sealedMu.Lock()
+	defer sealedMu.Unlock()
+
+	values, ok := sealedCache[path]
+	if !ok {
+		key, err := SecretsKeyFromEnv()
+		if err != nil {
+			return "", err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		values, err = OpenSecrets(key, b)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		if sealedCache == nil {
+			sealedCache = map[string]map[string]string{}
+		}
+		sealedCache[path] = values
+	}
+
+	v, ok := values[name]
+	if !ok {
+		return "", fmt.Errorf("%q not found in %s", name, path)
+	}
+	return v, nil
+}
+
+// SecretsKeyFromEnv decodes the 32-byte secrets file key from COURSE_SYNC_SECRETS_KEY.
+func SecretsKeyFromEnv() ([]byte, error) {
+	v := strings.TrimSpace(os.Getenv(secretsKeyEnv))
+	if v == "" {
+		return nil, fmt.Errorf("%s is not set", secretsKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid base64: %w", secretsKeyEnv, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s: expected a 32-byte key, got %d bytes", secretsKeyEnv, len(key))
+	}
+	return key, nil
+}
+
+// SealSecrets encrypts values for storage in the secrets file.
+func SealSecrets(key []byte, values map[string]string) ([]byte, error) {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(sealedPrefix))
+	return []byte(sealedPrefix + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// OpenSecrets decrypts a file produced by SealSecrets.
+func OpenSecrets(key []byte, data []byte) (map[string]string, error) {
+	rest, ok := bytes.CutPrefix(data, []byte(sealedPrefix))
+	if !ok {
+		return nil, errors.New("not a course-sync secrets file")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(rest)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoding: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("truncated secrets file")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(sealedPrefix))
+	if err != nil {
+		return nil, errors.New("decrypt failed (wrong key or corrupted file)")
+	}
+	var values map[string]string
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("decode secrets: %w", err)
+	}
+	return values, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
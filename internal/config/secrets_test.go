@@ -0,0 +1,199 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSecretPlainValues(t *testing.T) {
+	testCases := []string{
+		"plain-password",
+		"",
+		"https://example.com", // unknown scheme: literal value
+		"pa:ss",
+	}
+
+	for _, in := range testCases {
+		got, err := ResolveSecret(context.Background(), in)
+		if err != nil || got != in {
+			t.Errorf("ResolveSecret(%q) = %q, %v; want it unchanged", in, got, err)
+		}
+	}
+}
+
+func TestResolveSecretLiteral(t *testing.T) {
+	testCases := map[string]string{
+		"literal:file:abc":                "file:abc",
+		"literal:exec:touch /tmp/not-run": "exec:touch /tmp/not-run",
+		"literal:literal:x":               "literal:x",
+		"literal:":                        "",
+	}
+	for in, want := range testCases {
+		got, err := ResolveSecret(context.Background(), in)
+		if err != nil || got != want {
+			t.Errorf("ResolveSecret(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestResolveSecretFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(p, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ResolveSecret(context.Background(), "file:"+p)
+	if err != nil {
+		t.Fatalf("ResolveSecret() error: %v", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("Expected trailing newline to be trimmed, got %q", got)
+	}
+
+	if _, err := ResolveSecret(context.Background(), "file:/non/existent"); err == nil {
+		t.Error("Expected error for missing secret file")
+	}
+}
+
+func TestResolveSecretExec(t *testing.T) {
+	got, err := ResolveSecret(context.Background(), "exec:echo from-helper")
+	if err != nil {
+		t.Fatalf("ResolveSecret() error: %v", err)
+	}
+	if got != "from-helper" {
+		t.Errorf("Expected 'from-helper', got %q", got)
+	}
+
+	if _, err := ResolveSecret(context.Background(), "exec:false"); err == nil {
+		t.Error("Expected error for failing helper")
+	}
+}
+
+func TestResolveSecretEncrypted(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	sealed, err := SealSecrets(key, map[string]string{"sftp_pass": "p@ss"})
+	if err != nil {
+		t.Fatalf("SealSecrets() error: %v", err)
+	}
+
+	p := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := os.WriteFile(p, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(secretsFileEnv, p)
+	t.Setenv(secretsKeyEnv, base64.StdEncoding.EncodeToString(key))
+
+	got, err := ResolveSecret(context.Background(), "enc:sftp_pass")
+	if err != nil {
+		t.Fatalf("ResolveSecret() error: %v", err)
+	}
+	if got != "p@ss" {
+		t.Errorf("Expected 'p@ss', got %q", got)
+	}
+
+	if _, err := ResolveSecret(context.Background(), "enc:missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	wrong := make([]byte, 32)
+	if _, err := OpenSecrets(wrong, sealed); err == nil {
+		t.Error("Expected decrypt error with the wrong key")
+	}
+}
+
+func TestRegisterSecretBackend(t *testing.T) {
+	RegisterSecretBackend("test", SecretBackendFunc(func(ctx context.Context, ref string) (string, error) {
+		if ref == "fail" {
+			return "", errors.New("backend down")
+		}
+		return "value-of-" + ref, nil
+	}))
+
+	got, err := ResolveSecret(context.Background(), "test:thing")
+	if err != nil || got != "value-of-thing" {
+		t.Errorf("ResolveSecret() = %q, %v", got, err)
+	}
+	if _, err := ResolveSecret(context.Background(), "test:fail"); err == nil {
+		t.Error("Expected backend error to be returned")
+	}
+}
+
+func TestLoadResolvesSecretReferences(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ef_pass")
+	if err := os.WriteFile(p, []byte("from-file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EIGHTFOLD_PASSWORD", "file:"+p)
+	t.Setenv("SFTP_PASS", "literal")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.EightfoldPass != "from-file" {
+		t.Errorf("Expected EightfoldPass from file, got %q", cfg.EightfoldPass)
+	}
+	if cfg.SFTPPass != "literal" {
+		t.Errorf("Expected literal SFTPPass, got %q", cfg.SFTPPass)
+	}
+
+	t.Setenv("EIGHTFOLD_PASSWORD", "file:/non/existent")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "eightfold.password") {
+		t.Errorf("Expected error naming the field, got %v", err)
+	}
+}
+
+func TestLoadContextCancelsSecretBackends(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SFTP_PASS", "exec:sleep 10")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := LoadContext(ctx, Options{}); err == nil || !strings.Contains(err.Error(), "sftp.pass") {
+		t.Errorf("Expected the helper to be killed, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Expected Load to return with its context, took %v", d)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		EightfoldUser:     "svc-user",
+		EightfoldPass:     "hunter2",
+		UdemyClientSecret: "udemy-secret",
+		SFTPHost:          "sftp.example.com",
+	}
+
+	r := cfg.Redacted()
+	if r.EightfoldPass != redacted || r.UdemyClientSecret != redacted {
+		t.Errorf("Expected secrets to be redacted, got %+v", r)
+	}
+	if r.PluralsightToken != "" {
+		t.Errorf("Expected unset secret to stay empty, got %q", r.PluralsightToken)
+	}
+	if r.EightfoldUser != "svc-user" || r.SFTPHost != "sftp.example.com" {
+		t.Errorf("Expected non-secret fields to be kept, got %+v", r)
+	}
+	if cfg.EightfoldPass != "hunter2" {
+		t.Error("Redacted must not modify the original config")
+	}
+
+	s := cfg.String()
+	if strings.Contains(s, "hunter2") || strings.Contains(s, "udemy-secret") {
+		t.Errorf("String() leaked a secret: %s", s)
+	}
+	if !strings.Contains(s, "sftp.example.com") {
+		t.Errorf("String() should include non-secret values: %s", s)
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"course-sync/internal/schedule"
+)
+
+// Validate checks the format of every value that is set. Whether a command
+// has everything it needs is checked separately by Require.
+func (c Config) Validate() error {
+	var errs []error
+	bad := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("config: %s: %s%s", key, fmt.Sprintf(format, args...), c.from(key)))
+	}
+
+	// Ordered, so that the errors are reported in the same order every run.
+	for _, kv := range []struct{ key, v string }{
+		{"eightfold.base_url", c.EightfoldBaseURL},
+		{"udemy.base_url", c.UdemyBaseURL},
+		{"pluralsight.gql_url", c.PluralsightBaseURL},
+	} {
+		if kv.v == "" {
+			continue
+		}
+		u, err := url.Parse(kv.v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad(kv.key, "expected an http(s) URL, got %q", kv.v)
+		}
+	}
+
+	if c.SFTPPort < 1 || c.SFTPPort > 65535 {
+		bad("sftp.port", "must be between 1 and 65535, got %d", c.SFTPPort)
+	}
+	if c.SFTPAttempts < 1 {
+		bad("sftp.attempts", "must be at least 1, got %d", c.SFTPAttempts)
+	}
+	if c.SFTPDir != "" && !strings.HasPrefix(c.SFTPDir, "/") {
+		bad("sftp.dir", "must be an absolute path, got %q", c.SFTPDir)
+	}
+	if c.SFTPHostKey != "" {
+		parts := strings.Fields(c.SFTPHostKey)
+		if len(parts) < 2 {
+			bad("sftp.host_key", `expected "<type> <base64-key>"`)
+		} else if _, err := base64.StdEncoding.DecodeString(parts[1]); err != nil {
+			bad("sftp.host_key", "invalid base64 key: %v", err)
+		}
+	}
+	if c.SFTPKeyPath != "" {
+		if _, err := os.Stat(c.SFTPKeyPath); err != nil {
+			bad("sftp.key_path", "%v", err)
+		}
+	}
+	if c.SFTPTrustOnFirstUse && len(c.SFTPKnownHostsFiles()) == 0 {
+		bad("sftp.trust_on_first_use", "needs sftp.known_hosts, the file to record new host keys in")
+	}
+
+	if c.PGPEncrypt && len(c.PGPRecipientKeyFiles()) == 0 {
+		bad("pgp.encrypt", "needs pgp.recipient_key, the public key to encrypt to")
+	}
+	for _, kv := range []struct {
+		key   string
+		files []string
+	}{
+		{"pgp.recipient_key", c.PGPRecipientKeyFiles()},
+		{"pgp.signing_key", []string{c.PGPSigningKey}},
+		{"pgp.decryption_key", []string{c.PGPDecryptionKey}},
+	} {
+		for _, f := range kv.files {
+			if f == "" {
+				continue
+			}
+			if _, err := os.Stat(f); err != nil {
+				bad(kv.key, "%v", err)
+			}
+		}
+	}
+
+	if c.ArchiveRetention < 0 {
+		bad("archive.retention", "must not be negative")
+	}
+	if c.LockDir == "" {
+		bad("lock.dir", "must not be empty")
+	}
+
+	for _, kv := range []struct{ key, spec string }{
+		{"serve.courses_schedule", c.ServeCoursesSchedule},
+		{"serve.employees_schedule", c.ServeEmployeesSchedule},
+	} {
+		if kv.spec == "" {
+			continue
+		}
+		if _, err := schedule.Parse(kv.spec); err != nil {
+			bad(kv.key, "%v", err)
+		}
+	}
+	if c.ServeTimezone != "" {
+		if _, err := time.LoadLocation(c.ServeTimezone); err != nil {
+			bad("serve.timezone", "%v", err)
+		}
+	}
+	if c.ServeListen != "" {
+		if _, _, err := net.SplitHostPort(c.ServeListen); err != nil {
+			bad("serve.listen", "expected host:port, got %q", c.ServeListen)
+		}
+	}
+	if c.ServeJitter < 0 {
+		bad("serve.jitter", "must not be negative")
+	}
+	if c.ServeShutdownGrace < 0 {
+		bad("serve.shutdown_grace", "must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Requirements lists the sections each command needs (see Require).
+// The export commands need "sftp" only when uploading; serve needs what its
+// jobs need, which sync-courses and sync-employees check again per run.
+var Requirements = map[string][]string{
+	"sync-courses":     {"eightfold", "udemy", "pluralsight"},
+	"sync-employees":   {"eightfold"},
+	"replay":           {"eightfold"},
+	"export-csv":       {"udemy", "pluralsight"},
+	"export-xml":       {"udemy", "pluralsight"},
+	"export-employees": {"eightfold"},
+	"upload":           {"sftp"},
+	"serve":            {"eightfold"},
+}
+
+// Require checks that every setting the given sections ("eightfold",
+// "udemy", "pluralsight", "sftp") need is present.
+func (c Config) Require(sections ...string) error {
+	var errs []error
+	missing := func(what string) {
+		errs = append(errs, fmt.Errorf("config: %s is required", what))
+	}
+
+	for _, s := range sections {
+		switch s {
+		case "eightfold":
+			if c.EightfoldBaseURL == "" {
+				missing("eightfold.base_url (EIGHTFOLD_BASE_URL)")
+			}
+			if c.EightfoldBearerToken == "" && (c.EightfoldBasicAuth == "" || c.EightfoldUser == "" || c.EightfoldPass == "") {
+				missing("eightfold.bearer_token, or eightfold.basic_auth + username + password")
+			}
+		case "udemy":
+			if c.UdemyBaseURL == "" {
+				missing("udemy.base_url (UDEMY_BASE_URL)")
+			}
+			if c.UdemyClientID == "" || c.UdemyClientSecret == "" {
+				missing("udemy.client_id and udemy.client_secret")
+			}
+		case "pluralsight":
+			if c.PluralsightBaseURL == "" {
+				missing("pluralsight.gql_url (PLURALSIGHT_GQL_URL)")
+			}
+			if c.PluralsightToken == "" {
+				missing("pluralsight.token (PLURALSIGHT_TOKEN)")
+			}
+		case "sftp":
+			if c.SFTPHost == "" || c.SFTPUser == "" {
+				missing("sftp.host and sftp.user")
+			}
+			if c.SFTPPass == "" && c.SFTPKeyPath == "" {
+				missing("sftp.pass or sftp.key_path")
+			}
+			if !c.SFTPInsecureIgnoreHostKey && c.SFTPHostKey == "" && len(c.SFTPKnownHostsFiles()) == 0 {
+				missing("sftp.known_hosts or sftp.host_key (to verify the server; `course-sync sftp hostkey` shows its key)")
+			}
+		default:
+			errs = append(errs, fmt.Errorf("config: unknown section %q", s))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,577 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Feed is a file format Eightfold ingests, as recognised by ValidateFile.
+type Feed string
+
+const (
+	FeedCourseXML   Feed = "course XML"
+	FeedDeleteXML   Feed = "course delete XML"
+	FeedEmployeeXML Feed = "employee XML"
+	FeedCourseCSV   Feed = "course CSV"
+)
+
+// Issue is one problem found in a feed.
+type Issue struct {
+	Line int
+	// Record names the record, e.g. "EF_Course UDM+123"; empty for the
+	// file as a whole.
+	Record string
+	Field  string
+	Msg    string
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "line %d: ", i.Line)
+	if i.Record != "" {
+		b.WriteString(i.Record + ": ")
+	}
+	if i.Field != "" {
+		b.WriteString(i.Field + " ")
+	}
+	b.WriteString(i.Msg)
+	return b.String()
+}
+
+// Report is the result of validating a feed.
+type Report struct {
+	Feed    Feed
+	Records int
+	Issues  []Issue
+}
+
+// maxReportedIssues caps the issues listed by Report.Err.
+const maxReportedIssues = 5
+
+// Err summarises the issues, or returns nil when there are none.
+func (r Report) Err() error {
+	if len(r.Issues) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, maxReportedIssues+1)
+	for i, is := range r.Issues {
+		if i == maxReportedIssues {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(r.Issues)-i))
+			break
+		}
+		msgs = append(msgs, is.String())
+	}
+	return fmt.Errorf("%d problems in %s: %s", len(r.Issues), r.Feed, strings.Join(msgs, "; "))
+}
+
+// CanValidate reports whether path has an extension ValidateFile handles.
+func CanValidate(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml", ".csv":
+		return true
+	}
+	return false
+}
+
+// FeedOf returns the feed a file name declares: Eightfold takes files named
+// ef_course_delete* for deletes, whose XML looks like a course list that
+// only carries titles and ids. It returns "" when the content decides, as
+// the root element does for other XML files.
+func FeedOf(name string) Feed {
+	base := strings.ToLower(filepath.Base(name))
+	switch {
+	case strings.HasSuffix(base, ".csv"):
+		return FeedCourseCSV
+	case strings.HasSuffix(base, ".xml") && strings.HasPrefix(base, "ef_course_delete"):
+		return FeedDeleteXML
+	}
+	return ""
+}
+
+// ValidateFile checks a generated feed against the Eightfold formats
+// written by this package: required fields, number and date formats, enum
+// values and duplicate IDs. The feed is the one the file name declares
+// (see FeedOf). The error is for files that cannot be read or parsed, or
+// are not a known feed; problems in the records are in the Report (see
+// Report.Err).
+func ValidateFile(path string) (Report, error) {
+	return ValidateFileAs(path, FeedOf(path))
+}
+
+// ValidateFileAs is ValidateFile for a given feed, e.g. that of the name the
+// file is uploaded under. An empty feed goes by the file name.
+func ValidateFileAs(path string, feed Feed) (Report, error) {
+	if feed == "" {
+		feed = FeedOf(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Report{}, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return ValidateXML(f, feed)
+	case ".csv":
+		if feed != FeedCourseCSV {
+			return Report{}, fmt.Errorf("a CSV file is not a %s feed", feed)
+		}
+		return ValidateCSV(f)
+	default:
+		return Report{}, fmt.Errorf("unsupported file type %q", filepath.Ext(path))
+	}
+}
+
+// node is any XML element, kept generic so that the checks see exactly what
+// the file contains rather than what a struct would keep of it.
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []node     `xml:",any"`
+}
+
+func (n node) attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (n node) children(name string) []node {
+	var out []node
+	for _, c := range n.Nodes {
+		if c.XMLName.Local == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+type xmlRecord struct {
+	line int
+	node
+}
+
+// ValidateXML validates an EF_Course_List or EF_Employee_List document as
+// feed, or as the feed its root element names when feed is empty. A
+// delete feed has to be asked for: its root is that of a course feed.
+func ValidateXML(r io.Reader, feed Feed) (Report, error) {
+	switch feed {
+	case "", FeedCourseXML, FeedDeleteXML, FeedEmployeeXML:
+	default:
+		return Report{}, fmt.Errorf("%s is not an XML feed", feed)
+	}
+	dec := xml.NewDecoder(r)
+	var (
+		root    string
+		closed  bool
+		records []xmlRecord
+	)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Report{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root == "" {
+				root = t.Name.Local
+				if root != "EF_Course_List" && root != "EF_Employee_List" {
+					return Report{}, fmt.Errorf("unknown feed: root element <%s>, want EF_Course_List or EF_Employee_List", root)
+				}
+				continue
+			}
+			if closed {
+				return Report{}, errors.New("more than one root element")
+			}
+			line, _ := dec.InputPos()
+			var n node
+			if err := dec.DecodeElement(&n, &t); err != nil {
+				return Report{}, err
+			}
+			records = append(records, xmlRecord{line: line, node: n})
+		case xml.EndElement:
+			closed = true
+		}
+	}
+	if root == "" {
+		return Report{}, errors.New("empty document")
+	}
+	if !closed {
+		return Report{}, errors.New("unexpected end of document")
+	}
+
+	want := "EF_Course_List"
+	if feed == FeedEmployeeXML {
+		want = "EF_Employee_List"
+	}
+	switch {
+	case feed == "" && root == "EF_Employee_List":
+		feed = FeedEmployeeXML
+	case feed == "":
+		feed = FeedCourseXML
+	case root != want:
+		return Report{}, fmt.Errorf("root element <%s> is not that of a %s feed (<%s>)", root, feed, want)
+	}
+
+	v := &validator{report: Report{Feed: feed}, seen: map[string]map[string]int{}}
+	for _, rec := range records {
+		if feed == FeedEmployeeXML {
+			v.employee(rec)
+		} else {
+			v.course(rec)
+		}
+	}
+	return v.report, nil
+}
+
+// Enum values, lower case.
+var (
+	courseOperations = []string{"add", "update", "upsert", "delete"}
+	courseStatuses   = []string{"active", "inactive"}
+	customDataTypes  = []string{"string", "int", "float", "boolean", "date"}
+)
+
+// Date layouts accepted for published_ts / publishedDate: Eightfold's
+// sample format, a plain date (Udemy) and RFC 3339 (Pluralsight).
+var dateLayouts = []string{"2006-01-02T15:04:05", "2006-01-02", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05.999999999Z07:00"}
+
+var languageTag = regexp.MustCompile(`(?i)^[a-z]{2,3}([-_][a-z0-9]{2,8})*$`)
+
+type validator struct {
+	report Report
+	// seen maps an ID field to the values met so far and their line.
+	seen map[string]map[string]int
+}
+
+func (v *validator) add(line int, record, field, format string, args ...any) {
+	v.report.Issues = append(v.report.Issues, Issue{Line: line, Record: record, Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// unique flags value of field if an earlier record had it too.
+func (v *validator) unique(line int, record, field, value string) {
+	if value == "" {
+		return
+	}
+	seen := v.seen[field]
+	if seen == nil {
+		seen = map[string]int{}
+		v.seen[field] = seen
+	}
+	if first, ok := seen[value]; ok {
+		v.add(line, record, field, "%q is a duplicate, first at line %d", value, first)
+		return
+	}
+	seen[value] = line
+}
+
+// field returns the trimmed text of the single child name of rec, flagging
+// it when it is repeated.
+func (v *validator) field(rec xmlRecord, label, name string) (string, bool) {
+	cs := rec.children(name)
+	if len(cs) == 0 {
+		return "", false
+	}
+	if len(cs) > 1 {
+		v.add(rec.line, label, name, "appears %d times", len(cs))
+	}
+	return strings.TrimSpace(cs[0].Text), true
+}
+
+func (v *validator) required(line int, record, field, value string, present bool) {
+	if !present {
+		v.add(line, record, field, "is missing")
+	} else if value == "" {
+		v.add(line, record, field, "is empty")
+	}
+}
+
+func recordLabel(kind, id string, index int) string {
+	if id == "" {
+		return fmt.Sprintf("%s #%d", kind, index)
+	}
+	return kind + " " + id
+}
+
+func (v *validator) course(rec xmlRecord) {
+	label := recordLabel("EF_Course", strings.TrimSpace(firstText(rec.node, "lms_course_id")), v.report.Records+1)
+	id, hasID := v.field(rec, label, "lms_course_id")
+	v.report.Records++
+
+	v.required(rec.line, label, "lms_course_id", id, hasID)
+	v.unique(rec.line, label, "lms_course_id", id)
+	if op, ok := rec.attr("operation"); ok {
+		v.enum(rec.line, label, "@operation", op, courseOperations)
+	}
+	title, hasTitle := v.field(rec, label, "title")
+	if v.report.Feed == FeedDeleteXML {
+		return
+	}
+	v.required(rec.line, label, "title", title, hasTitle)
+
+	if s, ok := v.field(rec, label, "system_id"); ok {
+		v.required(rec.line, label, "system_id", s, true)
+		v.unique(rec.line, label, "system_id", s)
+	}
+	if s, ok := v.field(rec, label, "duration_hours"); ok {
+		v.duration(rec.line, label, "duration_hours", s)
+	}
+	if s, ok := v.field(rec, label, "published_ts"); ok {
+		v.date(rec.line, label, "published_ts", s)
+	}
+	if s, ok := v.field(rec, label, "language"); ok {
+		v.language(rec.line, label, "language", s)
+	}
+	if s, ok := v.field(rec, label, "course_url"); ok {
+		v.url(rec.line, label, "course_url", s)
+	}
+	if s, ok := v.field(rec, label, "status"); ok {
+		v.enum(rec.line, label, "status", s, courseStatuses)
+	}
+	for _, field := range []string{"description", "course_type", "difficulty", "category", "provider"} {
+		v.field(rec, label, field)
+	}
+	for _, l := range rec.children("skills_list") {
+		for _, s := range l.children("skill") {
+			if strings.TrimSpace(s.Text) == "" {
+				v.add(rec.line, label, "skill", "is empty")
+			}
+		}
+	}
+	v.customFields(rec, label)
+}
+
+func (v *validator) employee(rec xmlRecord) {
+	label := recordLabel("EF_Employee", strings.TrimSpace(firstText(rec.node, "employee_id")), v.report.Records+1)
+	id, hasID := v.field(rec, label, "employee_id")
+	v.report.Records++
+
+	v.required(rec.line, label, "employee_id", id, hasID)
+	v.unique(rec.line, label, "employee_id", id)
+	if s, ok := v.field(rec, label, "user_id"); ok {
+		v.unique(rec.line, label, "user_id", s)
+	}
+	v.field(rec, label, "level")
+	for _, l := range rec.children("email_list") {
+		for _, e := range l.children("email") {
+			s := strings.TrimSpace(e.Text)
+			if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+				v.add(rec.line, label, "email", "%q is not an email address", s)
+			}
+		}
+	}
+	v.customFields(rec, label)
+}
+
+// customFields checks custom_info/custom_field and
+// custom_multi_value_list/custom_mv_field.
+func (v *validator) customFields(rec xmlRecord, label string) {
+	check := func(f node, values []node) {
+		name := strings.TrimSpace(firstText(f, "field_name"))
+		if name == "" {
+			v.add(rec.line, label, "field_name", "is missing")
+			name = "custom field"
+		}
+		dataType := strings.TrimSpace(firstText(f, "data_type"))
+		if !v.enum(rec.line, label, name+" data_type", dataType, customDataTypes) {
+			return
+		}
+		for _, val := range values {
+			v.typed(rec.line, label, name, dataType, strings.TrimSpace(val.Text))
+		}
+	}
+	for _, ci := range rec.children("custom_info") {
+		for _, f := range ci.children("custom_field") {
+			check(f, f.children("field_value"))
+		}
+	}
+	for _, mv := range rec.children("custom_multi_value_list") {
+		for _, f := range mv.children("custom_mv_field") {
+			var values []node
+			for _, dl := range f.children("data_list") {
+				values = append(values, dl.children("field_value")...)
+			}
+			check(f, values)
+		}
+	}
+}
+
+func firstText(n node, name string) string {
+	if cs := n.children(name); len(cs) > 0 {
+		return cs[0].Text
+	}
+	return ""
+}
+
+// enum flags value unless it is one of allowed (ignoring case), and
+// reports whether it is.
+func (v *validator) enum(line int, record, field, value string, allowed []string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSpace(value), a) {
+			return true
+		}
+	}
+	v.add(line, record, field, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	return false
+}
+
+func (v *validator) duration(line int, record, field, s string) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f < 0 {
+		v.add(line, record, field, "%q is not a number of hours", s)
+	}
+}
+
+func (v *validator) date(line int, record, field, s string) {
+	if s == "" {
+		return
+	}
+	if !isDate(s) {
+		v.add(line, record, field, "%q is not a date (want e.g. 2020-10-01T07:19:45 or 2020-10-01)", s)
+	}
+}
+
+func isDate(s string) bool {
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) language(line int, record, field, s string) {
+	if s != "" && !languageTag.MatchString(s) {
+		v.add(line, record, field, "%q is not a language code like en or pt-br", s)
+	}
+}
+
+func (v *validator) url(line int, record, field, s string) {
+	if s == "" {
+		return
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(line, record, field, "%q is not an http(s) URL", s)
+	}
+}
+
+func (v *validator) typed(line int, record, field, dataType, s string) {
+	var err error
+	switch strings.ToLower(dataType) {
+	case "int":
+		_, err = strconv.ParseInt(s, 10, 64)
+	case "float":
+		_, err = strconv.ParseFloat(s, 64)
+	case "boolean":
+		_, err = strconv.ParseBool(s)
+	case "date":
+		if !isDate(s) {
+			err = errors.New("not a date")
+		}
+	case "string":
+		if s == "" {
+			v.add(line, record, field, "has an empty value")
+		}
+		return
+	}
+	if err != nil {
+		v.add(line, record, field, "%q is not a valid %s", s, dataType)
+	}
+}
+
+// CSV columns that identify a course CSV.
+var requiredColumns = []string{"systemId", "title", "COURSE_ID"}
+
+// ValidateCSV validates the Eightfold course CSV. Every row must have the
+// header's column count.
+func ValidateCSV(r io.Reader) (Report, error) {
+	cr := csv.NewReader(r)
+	head, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return Report{}, errors.New("empty file (no header)")
+	}
+	if err != nil {
+		return Report{}, err
+	}
+	col := map[string]int{}
+	for i, name := range head {
+		col[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := col[name]; !ok {
+			return Report{}, fmt.Errorf("unknown feed: no %s column in the header", name)
+		}
+	}
+
+	v := &validator{report: Report{Feed: FeedCourseCSV}, seen: map[string]map[string]int{}}
+	if len(col) != len(head) {
+		v.add(1, "", "header", "has duplicate column names")
+	}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return v.report, err
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(name string) (string, bool) {
+			i, ok := col[name]
+			if !ok {
+				return "", false
+			}
+			return strings.TrimSpace(row[i]), true
+		}
+
+		v.report.Records++
+		id, _ := get("COURSE_ID")
+		label := recordLabel("course", id, v.report.Records)
+		for _, field := range requiredColumns {
+			s, _ := get(field)
+			v.required(line, label, field, s, true)
+		}
+		for _, field := range []string{"systemId", "COURSE_ID"} {
+			s, _ := get(field)
+			v.unique(line, label, field, s)
+		}
+		if s, ok := get("durationHours"); ok && s != "" {
+			v.duration(line, label, "durationHours", s)
+		}
+		if s, ok := get("publishedDate"); ok {
+			v.date(line, label, "publishedDate", s)
+		}
+		if s, ok := get("language"); ok {
+			v.language(line, label, "language", s)
+		}
+		for _, field := range []string{"courseUrl", "imageUrl"} {
+			if s, ok := get(field); ok {
+				v.url(line, label, field, s)
+			}
+		}
+		if s, ok := get("status"); ok && s != "" {
+			v.enum(line, label, "status", s, courseStatuses)
+		}
+	}
+	return v.report, nil
+}
@@ -0,0 +1,208 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"course-sync/internal/domain"
+)
+
+// TestValidateWrittenFeeds checks that what the writers produce passes.
+func TestValidateWrittenFeeds(t *testing.T) {
+	dir := t.TempDir()
+	courses := []domain.UnifiedCourse{
+		{Source: "udemy", SourceID: "1", Title: "Go", CourseURL: "https://u/1", DurationHours: 1.5,
+			Language: "en_US", PublishedDate: "2023-01-01", Status: "active", Skills: []string{"Go"}},
+		{Source: "pluralsight", SourceID: "2", Title: "Rust", CourseURL: "https://p/2",
+			Language: "pt-BR", PublishedDate: "2020-10-01T07:19:45.000Z"},
+	}
+	tags := CourseTagConfig{Operation: "upsert", TagsBySource: map[string][]string{"udemy": {"IC1", "IC2"}, "pluralsight": {"M1"}}}
+	paths := map[string]Feed{}
+	write := func(name string, feed Feed, fn func(string) error) {
+		p := filepath.Join(dir, name)
+		if err := fn(p); err != nil {
+			t.Fatal(err)
+		}
+		paths[p] = feed
+	}
+	write("add.xml", FeedCourseXML, func(p string) error { return WriteEFCourseXML(p, courses, tags) })
+	write("ef_course_delete.xml", FeedDeleteXML, func(p string) error {
+		return WriteEFCourseDeleteXML(p, []DeleteCourse{{Title: "Old", LMSCourseID: "UDM+9"}, {LMSCourseID: "PLS+8"}})
+	})
+	write("employees.xml", FeedEmployeeXML, func(p string) error {
+		return WriteEFEmployeeUpdateXML(p, []domain.UnifiedEmployee{
+			{EmployeeID: "E1", Emails: []string{"a@example.com"}, Level: "IC3"},
+			{EmployeeID: "E2", Level: "M1"},
+		}, EmployeeTagConfig{BadgeMergeStrategy: "latest"})
+	})
+	write("courses.csv", FeedCourseCSV, func(p string) error { return WriteEightfoldCourseCSV(p, courses, tags) })
+
+	for p, feed := range paths {
+		rep, err := ValidateFile(p)
+		if err != nil || rep.Err() != nil || rep.Feed != feed || rep.Records != 2 {
+			t.Errorf("ValidateFile(%s) = %+v, %v", filepath.Base(p), rep, err)
+		}
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"courses.xml", `<?xml version="1.0"?>
+<EF_Course_List>
+  <EF_Course operation="replace">
+    <title>Go</title>
+    <lms_course_id>1</lms_course_id>
+    <duration_hours>1h30m</duration_hours>
+    <published_ts>01/10/2020</published_ts>
+    <status>retired</status>
+  </EF_Course>
+  <EF_Course>
+    <lms_course_id> </lms_course_id>
+    <course_type>Course</course_type>
+    <language>English</language>
+    <course_url>www.example.com</course_url>
+  </EF_Course>
+  <EF_Course>
+    <title>Go again</title>
+    <lms_course_id>1</lms_course_id>
+    <custom_info><custom_field><field_name>rank</field_name><data_type>int</data_type><field_value>high</field_value></custom_field></custom_info>
+    <custom_multi_value_list><custom_mv_field><field_name>eligibility_tags</field_name><data_type>text</data_type></custom_mv_field></custom_multi_value_list>
+  </EF_Course>
+</EF_Course_List>
+`, []string{
+			`line 3: EF_Course 1: @operation "replace" is not one of add, update, upsert, delete`,
+			`line 3: EF_Course 1: duration_hours "1h30m" is not a number of hours`,
+			`line 3: EF_Course 1: published_ts "01/10/2020" is not a date`,
+			`line 3: EF_Course 1: status "retired" is not one of active, inactive`,
+			`line 10: EF_Course #2: lms_course_id is empty`,
+			`line 10: EF_Course #2: title is missing`,
+			`line 10: EF_Course #2: language "English" is not a language code`,
+			`line 10: EF_Course #2: course_url "www.example.com" is not an http(s) URL`,
+			`line 16: EF_Course 1: lms_course_id "1" is a duplicate, first at line 3`,
+			`line 16: EF_Course 1: rank "high" is not a valid int`,
+			`line 16: EF_Course 1: eligibility_tags data_type "text" is not one of`,
+		}},
+		{"employees.xml", `<EF_Employee_List>
+  <EF_Employee><employee_id>E1</employee_id><email_list><email>Ann &lt;ann@example.com&gt;</email></email_list></EF_Employee>
+  <EF_Employee><employee_id>E1</employee_id><employee_id>E2</employee_id></EF_Employee>
+  <EF_Employee><user_id>u1</user_id></EF_Employee>
+</EF_Employee_List>`, []string{
+			`line 2: EF_Employee E1: email "Ann <ann@example.com>" is not an email address`,
+			`line 3: EF_Employee E1: employee_id appears 2 times`,
+			`line 3: EF_Employee E1: employee_id "E1" is a duplicate, first at line 2`,
+			`line 4: EF_Employee #3: employee_id is missing`,
+		}},
+		{"courses.csv", "systemId,title,COURSE_ID,durationHours,publishedDate,status\n" +
+			"UDM+1,Go,1,1.5,2023-01-01,active\n" +
+			"UDM+1,,1,-2,2023-13-01,ACTIVE\n" +
+			"\"PLS+2\",\"Multi\nline\",2,0,,\n", []string{
+			`line 3: course 1: title is empty`,
+			`line 3: course 1: systemId "UDM+1" is a duplicate, first at line 2`,
+			`line 3: course 1: COURSE_ID "1" is a duplicate, first at line 2`,
+			`line 3: course 1: durationHours "-2" is not a number of hours`,
+			`line 3: course 1: publishedDate "2023-13-01" is not a date`,
+		}},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range testCases {
+		p := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(p, []byte(tc.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rep, err := ValidateFile(p)
+		if err != nil {
+			t.Fatalf("ValidateFile(%s) error: %v", tc.name, err)
+		}
+		if len(rep.Issues) != len(tc.want) {
+			t.Errorf("ValidateFile(%s) found %d issues, want %d:\n%v", tc.name, len(rep.Issues), len(tc.want), rep.Issues)
+			continue
+		}
+		for i, want := range tc.want {
+			if got := rep.Issues[i].String(); !strings.HasPrefix(got, want) {
+				t.Errorf("ValidateFile(%s) issue %d = %q, want prefix %q", tc.name, i, got, want)
+			}
+		}
+		if err := rep.Err(); err == nil || !strings.Contains(err.Error(), "problems in") {
+			t.Errorf("Report.Err() = %v", err)
+		}
+	}
+}
+
+// TestValidateFeedFromName checks that the feed comes from the file name or
+// the caller, never from the shape of the records.
+func TestValidateFeedFromName(t *testing.T) {
+	dir := t.TempDir()
+	idsOnly := `<EF_Course_List><EF_Course operation="update"><title>Go</title><lms_course_id>1</lms_course_id></EF_Course></EF_Course_List>`
+	testCases := []struct {
+		name, content string
+		feed          Feed
+		want          Feed
+		records       int
+	}{
+		{"ef_course_update.xml", idsOnly, "", FeedCourseXML, 1},
+		{"ef_course_add.xml", "<EF_Course_List/>", "", FeedCourseXML, 0},
+		{"EF_Course_Delete_2026.xml", "<EF_Course_List><EF_Course><lms_course_id>1</lms_course_id></EF_Course></EF_Course_List>", "", FeedDeleteXML, 1},
+		{"ef_course_delete.xml", "<EF_Course_List/>", "", FeedDeleteXML, 0},
+		{"courses.xml", idsOnly, FeedDeleteXML, FeedDeleteXML, 1},
+		{"ef_emp_update.xml", "<EF_Employee_List/>", "", FeedEmployeeXML, 0},
+	}
+	for _, tc := range testCases {
+		p := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(p, []byte(tc.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rep, err := ValidateFileAs(p, tc.feed)
+		if err != nil || rep.Err() != nil || rep.Feed != tc.want || rep.Records != tc.records {
+			t.Errorf("ValidateFileAs(%s, %q) = %+v, %v; want a %s with %d records", tc.name, tc.feed, rep, err, tc.want, tc.records)
+		}
+	}
+
+	for name, feed := range map[string]Feed{
+		"ef_course_delete.xml": "",
+		"courses.xml":          FeedEmployeeXML,
+		"courses.csv":          FeedCourseXML,
+	} {
+		p := filepath.Join(dir, name)
+		content := "<EF_Employee_List/>"
+		switch name {
+		case "courses.xml":
+			content = "<EF_Course_List/>"
+		case "courses.csv":
+			content = "systemId,title,COURSE_ID\n"
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateFileAs(p, feed); err == nil {
+			t.Errorf("Expected ValidateFileAs(%s, %q) to fail", name, feed)
+		}
+	}
+}
+
+func TestValidateUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"truncated.xml": "<EF_Course_List><EF_Course>",
+		"unknown.xml":   "<courses><course>Go</course></courses>",
+		"empty.xml":     "",
+		"ragged.csv":    "systemId,title,COURSE_ID\nUDM+1,Go\n",
+		"empty.csv":     "",
+		"other.csv":     "id,title\n1,Go\n",
+		"notes.txt":     "x",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateFile(p); err == nil {
+			t.Errorf("Expected ValidateFile(%s) to fail", name)
+		}
+	}
+}
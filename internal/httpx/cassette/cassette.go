@@ -0,0 +1,261 @@
+// Package cassette records HTTP exchanges to a file and replays them, so a
+// run against the real APIs can be reproduced offline: the provider
+// mapping, paging and retry code then see exactly the bytes production saw.
+//
+// Recording scrubs what must not end up in a file: credentials in headers,
+// query parameters and JSON bodies are replaced with "REDACTED", personal
+// names are blanked the same way, and email addresses are replaced with
+// stable pseudonyms (the same address always maps to the same
+// user-xxxxxxxx@example.invalid), so records that refer to each other still
+// match on replay.
+//
+//	c, _ := cassette.Open("run.cassette.json", cassette.Record)
+//	client.HTTP.Transport = c.Transport(client.HTTP.Transport)
+//	// ... run ...
+//	c.Save()
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"course-sync/internal/atomicfile"
+)
+
+// Mode says whether a cassette records or replays.
+type Mode int
+
+const (
+	// Record sends requests to the network and keeps the exchanges.
+	Record Mode = iota
+	// Replay answers requests from the file and never touches the network.
+	Replay
+)
+
+func (m Mode) String() string {
+	if m == Replay {
+		return "replay"
+	}
+	return "record"
+}
+
+// Interaction is one recorded exchange.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a scrubbed request; Method, URL and Body select it on replay.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a scrubbed response, served as is on replay.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type file struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+const version = 1
+
+// Cassette holds the exchanges of one file. It is safe for concurrent use
+// by several clients.
+type Cassette struct {
+	Path string
+	Mode Mode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Open returns a cassette for path. In Replay mode the file must exist; in
+// Record mode it is (re)written by Save.
+func Open(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: mode}
+	if mode == Record {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", path, err)
+	}
+	if f.Version != version {
+		return nil, fmt.Errorf("cassette: %s: unsupported version %d", path, f.Version)
+	}
+	c.interactions = f.Interactions
+	c.used = make([]bool, len(f.Interactions))
+	return c, nil
+}
+
+// Len is the number of exchanges recorded, or loaded for replay.
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions)
+}
+
+// Save writes the recorded exchanges to Path, replacing it atomically. It
+// does nothing in Replay mode.
+func (c *Cassette) Save() error {
+	if c.Mode != Record {
+		return nil
+	}
+	c.mu.Lock()
+	b, err := json.MarshalIndent(file{Version: version, Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	err = atomicfile.Write(c.Path, 0o600, func(w io.Writer) error {
+		_, err := w.Write(append(b, '\n'))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// Transport wraps next (http.DefaultTransport when nil) so that requests
+// are recorded or replayed according to the cassette's mode.
+func (c *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{c: c, next: next}
+}
+
+type transport struct {
+	c    *Cassette
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.c.Mode == Replay {
+		return t.c.replay(req, body)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		// Network errors are not recorded; a replay of the run sees the
+		// retry that followed.
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	// Scrubbing changes the body, so its recorded length would be wrong.
+	respHeader := scrubHeader(resp.Header)
+	delete(respHeader, "Content-Length")
+	t.c.record(Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Header: scrubHeader(req.Header),
+			Body:   scrubBody(body),
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: respHeader,
+			Body:   scrubBody(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// readBody reads and restores the request body.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func (c *Cassette) record(in Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, in)
+}
+
+// ErrNoMatch is returned, wrapped, for a request the cassette has no
+// (unused) exchange for.
+var ErrNoMatch = errors.New("no recorded exchange")
+
+// replay answers with the first unused exchange for the same method, URL
+// and body. Failing that, it settles for the same method and URL, since
+// bodies may carry values that differ between runs (timestamps, ordering).
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	method, u, b := req.Method, scrubURL(req.URL), scrubBody(body)
+
+	c.mu.Lock()
+	match := -1
+	for i, in := range c.interactions {
+		if c.used[i] || in.Request.Method != method || in.Request.URL != u {
+			continue
+		}
+		if in.Request.Body == b {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("cassette %s: %w for %s %s", c.Path, ErrNoMatch, method, u)
+	}
+	c.used[match] = true
+	rec := c.interactions[match].Response
+	c.mu.Unlock()
+
+	header := rec.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		switch {
+		case r.URL.Path == "/auth":
+			io.WriteString(w, `{"access_token":"tok-123","token_type":"Bearer"}`)
+		case calls == 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"message":"slow down"}`)
+		default:
+			io.WriteString(w, `{"id":"p1","email":"Jane.Doe@corp.com","firstName":"Jane"}`)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "run.cassette.json")
+	rec, err := Open(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec.Transport(nil)}
+	exchange := func(client *http.Client, method, url, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok-123")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	exchange(client, http.MethodPost, srv.URL+"/auth", `{"username":"jane.doe@corp.com","password":"hunter2"}`)
+	exchange(client, http.MethodGet, srv.URL+"/employees?email=jane.doe@corp.com", "")
+	if status, body := exchange(client, http.MethodGet, srv.URL+"/employees?email=jane.doe@corp.com", ""); status != 200 || !strings.Contains(body, "Jane.Doe@corp.com") {
+		t.Fatalf("Expected the live response while recording, got %d %s", status, body)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(path)
+	for _, secret := range []string{"tok-123", "hunter2", "corp.com", "Jane", "abc"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("Cassette leaks %q:\n%s", secret, b)
+		}
+	}
+
+	srv.Close()
+	rep, err := Open(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Len() != 3 {
+		t.Fatalf("Expected 3 exchanges, got %d", rep.Len())
+	}
+	client = &http.Client{Transport: rep.Transport(nil)}
+	if status, body := exchange(client, http.MethodPost, srv.URL+"/auth", `{"username":"jane.doe@corp.com","password":"other"}`); status != 200 || !strings.Contains(body, `"access_token":"REDACTED"`) {
+		t.Errorf("Unexpected auth replay %d %s", status, body)
+	}
+	// Replayed data carries pseudonyms, which must still match the recording.
+	pseudonym := scrubText("jane.doe@corp.com")
+	if status, _ := exchange(client, http.MethodGet, srv.URL+"/employees?email="+pseudonym, ""); status != http.StatusTooManyRequests {
+		t.Errorf("Expected the recorded 429 first, got %d", status)
+	}
+	status, body := exchange(client, http.MethodGet, srv.URL+"/employees?email=jane.doe@corp.com", "")
+	if status != 200 || !strings.Contains(body, pseudonym) || !strings.Contains(body, `"firstName":"REDACTED"`) {
+		t.Errorf("Unexpected employee replay %d %s", status, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/employees?email=jane.doe@corp.com", nil)
+	if _, err := client.Do(req); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Expected ErrNoMatch once the exchanges are used up, got %v", err)
+	}
+}
+
+func TestScrubText(t *testing.T) {
+	a := scrubText("contact: Jane.Doe@corp.com")
+	if a != scrubText("contact: jane.doe@CORP.com") || strings.Contains(a, "corp.com") {
+		t.Errorf("Expected a stable, case-insensitive pseudonym, got %q", a)
+	}
+	if scrubText(a) != a {
+		t.Errorf("Expected scrubbing to be idempotent, got %q", scrubText(a))
+	}
+}
+
+func TestOpenReplayMissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json"), Replay); err == nil {
+		t.Error("Expected an error for a missing cassette")
+	}
+}
@@ -0,0 +1,131 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveHeaders carry credentials or session state.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// secretKeys and personalKeys are JSON keys and query parameters whose
+// values are redacted, compared after lowercasing and dropping '_' and '-'.
+var (
+	secretKeys = map[string]bool{
+		"password": true, "token": true, "accesstoken": true, "refreshtoken": true, "idtoken": true,
+		"clientsecret": true, "secret": true, "apikey": true, "authorization": true,
+	}
+	personalKeys = map[string]bool{
+		"firstname": true, "lastname": true, "fullname": true, "displayname": true,
+		"phone": true, "phonenumber": true, "mobile": true, "address": true, "birthdate": true,
+	}
+)
+
+func sensitiveKey(k string) bool {
+	k = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(k))
+	return secretKeys[k] || personalKeys[k]
+}
+
+var emailRe = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+const pseudonymDomain = "@example.invalid"
+
+// scrubText replaces email addresses with stable pseudonyms. Pseudonyms are
+// left alone, so scrubbing is idempotent (replayed requests carry them).
+func scrubText(s string) string {
+	return emailRe.ReplaceAllStringFunc(s, func(email string) string {
+		lower := strings.ToLower(email)
+		if strings.HasSuffix(lower, pseudonymDomain) {
+			return email
+		}
+		return fmt.Sprintf("user-%08x%s", crc32.ChecksumIEEE([]byte(lower)), pseudonymDomain)
+	})
+}
+
+func scrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for _, k := range sensitiveHeaders {
+		if _, ok := out[k]; ok {
+			out[k] = []string{redacted}
+		}
+	}
+	return out
+}
+
+// scrubURL scrubs the query and path; the query comes back sorted, so the
+// result also serves as the replay key.
+func scrubURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	c.Path = scrubText(c.Path)
+	c.RawPath = ""
+	if c.RawQuery != "" {
+		q := c.Query()
+		for k, vs := range q {
+			for i, v := range vs {
+				if sensitiveKey(k) {
+					vs[i] = redacted
+				} else {
+					vs[i] = scrubText(v)
+				}
+			}
+		}
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// scrubBody scrubs a JSON body value by value; anything else as text.
+func scrubBody(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return scrubText(string(b))
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(scrubValue(v)); err != nil {
+		return scrubText(string(b))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func scrubValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if val != nil && sensitiveKey(k) {
+				if _, nested := val.(map[string]any); !nested {
+					t[k] = redacted
+					continue
+				}
+			}
+			t[k] = scrubValue(val)
+		}
+		return t
+	case []any:
+		for i := range t {
+			t[i] = scrubValue(t[i])
+		}
+		return t
+	case string:
+		return scrubText(t)
+	}
+	return v
+}
@@ -12,6 +12,28 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/logging"
+	"course-sync/internal/metrics"
+	"course-sync/internal/tracing"
+)
+
+var tracer = otel.Tracer("course-sync/internal/httpx")
+
+var (
+	requestsTotal = metrics.NewCounter("course_sync_http_requests_total",
+		"HTTP attempts made by httpx, by host, method and status code (\"error\" when no response was read).",
+		"host", "method", "code")
+	requestDuration = metrics.NewHistogram("course_sync_http_request_duration_seconds",
+		"Duration of httpx attempts, including reading the body.",
+		metrics.HTTPBuckets, "host")
+	retriesTotal = metrics.NewCounter("course_sync_http_retries_total",
+		"Retries made by httpx, by host and error class.",
+		"host", "class")
 )
 
 func init() {
@@ -40,19 +62,54 @@ func snippet(b []byte, max int) string {
 	return s[:max] + "…"
 }
 
+// ErrorClass labels why an attempt failed. It selects the backoff multiplier
+// and is reported to RetryConfig.OnRetry.
+type ErrorClass string
+
+const (
+	ClassNone     ErrorClass = ""
+	ClassNetwork  ErrorClass = "network"   // transport/read errors (timeouts, resets, EOF)
+	ClassGoAway   ErrorClass = "goaway"    // HTTP/2 GOAWAY or server-closed connection
+	ClassStatus   ErrorClass = "status"    // non-2xx response
+	ClassHTMLBody ErrorClass = "html_body" // 2xx response carrying an HTML page instead of JSON
+)
+
 // RetryConfig controls retry behavior.
 type RetryConfig struct {
 	MaxAttempts int
 	BaseDelay   time.Duration
 	MaxDelay    time.Duration
 
+	// Jitter is the upper bound of the random delay added to each computed
+	// backoff (default 400ms). It is not added to Retry-After waits.
+	Jitter time.Duration
+
 	// If true, retry any 5xx.
 	Retry5xx bool
 
 	// Extra statuses to retry (e.g. 429, 408).
 	RetryStatuses map[int]bool
+
+	// If true, a 2xx response whose body looks like an HTML page (gateway or
+	// maintenance pages served with 200) is retried as ClassHTMLBody.
+	RetryOnHTMLBody bool
+
+	// Multipliers scales the computed backoff per error class before MaxDelay
+	// is applied, e.g. {ClassGoAway: 2} waits twice as long after a GOAWAY.
+	Multipliers map[ErrorClass]float64
+
+	// Classify, if set, is consulted before the default rules for every attempt
+	// (err is nil when a response was read). Returning ClassNone falls back to
+	// the defaults; any other class decides the outcome with the returned bool.
+	Classify func(resp *http.Response, body []byte, err error) (ErrorClass, bool)
+
+	// OnRetry, if set, is called before sleeping for the next attempt. Retries
+	// are logged (to the logger in the context) either way.
+	OnRetry func(attempt int, class ErrorClass, err error, sleep time.Duration)
 }
 
+const defaultJitter = 400 * time.Millisecond
+
 func DefaultRetryConfig() RetryConfig {
 	return RetryConfig{
 		MaxAttempts: 8,
@@ -88,6 +145,9 @@ func DoWithRetry(
 	if cfg.MaxDelay <= 0 {
 		cfg.MaxDelay = 30 * time.Second
 	}
+	if cfg.Jitter <= 0 {
+		cfg.Jitter = defaultJitter
+	}
 	if cfg.RetryStatuses == nil {
 		cfg.RetryStatuses = DefaultRetryConfig().RetryStatuses
 	}
@@ -99,58 +159,74 @@ func DoWithRetry(
 			return nil, nil, err
 		}
 
+		host := req.URL.Host
+		start := time.Now()
+		span := startAttempt(ctx, req, attempt)
 		resp, err := client.Do(req)
 		if err != nil {
-			if isRetryableNetErr(err) {
+			observe(req, "error", start)
+			class, retry := cfg.classify(nil, nil, err)
+			retry = retry && attempt < cfg.MaxAttempts
+			endAttempt(span, 0, class, retry, err)
+			if retry {
 				lastErr = err
-				if attempt < cfg.MaxAttempts {
-					if err := sleepBackoff(ctx, attempt, cfg.BaseDelay, cfg.MaxDelay, 0); err != nil {
-						return nil, nil, err
-					}
-					continue
+				if err := cfg.wait(ctx, host, attempt, class, err, 0); err != nil {
+					return nil, nil, err
 				}
+				continue
 			}
 			return nil, nil, err
 		}
 
 		body, readErr := readAndClose(resp.Body)
 		if readErr != nil {
-			if isRetryableNetErr(readErr) {
+			observe(req, "error", start)
+			class, retry := cfg.classify(resp, body, readErr)
+			retry = retry && attempt < cfg.MaxAttempts
+			endAttempt(span, resp.StatusCode, class, retry, readErr)
+			if retry {
 				lastErr = readErr
-				if attempt < cfg.MaxAttempts {
-					if err := sleepBackoff(ctx, attempt, cfg.BaseDelay, cfg.MaxDelay, 0); err != nil {
-						return nil, nil, err
-					}
-					continue
+				if err := cfg.wait(ctx, host, attempt, class, readErr, 0); err != nil {
+					return nil, nil, err
 				}
+				continue
 			}
 			return resp, body, readErr
 		}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		observe(req, strconv.Itoa(resp.StatusCode), start)
+
+		class, retry := cfg.classify(resp, body, nil)
+		if class == ClassNone {
+			endAttempt(span, resp.StatusCode, class, false, nil)
 			return resp, body, nil
 		}
 
-		herr := &HTTPError{
-			Method:     req.Method,
-			URL:        req.URL.String(),
-			StatusCode: resp.StatusCode,
-			Header:     resp.Header.Clone(),
-			Body:       body,
+		var attemptErr error
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			attemptErr = fmt.Errorf("httpx: %s %s returned status=%d with unexpected %s body=%s", req.Method, req.URL.String(), resp.StatusCode, class, snippet(body, 300))
+		} else {
+			attemptErr = &HTTPError{
+				Method:     req.Method,
+				URL:        req.URL.String(),
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header.Clone(),
+				Body:       body,
+			}
 		}
+		endAttempt(span, resp.StatusCode, class, retry && attempt < cfg.MaxAttempts, attemptErr)
 
-		retryAfter := ParseRetryAfter(resp)
-		if isRetryableStatus(resp.StatusCode, cfg) {
-			lastErr = herr
+		if retry {
+			lastErr = attemptErr
 			if attempt < cfg.MaxAttempts {
-				if err := sleepBackoff(ctx, attempt, cfg.BaseDelay, cfg.MaxDelay, retryAfter); err != nil {
+				if err := cfg.wait(ctx, host, attempt, class, attemptErr, ParseRetryAfter(resp)); err != nil {
 					return nil, nil, err
 				}
 				continue
 			}
 		}
 
-		return resp, body, herr
+		return resp, body, attemptErr
 	}
 
 	if lastErr != nil {
@@ -159,6 +235,98 @@ func DoWithRetry(
 	return nil, nil, errors.New("httpx: request failed")
 }
 
+// classify decides the error class of an attempt and whether it is retryable.
+// A successful attempt is reported as ClassNone.
+func (cfg RetryConfig) classify(resp *http.Response, body []byte, err error) (ErrorClass, bool) {
+	if cfg.Classify != nil {
+		if class, retry := cfg.Classify(resp, body, err); class != ClassNone {
+			return class, retry
+		}
+	}
+
+	if err != nil {
+		if isGoAwayErr(err) {
+			return ClassGoAway, true
+		}
+		return ClassNetwork, isRetryableNetErr(err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if cfg.RetryOnHTMLBody && looksLikeHTML(body) {
+			return ClassHTMLBody, true
+		}
+		return ClassNone, false
+	}
+	return ClassStatus, isRetryableStatus(resp.StatusCode, cfg)
+}
+
+// backoff computes the wait before the next attempt. A positive retryAfter
+// (from the Retry-After header) wins over the exponential schedule.
+func (cfg RetryConfig) backoff(attempt int, class ErrorClass, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	sleep := cfg.BaseDelay * time.Duration(1<<(attempt-1))
+	if m := cfg.Multipliers[class]; m > 0 {
+		sleep = time.Duration(float64(sleep) * m)
+	}
+	if sleep > cfg.MaxDelay || sleep <= 0 {
+		sleep = cfg.MaxDelay
+	}
+	if cfg.Jitter > 0 {
+		sleep += time.Duration(rand.Int63n(int64(cfg.Jitter)))
+	}
+	return sleep
+}
+
+func (cfg RetryConfig) wait(ctx context.Context, host string, attempt int, class ErrorClass, err error, retryAfter time.Duration) error {
+	retriesTotal.Inc(host, string(class))
+	sleep := cfg.backoff(attempt, class, retryAfter)
+	logging.FromContext(ctx).Warn("retrying request", "component", "httpx", "host", host,
+		"attempt", attempt, "class", string(class), "sleep", sleep, logging.Err(err))
+	if cfg.OnRetry != nil {
+		cfg.OnRetry(attempt, class, err, sleep)
+	}
+	return sleepCtx(ctx, sleep)
+}
+
+// startAttempt starts the client span of one attempt. The request keeps its
+// own context: the span only times the attempt, nothing is propagated to
+// the provider APIs.
+func startAttempt(ctx context.Context, req *http.Request, attempt int) trace.Span {
+	_, span := tracer.Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+			attribute.Int("http.request.resend_count", attempt-1),
+		))
+	return span
+}
+
+// endAttempt ends an attempt span, recording why it failed and whether it
+// will be retried.
+func endAttempt(span trace.Span, status int, class ErrorClass, retry bool, err error) {
+	if status > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+	if class != ClassNone {
+		span.SetAttributes(
+			attribute.String("error.type", string(class)),
+			attribute.Bool("retry", retry),
+		)
+	}
+	tracing.End(span, &err)
+}
+
+// observe records one attempt in the request metrics.
+func observe(req *http.Request, code string, start time.Time) {
+	requestsTotal.Inc(req.URL.Host, req.Method, code)
+	requestDuration.Observe(time.Since(start).Seconds(), req.URL.Host)
+}
+
 func readAndClose(rc io.ReadCloser) ([]byte, error) {
 	defer rc.Close()
 	return io.ReadAll(rc)
@@ -174,18 +342,8 @@ func isRetryableStatus(code int, cfg RetryConfig) bool {
 	return false
 }
 
-func sleepBackoff(ctx context.Context, attempt int, base, max time.Duration, retryAfter time.Duration) error {
-	sleep := retryAfter
-	if sleep <= 0 {
-		sleep = base * time.Duration(1<<(attempt-1))
-		if sleep > max {
-			sleep = max
-		}
-		// jitter 0..400ms
-		sleep += time.Duration(rand.Intn(400)) * time.Millisecond
-	}
-
-	t := time.NewTimer(sleep)
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
 	defer t.Stop()
 	select {
 	case <-t.C:
@@ -213,7 +371,20 @@ func isRetryableNetErr(err error) bool {
 	if strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe") || strings.Contains(msg, "eof") {
 		return true
 	}
-	return false
+	return isGoAwayErr(err)
+}
+
+// isGoAwayErr reports whether err comes from the server tearing down an
+// HTTP/2 connection (GOAWAY) or closing a reused connection under us.
+func isGoAwayErr(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "GOAWAY") || strings.Contains(msg, "connection closed")
+}
+
+// looksLikeHTML reports whether body is an HTML document rather than an API payload.
+func looksLikeHTML(body []byte) bool {
+	s := strings.ToLower(strings.TrimSpace(string(body[:min(len(body), 64)])))
+	return strings.HasPrefix(s, "<!doctype html") || strings.HasPrefix(s, "<html")
 }
 
 // ParseRetryAfter parses Retry-After header (seconds or HTTP date).
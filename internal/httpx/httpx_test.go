@@ -138,6 +138,16 @@ func TestIsRetryableNetErr(t *testing.T) {
 		t.Error("Expected 'EOF' error to be retryable")
 	}
 
+	goAwayErr := errors.New("http2: server sent GOAWAY and closed the connection")
+	if !isRetryableNetErr(goAwayErr) || !isGoAwayErr(goAwayErr) {
+		t.Error("Expected GOAWAY error to be retryable and classified as GOAWAY")
+	}
+
+	closedErr := errors.New("http: server closed idle connection; connection closed")
+	if !isGoAwayErr(closedErr) {
+		t.Error("Expected 'connection closed' error to be classified as GOAWAY")
+	}
+
 	// Test non-retryable error
 	otherErr := errors.New("some other error")
 	if isRetryableNetErr(otherErr) {
@@ -0,0 +1,130 @@
+// Package httpxtest holds helpers shared by the in-process API fakes
+// (eightfoldtest, udemytest, pluralsighttest): a request log and scripted
+// faults that exercise the retry paths of the real clients.
+package httpxtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault makes matching requests fail or slow down.
+type Fault struct {
+	// Method and Path select the requests; empty matches any. Path is a
+	// prefix of the URL path, e.g. "/api/v2/core/employees".
+	Method string
+	Path   string
+	// Status is returned instead of the real answer; 0 only delays, unless
+	// Body is set.
+	Status int
+	// RetryAfter is sent as the Retry-After header with Status.
+	RetryAfter string
+	// Body, when set, replaces the JSON error body, e.g. an HTML gateway
+	// page or a GraphQL error. It is served with Status, or 200 when
+	// Status is 0, as ContentType (application/json by default).
+	Body        string
+	ContentType string
+	// Delay is waited before answering (or until the client gives up).
+	Delay time.Duration
+	// Times is the number of requests the fault applies to; 0 means all.
+	Times int
+}
+
+// Faults records requests and applies injected faults. The zero value is
+// ready to use; fakes embed it and wrap their mux with Handler.
+type Faults struct {
+	mu       sync.Mutex
+	faults   []*Fault
+	requests []string
+}
+
+// Inject adds a fault. Faults are checked in the order they were added.
+func (f *Faults) Inject(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, &fault)
+}
+
+// Requests returns "METHOD /path?query" for every request received.
+func (f *Faults) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.requests)
+}
+
+// Handler logs each request and applies the first matching fault before
+// handing the request to next.
+func (f *Faults) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := f.match(r)
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		switch {
+		case fault.Body != "":
+			contentType := fault.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(statusOrOK(fault.Status))
+			w.Write([]byte(fault.Body))
+		case fault.Status != 0:
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			WriteError(w, fault.Status, "injected fault")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (f *Faults) match(r *http.Request) Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	for i, fault := range f.faults {
+		if (fault.Method == "" || fault.Method == r.Method) && strings.HasPrefix(r.URL.Path, fault.Path) {
+			matched := *fault
+			if fault.Times > 0 {
+				if fault.Times--; fault.Times == 0 {
+					f.faults = slices.Delete(f.faults, i, i+1)
+				}
+			}
+			return matched
+		}
+	}
+	return Fault{}
+}
+
+func statusOrOK(status int) int {
+	if status == 0 {
+		return http.StatusOK
+	}
+	return status
+}
+
+// WriteJSON answers v as JSON.
+func WriteJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError answers status with a {"message": ...} body.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,84 @@
+// Package logging builds the slog loggers used across course-sync.
+//
+// Every command run gets a logger carrying run_id and command; it travels in
+// the context so that providers, httpx and the SFTP client log with the same
+// attributes (plus their own component/provider):
+//
+//	log := logging.FromContext(ctx).With("component", "sftp")
+//	log.Info("uploaded", "remote", remotePath, "bytes", n)
+//
+// Output is text (default) or JSON, chosen with $COURSE_SYNC_LOG_FORMAT; the
+// minimum level comes from $COURSE_SYNC_LOG_LEVEL (debug, info, warn, error).
+// Tokens, passwords and email addresses are redacted from messages and
+// attributes before they reach the handler (see Redact).
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	levelEnv  = "COURSE_SYNC_LOG_LEVEL"
+	formatEnv = "COURSE_SYNC_LOG_FORMAT"
+)
+
+// Options selects the handler.
+type Options struct {
+	Level slog.Level
+	JSON  bool
+}
+
+// OptionsFromEnv reads $COURSE_SYNC_LOG_LEVEL and $COURSE_SYNC_LOG_FORMAT.
+func OptionsFromEnv() (Options, error) {
+	var opts Options
+	if v := strings.TrimSpace(os.Getenv(levelEnv)); v != "" {
+		if err := opts.Level.UnmarshalText([]byte(v)); err != nil {
+			return Options{}, fmt.Errorf("%s: invalid level %q (want debug, info, warn or error)", levelEnv, v)
+		}
+	}
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv(formatEnv))); v {
+	case "", "text":
+	case "json":
+		opts.JSON = true
+	default:
+		return Options{}, fmt.Errorf("%s: invalid format %q (want text or json)", formatEnv, v)
+	}
+	return opts, nil
+}
+
+// New returns a redacting logger writing to w.
+func New(w io.Writer, opts Options) *slog.Logger {
+	hopts := &slog.HandlerOptions{Level: opts.Level}
+	var h slog.Handler
+	if opts.JSON {
+		h = slog.NewJSONHandler(w, hopts)
+	} else {
+		h = slog.NewTextHandler(w, hopts)
+	}
+	return slog.New(redactHandler{h})
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger in ctx, or slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// Err is the conventional attribute for errors.
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
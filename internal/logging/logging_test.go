@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"plain message", "plain message"},
+		{"Authorization: Bearer eyJhbGciOi.abc-def", "Authorization: Bearer [REDACTED]"},
+		{"basic dXNlcjpwYXNz==", "basic [REDACTED]"},
+		{"GET /x?access_token=abc123&page=2", "GET /x?access_token=[REDACTED]&page=2"},
+		{`body={"access_token":"abc","expires_in":3600}`, `body={"access_token":"[REDACTED]","expires_in":3600}`},
+		{"user jane.doe@femsa.com not found", "user j***@femsa.com not found"},
+		{"a@b.co, x.y@z.example.org", "a***@b.co, x***@z.example.org"},
+	}
+
+	for _, tc := range testCases {
+		if got := Redact(tc.input); got != tc.expected {
+			t.Errorf("Redact(%q) = %q, want %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestJSONLoggerRedactsAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, Options{JSON: true}).With("run_id", "abc", "bearer_token", "tok")
+	log.Info("fetched jane@femsa.com",
+		"email", "jane@femsa.com",
+		"client_secret", "s3cr3t",
+		"password", "",
+		Err(errors.New("401 for Bearer xyz")),
+		slog.Group("req", "authorization", "Basic Zm9v"),
+		"sftp_pass", "hunter2",
+		"db_pwd", "hunter3",
+		"credentials", map[string]string{"user": "u"},
+		"private_key", []byte("-----BEGIN"),
+		"headers", map[string]string{"Authorization": "Bearer abc"},
+		"users", []string{"jane@femsa.com"},
+	)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("Expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":           "fetched j***@femsa.com",
+		"run_id":        "abc",
+		"bearer_token":  redacted,
+		"email":         "j***@femsa.com",
+		"client_secret": redacted,
+		"password":      "",
+		"error":         "401 for Bearer [REDACTED]",
+		"req":           map[string]any{"authorization": redacted},
+		"sftp_pass":     redacted,
+		"db_pwd":        redacted,
+		"credentials":   redacted,
+		"private_key":   redacted,
+		"headers":       "map[Authorization:Bearer [REDACTED]]",
+		"users":         "[j***@femsa.com]",
+	}
+	for k, v := range want {
+		if got, _ := json.Marshal(rec[k]); string(got) != mustJSON(v) {
+			t.Errorf("%s = %s, want %s", k, got, mustJSON(v))
+		}
+	}
+	if strings.Contains(buf.String(), "s3cr3t") || strings.Contains(buf.String(), "xyz") || strings.Contains(buf.String(), "hunter") {
+		t.Errorf("Secret leaked: %s", buf.String())
+	}
+}
+
+func mustJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv(levelEnv, "debug")
+	t.Setenv(formatEnv, "JSON")
+	opts, err := OptionsFromEnv()
+	if err != nil || opts.Level != slog.LevelDebug || !opts.JSON {
+		t.Errorf("OptionsFromEnv() = %+v, %v", opts, err)
+	}
+
+	t.Setenv(levelEnv, "")
+	t.Setenv(formatEnv, "")
+	if opts, err := OptionsFromEnv(); err != nil || opts.Level != slog.LevelInfo || opts.JSON {
+		t.Errorf("OptionsFromEnv() defaults = %+v, %v", opts, err)
+	}
+
+	t.Setenv(levelEnv, "loud")
+	if _, err := OptionsFromEnv(); err == nil {
+		t.Error("Expected an invalid level to be rejected")
+	}
+	t.Setenv(levelEnv, "")
+	t.Setenv(formatEnv, "xml")
+	if _, err := OptionsFromEnv(); err == nil {
+		t.Error("Expected an invalid format to be rejected")
+	}
+}
+
+func TestLevelFiltersDebug(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, Options{Level: slog.LevelInfo})
+	log.Debug("hidden")
+	log.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("Expected slog.Default() without a logger in the context")
+	}
+	l := New(&bytes.Buffer{}, Options{})
+	if FromContext(NewContext(context.Background(), l)) != l {
+		t.Error("Expected the logger stored in the context")
+	}
+}
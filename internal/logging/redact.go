@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute key fragments whose values are never logged.
+var sensitiveKeys = []string{"token", "secret", "pass", "pwd", "authorization", "basic_auth", "api_key", "apikey", "cookie", "credentials", "private_key"}
+
+var (
+	emailRe  = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	schemeRe = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+	paramRe  = regexp.MustCompile(`(?i)("?(?:access_token|refresh_token|client_secret|password|token)"?\s*[:=]\s*"?)[^"&\s,}]+`)
+)
+
+// Redact masks credentials and email addresses in s: "Bearer abc" becomes
+// "Bearer [REDACTED]", token=abc / "password":"abc" lose their values and
+// jane.doe@femsa.com becomes j***@femsa.com (the domain is kept, it helps
+// telling tenants apart).
+func Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	s = schemeRe.ReplaceAllString(s, "$1 "+redacted)
+	s = paramRe.ReplaceAllString(s, "${1}"+redacted)
+	s = emailRe.ReplaceAllString(s, "$1***@$2")
+	return s
+}
+
+func sensitiveKey(key string) bool {
+	k := strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if sensitiveKey(a.Key) {
+		if a.Value.Kind() == slog.KindString && a.Value.String() == "" {
+			return a
+		}
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		out := make([]slog.Attr, len(attrs))
+		for i, ga := range attrs {
+			out[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(out...)}
+	case slog.KindAny:
+		// Structs, maps and the like are logged as their fmt form so
+		// that the strings in them are redacted too.
+		switch v := a.Value.Any().(type) {
+		case nil:
+		case error:
+			return slog.String(a.Key, Redact(v.Error()))
+		default:
+			return slog.String(a.Key, Redact(fmt.Sprint(v)))
+		}
+	}
+	return a
+}
+
+// redactHandler applies redactAttr to every attribute and Redact to the
+// message before passing the record on.
+type redactHandler struct{ slog.Handler }
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = redactAttr(a)
+	}
+	return redactHandler{h.Handler.WithAttrs(out)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name)}
+}
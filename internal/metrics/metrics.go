@@ -0,0 +1,313 @@
+// Package metrics is a small Prometheus registry: labelled counters, gauges
+// and histograms rendered in the text exposition format, served over HTTP
+// (`course-sync serve` exposes /metrics) or written to a file for the
+// node_exporter textfile collector after one-shot runs.
+//
+// Metrics are declared as package variables with NewCounter, NewGauge and
+// NewHistogram, which register them in Default:
+//
+//	var fetched = metrics.NewCounter("course_sync_courses_fetched_total",
+//		"Courses fetched from each provider.", "provider")
+//
+//	fetched.Add(float64(len(courses)), "udemy")
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"course-sync/internal/atomicfile"
+)
+
+// Default is the registry the package-level constructors register in.
+var Default = NewRegistry()
+
+// Registry holds metric families by name.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string // counter, gauge or histogram
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64  // counter, gauge
+	counts []uint64 // histogram, per bucket (not cumulative)
+	sum    float64
+	count  uint64
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.families[f.name]; ok {
+		panic("metrics: duplicate metric " + f.name)
+	}
+	f.series = map[string]*series{}
+	r.families[f.name] = f
+	return f
+}
+
+// lookup returns the series for values, if it exists.
+func (f *family) lookup(values []string) (*series, bool) {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s: got %d label values, want %d", f.name, len(values), len(f.labels)))
+	}
+	s, ok := f.series[strings.Join(values, "\xff")]
+	return s, ok
+}
+
+// with returns the series for values, creating it on first use.
+func (f *family) with(values []string) *series {
+	s, ok := f.lookup(values)
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if f.typ == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[strings.Join(values, "\xff")] = s
+	}
+	return s
+}
+
+// Counter is a labelled, monotonically increasing value.
+type Counter struct{ f *family }
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(&family{name: name, help: help, typ: "counter", labels: labels})}
+}
+
+// NewCounter registers a counter in Default.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// Add adds v (which must not be negative) to the series for labelValues.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: " + c.f.name + ": counter decreased")
+	}
+	c.f.mu.Lock()
+	c.f.with(labelValues).value += v
+	c.f.mu.Unlock()
+}
+
+func (c *Counter) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Value returns the current value of a series, 0 if it was never added to.
+// It does not create the series.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if s, ok := c.f.lookup(labelValues); ok {
+		return s.value
+	}
+	return 0
+}
+
+// Gauge is a labelled value that can go up and down.
+type Gauge struct{ f *family }
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(&family{name: name, help: help, typ: "gauge", labels: labels})}
+}
+
+// NewGauge registers a gauge in Default.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.with(labelValues).value = v
+	g.f.mu.Unlock()
+}
+
+// Value returns the current value of a series, 0 if it was never set.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	v, _ := g.Lookup(labelValues...)
+	return v
+}
+
+// Lookup returns the current value of a series and whether it was ever
+// set. Unlike Set, it does not create the series, so a series that is only
+// looked at is never written out.
+func (g *Gauge) Lookup(labelValues ...string) (float64, bool) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	if s, ok := g.f.lookup(labelValues); ok {
+		return s.value, true
+	}
+	return 0, false
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct{ f *family }
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Histogram{r.register(&family{name: name, help: help, typ: "histogram", labels: labels, buckets: b})}
+}
+
+// NewHistogram registers a histogram in Default.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.with(labelValues)
+	if i := sort.SearchFloat64s(h.f.buckets, v); i < len(h.f.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// Common bucket layouts, in seconds.
+var (
+	HTTPBuckets     = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	TransferBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}
+)
+
+// Label is a constant label added to every series written, see WriteFile.
+type Label struct{ Name, Value string }
+
+// Write renders every family in the Prometheus text format, sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	return r.write(w, nil)
+}
+
+func (r *Registry) write(w io.Writer, constLabels []Label) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		r.mu.Lock()
+		f := r.families[name]
+		r.mu.Unlock()
+		f.write(bw, constLabels)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer, constLabels []Label) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.series) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+	for _, k := range keys {
+		s := f.series[k]
+		labels := make([]Label, 0, len(constLabels)+len(f.labels)+1)
+		labels = append(labels, constLabels...)
+		for i, n := range f.labels {
+			labels = append(labels, Label{n, s.labels[i]})
+		}
+		if f.typ != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, labelString(labels), formatFloat(s.value))
+			continue
+		}
+		var cum uint64
+		for i, le := range f.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelString(append(labels, Label{"le", formatFloat(le)})), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelString(append(labels, Label{"le", "+Inf"})), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, labelString(labels), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, labelString(labels), s.count)
+	}
+}
+
+func labelString(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, l.Name, escapeLabel(l.Value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler serves the registry for Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// WriteFile writes the registry to path atomically (temp file + rename), as
+// the textfile collector may read it at any moment. constLabels are added to
+// every series so that files written by different processes never export
+// the same series, which the collector rejects.
+func (r *Registry) WriteFile(path string, constLabels ...Label) error {
+	err := atomicfile.Write(path, 0o644, func(w io.Writer) error {
+		return r.write(w, constLabels)
+	})
+	if err != nil {
+		return fmt.Errorf("metrics: write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_requests_total", "Requests.\nPer host.", "host", "code")
+	g := r.NewGauge("test_last_success_timestamp_seconds", "Last success.")
+	h := r.NewHistogram("test_duration_seconds", "Duration.", []float64{1, 0.1}, "host")
+	r.NewCounter("test_unused_total", "Never set.")
+
+	c.Inc("a.test", "200")
+	c.Add(2, "a.test", "200")
+	c.Inc(`b"\.test`, "error")
+	g.Set(1.7e9)
+	h.Observe(0.05, "a.test")
+	h.Observe(0.5, "a.test")
+	h.Observe(3, "a.test")
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{host="a.test",le="0.1"} 1
+test_duration_seconds_bucket{host="a.test",le="1"} 2
+test_duration_seconds_bucket{host="a.test",le="+Inf"} 3
+test_duration_seconds_sum{host="a.test"} 3.55
+test_duration_seconds_count{host="a.test"} 3
+# HELP test_last_success_timestamp_seconds Last success.
+# TYPE test_last_success_timestamp_seconds gauge
+test_last_success_timestamp_seconds 1.7e+09
+# HELP test_requests_total Requests.\nPer host.
+# TYPE test_requests_total counter
+test_requests_total{host="a.test",code="200"} 3
+test_requests_total{host="b\"\\.test",code="error"} 1
+`
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestValueDoesNotCreateSeries(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_total", "x", "a")
+	g := r.NewGauge("test_seconds", "x", "a")
+
+	if v := c.Value("missing"); v != 0 {
+		t.Errorf("Counter.Value() = %v, want 0", v)
+	}
+	if v, ok := g.Lookup("missing"); v != 0 || ok {
+		t.Errorf("Gauge.Lookup() = %v, %v; want 0, false", v, ok)
+	}
+	g.Set(0, "zero")
+	if v, ok := g.Lookup("zero"); v != 0 || !ok {
+		t.Errorf("Gauge.Lookup() = %v, %v; want 0, true", v, ok)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "missing") || !strings.Contains(buf.String(), `test_seconds{a="zero"} 0`) {
+		t.Errorf("Expected only the set series written, got:\n%s", buf.String())
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_total", "x", "a")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for missing label values")
+		}
+	}()
+	c.Inc()
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("test_gauge", "x")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a duplicate metric")
+		}
+	}()
+	r.NewCounter("test_gauge", "x")
+}
+
+func TestHandlerAndWriteFile(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "x").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") ||
+		!strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("Unexpected response: %q %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "textfile", "course_sync.prom")
+	if err := r.WriteFile(path, Label{"profile", "sync-courses"}); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(b), `test_total{profile="sync-courses"} 1`+"\n") {
+		t.Errorf("ReadFile() = %q, %v", b, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the metrics file to remain, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,316 @@
+// Package pgp encrypts (and optionally signs) files for the Eightfold
+// inbound directory with OpenPGP, and decrypts them again for
+// troubleshooting.
+//
+// Keys are read from files, ASCII-armored or binary, RSA or elliptic-curve
+// (Curve25519 and the NIST curves). Encrypted output is binary (.pgp) or
+// ASCII-armored (.asc). It uses github.com/ProtonMail/go-crypto/openpgp,
+// the maintained fork of golang.org/x/crypto/openpgp.
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"course-sync/internal/atomicfile"
+)
+
+// Config selects the keys for Encrypter.
+type Config struct {
+	// Recipients are public key files; the output can be decrypted with the
+	// private key of any of them.
+	Recipients []string
+	// SigningKey is a private key file to sign with. Empty means unsigned.
+	SigningKey        string
+	SigningPassphrase string
+	// Armor writes ASCII-armored (.asc) instead of binary (.pgp) output.
+	Armor bool
+}
+
+// Ext is the file name suffix of the encrypted output.
+func (c Config) Ext() string {
+	if c.Armor {
+		return ".asc"
+	}
+	return ".pgp"
+}
+
+// Encrypted reports whether name already has an OpenPGP suffix, so that a
+// file is not encrypted twice.
+func Encrypted(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pgp", ".gpg", ".asc":
+		return true
+	}
+	return false
+}
+
+// Encrypter encrypts files to a fixed set of recipients.
+type Encrypter struct {
+	cfg    Config
+	to     openpgp.EntityList
+	signer *openpgp.Entity
+}
+
+// NewEncrypter reads the keys named by cfg.
+func NewEncrypter(cfg Config) (*Encrypter, error) {
+	if len(cfg.Recipients) == 0 {
+		return nil, errors.New("pgp: no recipient key")
+	}
+	to, err := ReadKeys(cfg.Recipients...)
+	if err != nil {
+		return nil, err
+	}
+	e := &Encrypter{cfg: cfg, to: to}
+	if cfg.SigningKey != "" {
+		keys, err := ReadKeys(cfg.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		if keys[0].PrivateKey == nil {
+			return nil, fmt.Errorf("pgp: %s: not a private key", cfg.SigningKey)
+		}
+		if err := Unlock(keys, cfg.SigningPassphrase); err != nil {
+			return nil, fmt.Errorf("pgp: %s: %w", cfg.SigningKey, err)
+		}
+		e.signer = keys[0]
+	}
+	return e, nil
+}
+
+// Signed reports whether the output is signed.
+func (e *Encrypter) Signed() bool { return e.signer != nil }
+
+// Encrypt writes src, named name in the message, to dst encrypted.
+func (e *Encrypter) Encrypt(dst io.Writer, src io.Reader, name string, modTime time.Time) error {
+	out := dst
+	var armored io.WriteCloser
+	if e.cfg.Armor {
+		var err error
+		if armored, err = armor.Encode(dst, "PGP MESSAGE", nil); err != nil {
+			return err
+		}
+		out = armored
+	}
+	hints := &openpgp.FileHints{IsBinary: true, FileName: name, ModTime: modTime}
+	w, err := openpgp.Encrypt(out, e.to, e.signer, hints, nil)
+	if err != nil {
+		return fmt.Errorf("pgp: %w", err)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("pgp: %w", err)
+	}
+	if armored != nil {
+		return armored.Close()
+	}
+	return nil
+}
+
+// EncryptFile encrypts the file src to dst, writing through a temporary
+// file so dst is never left half written.
+func (e *Encrypter) EncryptFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(dst, 0o600, func(w io.Writer) error {
+		if err := e.Encrypt(w, in, filepath.Base(src), fi.ModTime()); err != nil {
+			return fmt.Errorf("encrypt %s: %w", src, err)
+		}
+		return nil
+	})
+}
+
+// Details describes a decrypted message.
+type Details struct {
+	// FileName is the name stored in the message.
+	FileName string
+	// EncryptedTo lists the recipient key IDs, in hex.
+	EncryptedTo []string
+	// Signed is set when the message carries a signature; SignedBy is the
+	// signer's key ID and Signer its identity when the key is known.
+	Signed   bool
+	SignedBy string
+	Signer   string
+	// Verified is set when the signature was checked and is good.
+	Verified bool
+}
+
+// ErrUnknownSigner is returned, wrapped, by Decrypt for a message signed
+// with a key not in the keyring.
+var ErrUnknownSigner = errors.New("pgp: signed by an unknown key")
+
+// Decrypt decrypts src, binary or ASCII-armored, into dst with the private
+// keys in keys, and checks the signature against the public keys in it. A
+// bad signature is an error, reported after dst was written; an unknown
+// signer is reported with ErrUnknownSigner.
+func Decrypt(dst io.Writer, src io.Reader, keys openpgp.EntityList) (Details, error) {
+	var d Details
+	br := bufio.NewReader(src)
+	var in io.Reader = br
+	if head, _ := br.Peek(len("-----BEGIN")); string(head) == "-----BEGIN" {
+		block, err := armor.Decode(br)
+		if err != nil {
+			return d, fmt.Errorf("pgp: %w", err)
+		}
+		in = block.Body
+	}
+	md, err := openpgp.ReadMessage(in, keys, nil, nil)
+	if err != nil {
+		return d, fmt.Errorf("pgp: %w", err)
+	}
+	for _, id := range md.EncryptedToKeyIds {
+		d.EncryptedTo = append(d.EncryptedTo, keyID(id))
+	}
+	if md.LiteralData != nil {
+		d.FileName = md.LiteralData.FileName
+	}
+	d.Signed = md.IsSigned
+	if md.IsSigned {
+		d.SignedBy = keyID(md.SignedByKeyId)
+		if md.SignedBy != nil {
+			d.Signer = identity(md.SignedBy.Entity)
+		}
+	}
+	if _, err := io.Copy(dst, md.UnverifiedBody); err != nil {
+		return d, fmt.Errorf("pgp: %w", err)
+	}
+	switch {
+	case md.SignatureError != nil && !md.IsSigned:
+		return d, fmt.Errorf("pgp: integrity check failed: %w", md.SignatureError)
+	case md.SignatureError != nil:
+		return d, fmt.Errorf("pgp: bad signature by %s: %w", d.SignedBy, md.SignatureError)
+	case md.IsSigned && md.SignedBy == nil:
+		return d, fmt.Errorf("%w %s", ErrUnknownSigner, d.SignedBy)
+	}
+	d.Verified = md.IsSigned
+	return d, nil
+}
+
+// ReadKeys reads the keys in the given files, armored or binary.
+func ReadKeys(paths ...string) (openpgp.EntityList, error) {
+	var all openpgp.EntityList
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("pgp: %w", err)
+		}
+		var keys openpgp.EntityList
+		if bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+			keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+		} else {
+			keys, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pgp: %s: %w", p, err)
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("pgp: %s: no keys", p)
+		}
+		all = append(all, keys...)
+	}
+	return all, nil
+}
+
+// Unlock decrypts the passphrase-protected private keys in keys.
+func Unlock(keys openpgp.EntityList, passphrase string) error {
+	for _, e := range keys {
+		privs := []*packet.PrivateKey{e.PrivateKey}
+		for _, sub := range e.Subkeys {
+			privs = append(privs, sub.PrivateKey)
+		}
+		for _, pk := range privs {
+			if pk == nil || !pk.Encrypted {
+				continue
+			}
+			if passphrase == "" {
+				return errors.New("private key is passphrase-protected but no passphrase is set")
+			}
+			if err := pk.Decrypt([]byte(passphrase)); err != nil {
+				return fmt.Errorf("unlocking the private key: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// GenerateKey makes a new RSA key pair for name <email>, e.g. to try the
+// encryption locally.
+func GenerateKey(name, email string, bits int) (*openpgp.Entity, error) {
+	e, err := openpgp.NewEntity(name, "", email, &packet.Config{
+		RSABits:       bits,
+		DefaultHash:   crypto.SHA256,
+		DefaultCipher: packet.CipherAES256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pgp: %w", err)
+	}
+	return e, nil
+}
+
+// WritePublicKey writes the ASCII-armored public key of e.
+func WritePublicKey(w io.Writer, e *openpgp.Entity) error {
+	aw, err := armor.Encode(w, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return err
+	}
+	if err := e.Serialize(aw); err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+// WritePrivateKey writes the ASCII-armored private key of e, unprotected.
+func WritePrivateKey(w io.Writer, e *openpgp.Entity) error {
+	aw, err := armor.Encode(w, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return err
+	}
+	if err := e.SerializePrivate(aw, nil); err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+// Fingerprint is the hex fingerprint of e's primary key.
+func Fingerprint(e *openpgp.Entity) string {
+	return fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+}
+
+func keyID(id uint64) string { return fmt.Sprintf("%016X", id) }
+
+// identity is the user ID of e marked primary, or else its first by name.
+func identity(e *openpgp.Entity) string {
+	names := slices.Sorted(maps.Keys(e.Identities))
+	for _, name := range names {
+		if sig := e.Identities[name].SelfSignature; sig != nil && sig.IsPrimaryId != nil && *sig.IsPrimaryId {
+			return name
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
@@ -0,0 +1,165 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// writeKeys generates a key pair and writes <name>.pub.asc and
+// <name>.key.asc to dir.
+func writeKeys(t *testing.T, dir, name string) (pub, priv string, e *openpgp.Entity) {
+	t.Helper()
+	e, err := GenerateKey(name, name+"@example.com", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv = filepath.Join(dir, name+".pub.asc"), filepath.Join(dir, name+".key.asc")
+	var pb, kb bytes.Buffer
+	if err := WritePublicKey(&pb, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePrivateKey(&kb, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pub, pb.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(priv, kb.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv, e
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	recipientPub, recipientKey, _ := writeKeys(t, dir, "eightfold")
+	signerPub, signerKey, signer := writeKeys(t, dir, "course-sync")
+	_, otherKey, _ := writeKeys(t, dir, "other")
+
+	src := filepath.Join(dir, "ef_course_add.xml")
+	plain := []byte("<courses><course>Go</course></courses>\n")
+	if err := os.WriteFile(src, plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, armor := range []bool{false, true} {
+		cfg := Config{Recipients: []string{recipientPub}, SigningKey: signerKey, Armor: armor}
+		enc, err := NewEncrypter(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := src + cfg.Ext()
+		if err := enc.EncryptFile(src, dst); err != nil {
+			t.Fatalf("EncryptFile() error: %v", err)
+		}
+		ciphertext, _ := os.ReadFile(dst)
+		if bytes.Contains(ciphertext, []byte("<courses>")) || armor != bytes.HasPrefix(ciphertext, []byte("-----BEGIN PGP MESSAGE-----")) {
+			t.Fatalf("Unexpected output with armor %v: %.40q", armor, ciphertext)
+		}
+
+		keys, err := ReadKeys(recipientKey, signerPub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		d, err := Decrypt(&out, bytes.NewReader(ciphertext), keys)
+		if err != nil {
+			t.Fatalf("Decrypt() error: %v", err)
+		}
+		if !bytes.Equal(out.Bytes(), plain) || d.FileName != "ef_course_add.xml" || !d.Verified ||
+			d.SignedBy != keyID(signer.PrimaryKey.KeyId) || !strings.Contains(d.Signer, "course-sync@example.com") {
+			t.Errorf("Decrypt() = %q, %+v", out.Bytes(), d)
+		}
+
+		// Without the signer's public key the content still decrypts, but
+		// the signature cannot be checked.
+		keys, _ = ReadKeys(recipientKey)
+		out.Reset()
+		if d, err := Decrypt(&out, bytes.NewReader(ciphertext), keys); !errors.Is(err, ErrUnknownSigner) || d.Verified || !bytes.Equal(out.Bytes(), plain) {
+			t.Errorf("Expected an unknown signer, got %+v, %v", d, err)
+		}
+
+		keys, _ = ReadKeys(otherKey)
+		if _, err := Decrypt(&out, bytes.NewReader(ciphertext), keys); err == nil {
+			t.Error("Expected decryption with another key to fail")
+		}
+	}
+}
+
+func TestEncryptUnsigned(t *testing.T) {
+	dir := t.TempDir()
+	pub, key, _ := writeKeys(t, dir, "eightfold")
+	enc, err := NewEncrypter(Config{Recipients: []string{pub}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ciphertext bytes.Buffer
+	if err := enc.Encrypt(&ciphertext, strings.NewReader("a,b\n"), "DF_COURSE_IMPORT.csv", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := ReadKeys(key)
+	var out bytes.Buffer
+	d, err := Decrypt(&out, &ciphertext, keys)
+	if err != nil || d.Signed || out.String() != "a,b\n" {
+		t.Errorf("Decrypt() = %q, %+v, %v", out.String(), d, err)
+	}
+}
+
+func TestNewEncrypterErrors(t *testing.T) {
+	dir := t.TempDir()
+	pub, _, _ := writeKeys(t, dir, "eightfold")
+	if _, err := NewEncrypter(Config{}); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+	if _, err := NewEncrypter(Config{Recipients: []string{filepath.Join(dir, "missing.asc")}}); err == nil {
+		t.Error("Expected an error for a missing key file")
+	}
+	if _, err := NewEncrypter(Config{Recipients: []string{pub}, SigningKey: pub}); err == nil || !strings.Contains(err.Error(), "not a private key") {
+		t.Errorf("Expected a public signing key to be rejected, got %v", err)
+	}
+	if !Encrypted("ef_course_add.xml.pgp") || !Encrypted("x.ASC") || Encrypted("ef_course_add.xml") {
+		t.Error("Unexpected Encrypted() result")
+	}
+}
+
+// TestCurve25519Keys encrypts to and signs with elliptic-curve keys, which
+// gpg makes by default.
+func TestCurve25519Keys(t *testing.T) {
+	ecc := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA, Curve: packet.Curve25519}
+	recipient, err := openpgp.NewEntity("eightfold", "", "eightfold@example.com", ecc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := openpgp.NewEntity("course-sync", "", "course-sync@example.com", ecc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second user ID must not change who the signer is reported as.
+	if err := signer.AddUserId("course-sync bot", "", "bot@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	enc := &Encrypter{to: openpgp.EntityList{recipient}, signer: signer}
+	var ciphertext bytes.Buffer
+	if err := enc.Encrypt(&ciphertext, strings.NewReader("a,b\n"), "DF_COURSE_IMPORT.csv", time.Time{}); err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	var out bytes.Buffer
+	d, err := Decrypt(&out, &ciphertext, openpgp.EntityList{recipient, signer})
+	if err != nil || !d.Verified || out.String() != "a,b\n" {
+		t.Fatalf("Decrypt() = %q, %+v, %v", out.String(), d, err)
+	}
+	for range 20 {
+		if got := identity(signer); got != "course-sync <course-sync@example.com>" {
+			t.Fatalf("identity() = %q, want the primary user ID", got)
+		}
+	}
+}
@@ -6,22 +6,42 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"course-sync/internal/httpx"
+	"course-sync/internal/tracing"
 )
 
+var tracer = otel.Tracer("course-sync/internal/providers/eightfold")
+
 const (
 	contentTypeJSON = "application/json"
 	acceptJSON      = contentTypeJSON
 )
 
 type Client struct {
-	BaseURL     string
-	HTTP        *http.Client
+	BaseURL string
+	HTTP    *http.Client
+
+	// BearerToken is a static token (e.g. EIGHTFOLD_BEARER_TOKEN). It is used
+	// as-is when Tokens is nil; Authenticate sets it to the first token issued.
 	BearerToken string
+
+	// Tokens, when set, supplies (and refreshes) the token for every request.
+	// Authenticate installs one backed by the password grant.
+	Tokens *TokenSource
+
+	// MaxPageSize caps the page size of listings; 0 means
+	// DefaultMaxPageSize.
+	MaxPageSize int
 }
 
 type CourseUpsertRequest struct {
@@ -43,7 +63,7 @@ type CourseUpsertRequest struct {
 }
 
 func (c *Client) UpsertCourse(ctx context.Context, course CourseUpsertRequest) error {
-	if c.BearerToken == "" {
+	if !c.hasAuth() {
 		return errors.New("eightfold: missing bearer token (call Authenticate first)")
 	}
 
@@ -52,21 +72,7 @@ func (c *Client) UpsertCourse(ctx context.Context, course CourseUpsertRequest) e
 		return err
 	}
 
-	_, _, err = httpx.DoWithRetry(
-		ctx,
-		c.HTTP,
-		func(ctx context.Context) (*http.Request, error) {
-			r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/v2/core/courses", bytes.NewReader(b))
-			if err != nil {
-				return nil, err
-			}
-			r.Header.Set("Content-Type", contentTypeJSON)
-			r.Header.Set("Accept", acceptJSON)
-			r.Header.Set("Authorization", "Bearer "+c.BearerToken)
-			return r, nil
-		},
-		httpx.DefaultRetryConfig(),
-	)
+	_, _, err = c.do(ctx, http.MethodPost, c.BaseURL+"/api/v2/core/courses", b)
 	if err != nil {
 		return fmt.Errorf("eightfold: upsert course failed: %w", err)
 	}
@@ -93,7 +99,7 @@ type UpdateEmployeeRequest struct {
 }
 
 func (c *Client) UpdateEmployee(ctx context.Context, profileID string, req UpdateEmployeeRequest) error {
-	if c.BearerToken == "" {
+	if !c.hasAuth() {
 		return errors.New("eightfold: missing bearer token")
 	}
 
@@ -104,21 +110,7 @@ func (c *Client) UpdateEmployee(ctx context.Context, profileID string, req Updat
 
 	urlStr := fmt.Sprintf("%s/api/v2/core/employees/%s", c.BaseURL, profileID)
 
-	_, _, err = httpx.DoWithRetry(
-		ctx,
-		c.HTTP,
-		func(ctx context.Context) (*http.Request, error) {
-			r, err := http.NewRequestWithContext(ctx, http.MethodPatch, urlStr, bytes.NewReader(b))
-			if err != nil {
-				return nil, err
-			}
-			r.Header.Set("Content-Type", contentTypeJSON)
-			r.Header.Set("Accept", acceptJSON)
-			r.Header.Set("Authorization", "Bearer "+c.BearerToken)
-			return r, nil
-		},
-		httpx.DefaultRetryConfig(),
-	)
+	_, _, err = c.do(ctx, http.MethodPatch, urlStr, b)
 	if err != nil {
 		return fmt.Errorf("eightfold: update employee failed: %w", err)
 	}
@@ -156,18 +148,35 @@ type AuthResponse struct {
 	} `json:"data"`
 }
 
+// Authenticate performs the password grant and installs a TokenSource that
+// repeats it whenever the token is about to expire (per expires_in) or
+// Eightfold rejects it with a 401.
 func (c *Client) Authenticate(ctx context.Context, basicBase64 string, req AuthRequest) error {
 	b, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
 
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		return c.authenticate(ctx, basicBase64, b)
+	})
+	token, err := ts.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.Tokens = ts
+	c.BearerToken = token
+	return nil
+}
+
+func (c *Client) authenticate(ctx context.Context, basicBase64 string, reqBody []byte) (string, time.Duration, error) {
 	var ar AuthResponse
-	err = httpx.DoJSON(
+	err := httpx.DoJSON(
 		ctx,
 		c.HTTP,
 		func(ctx context.Context) (*http.Request, error) {
-			r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/oauth/v1/authenticate", bytes.NewReader(b))
+			r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/oauth/v1/authenticate", bytes.NewReader(reqBody))
 			if err != nil {
 				return nil, err
 			}
@@ -180,21 +189,76 @@ func (c *Client) Authenticate(ctx context.Context, basicBase64 string, req AuthR
 		httpx.DefaultRetryConfig(),
 	)
 	if err != nil {
-		return fmt.Errorf("eightfold auth failed: %w", err)
+		return "", 0, fmt.Errorf("eightfold auth failed: %w", err)
 	}
 
 	token := ar.Data.AccessToken
 	if token == "" {
-		return fmt.Errorf("eightfold auth: token not found")
+		return "", 0, fmt.Errorf("eightfold auth: token not found")
 	}
-	c.BearerToken = token
-	return nil
+	return token, time.Duration(ar.Data.ExpiresIn) * time.Second, nil
+}
 
+func (c *Client) hasAuth() bool {
+	return c.Tokens != nil || strings.TrimSpace(c.BearerToken) != ""
+}
+
+func (c *Client) bearer(ctx context.Context) (string, error) {
+	if c.Tokens != nil {
+		return c.Tokens.Token(ctx)
+	}
+	return c.BearerToken, nil
+}
+
+// do sends an authenticated request with the default retry policy. body, if
+// non-nil, is sent as JSON. When the token comes from Tokens and Eightfold
+// answers 401, the token is refreshed and the request is sent once more.
+func (c *Client) do(ctx context.Context, method, urlStr string, body []byte) (*http.Response, []byte, error) {
+	send := func(token string) (*http.Response, []byte, error) {
+		return httpx.DoWithRetry(
+			ctx,
+			c.HTTP,
+			func(ctx context.Context) (*http.Request, error) {
+				var rd io.Reader
+				if body != nil {
+					rd = bytes.NewReader(body)
+				}
+				r, err := http.NewRequestWithContext(ctx, method, urlStr, rd)
+				if err != nil {
+					return nil, err
+				}
+				if body != nil {
+					r.Header.Set("Content-Type", contentTypeJSON)
+				}
+				r.Header.Set("Accept", acceptJSON)
+				r.Header.Set("Authorization", "Bearer "+token)
+				return r, nil
+			},
+			httpx.DefaultRetryConfig(),
+		)
+	}
+
+	token, err := c.bearer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, respBody, err := send(token)
+
+	var herr *httpx.HTTPError
+	if c.Tokens != nil && errors.As(err, &herr) && herr.StatusCode == http.StatusUnauthorized {
+		c.Tokens.Invalidate(token)
+		if token, err = c.Tokens.Token(ctx); err != nil {
+			return nil, nil, fmt.Errorf("eightfold: re-authenticate after 401: %w", err)
+		}
+		resp, respBody, err = send(token)
+	}
+	return resp, respBody, err
 }
 
 type ListCoursesResponse struct {
-	Data []map[string]any `json:"data"`
-	Meta ListCoursesMeta  `json:"meta"`
+	Data []Course        `json:"data"`
+	Meta ListCoursesMeta `json:"meta"`
 }
 
 type ListCoursesMeta struct {
@@ -203,10 +267,17 @@ type ListCoursesMeta struct {
 	TotalCount     int `json:"totalCount"`
 }
 
-// ListCoursesPage lists one page of courses. It uses best-effort pagination:
-// some Eightfold tenants honor `pageStartIndex`; if yours doesn't, you can still use ListCourses(limit).
-func (c *Client) ListCoursesPage(ctx context.Context, pageStartIndex int, limit int) ([]map[string]any, ListCoursesMeta, error) {
-	if c.BearerToken == "" {
+// ListCoursesPage lists one page of courses, starting at the offset
+// pageStartIndex (sent as `start`, as paginate does; the response reports it
+// back as meta.pageStartIndex).
+func (c *Client) ListCoursesPage(ctx context.Context, pageStartIndex int, limit int) (_ []Course, _ ListCoursesMeta, err error) {
+	ctx, span := tracer.Start(ctx, "eightfold.courses.page", trace.WithAttributes(
+		attribute.Int("start", pageStartIndex),
+		attribute.Int("limit", limit),
+	))
+	defer tracing.End(span, &err)
+
+	if !c.hasAuth() {
 		return nil, ListCoursesMeta{}, errors.New("eightfold: missing bearer token (call Authenticate first)")
 	}
 
@@ -219,36 +290,35 @@ func (c *Client) ListCoursesPage(ctx context.Context, pageStartIndex int, limit
 		q.Set("limit", fmt.Sprintf("%d", limit))
 	}
 	if pageStartIndex > 0 {
-		// Tenants differ on the paging parameter name; try both.
+		// An offset, not a page number (see paginate).
 		q.Set("start", fmt.Sprintf("%d", pageStartIndex))
 	}
 	u.RawQuery = q.Encode()
 
-	var out ListCoursesResponse
-	err = httpx.DoJSON(
-		ctx,
-		c.HTTP,
-		func(ctx context.Context) (*http.Request, error) {
-			r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-			if err != nil {
-				return nil, err
-			}
-			r.Header.Set("Accept", "application/json")
-			r.Header.Set("Authorization", "Bearer "+c.BearerToken)
-			return r, nil
-		},
-		&out,
-		httpx.DefaultRetryConfig(),
-	)
+	_, body, err := c.do(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, ListCoursesMeta{}, fmt.Errorf("eightfold: list courses failed: %w", err)
 	}
 
+	var out ListCoursesResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, ListCoursesMeta{}, fmt.Errorf("eightfold: list courses: json parse error: %w", err)
+	}
+	span.SetAttributes(attribute.Int("results", len(out.Data)))
+
 	return out.Data, out.Meta, nil
 }
 
-func (c *Client) ListCourses(ctx context.Context, limit int) ([]map[string]any, error) {
+func (c *Client) ListCourses(ctx context.Context, limit int) ([]Course, error) {
 	rows, _, err := c.ListCoursesPage(ctx, 0, limit)
 	return rows, err
 
 }
+
+// CoursesPaginator pages through /api/v2/core/courses, pageSize rows at a
+// time (capped at MaxPageSize).
+func (c *Client) CoursesPaginator(pageSize int) *Paginator {
+	p := c.Paginate("/api/v2/core/courses", "eightfold.courses.page")
+	p.PageSize = pageSize
+	return p
+}
@@ -396,8 +396,8 @@ func TestListCoursesWithMockServer(t *testing.T) {
 		t.Errorf("Expected 1 course, got %d", len(courses))
 	}
 
-	if courses[0]["title"] != "Course 1" {
-		t.Errorf("Expected course title to be 'Course 1', got '%v'", courses[0]["title"])
+	if courses[0].Title != "Course 1" {
+		t.Errorf("Expected course title to be 'Course 1', got '%v'", courses[0].Title)
 	}
 }
 
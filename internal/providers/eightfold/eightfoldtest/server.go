@@ -0,0 +1,342 @@
+// Package eightfoldtest provides an in-process fake of the Eightfold API for
+// end-to-end tests: the password grant, course and employee listings and
+// employee updates, backed by in-memory state, with scripted faults.
+//
+//	srv := eightfoldtest.NewServer()
+//	defer srv.Close()
+//	srv.AddEmployees(map[string]any{"id": "p1", "email": "jane@example.com"})
+//	srv.Inject(eightfoldtest.Fault{Method: "PATCH", Status: 429, Times: 1})
+//	// point EIGHTFOLD_BASE_URL at srv.URL, with srv.Token as bearer token
+package eightfoldtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"course-sync/internal/httpx/httpxtest"
+	"course-sync/internal/providers/eightfold"
+)
+
+// Shape selects the response shape of the employees listing.
+type Shape int
+
+const (
+	// DataMeta answers {"data": [...], "meta": {...}} and pages with
+	// start/limit offsets.
+	DataMeta Shape = iota
+	// ResultsNext answers {"results": [...], "next": "<url>"}.
+	ResultsNext
+)
+
+// Fault makes matching requests fail or slow down; see httpxtest.Fault.
+type Fault = httpxtest.Fault
+
+// Server is a fake Eightfold API. Its fields configure the behaviour and
+// may be set before the first request.
+type Server struct {
+	*httptest.Server
+	// Faults logs the requests and holds the injected faults.
+	httpxtest.Faults
+
+	// Token is a bearer token that is always accepted.
+	Token string
+	// BasicAuth, Username and Password are what the password grant
+	// expects; a token it issues is valid for TokenTTL.
+	BasicAuth string
+	Username  string
+	Password  string
+	TokenTTL  time.Duration
+
+	// PageSize is the size of a page asked for without a limit, and
+	// MaxLimit the largest limit accepted (larger ones get a 400).
+	PageSize int
+	MaxLimit int
+	// EmployeeShape is the response shape of the employees listing.
+	EmployeeShape Shape
+	// RejectUnknownParams answers a 400 to query parameters the fake does
+	// not know (pageStartIndex, filters...), as strict tenants do.
+	RejectUnknownParams bool
+	// HonorFields applies the fields projection of the employees listing
+	// (and accepts the parameter with RejectUnknownParams), as tenants do
+	// that support the projection but not the filters.
+	HonorFields bool
+
+	mu        sync.Mutex
+	issued    map[string]time.Time
+	tokens    int
+	courses   []map[string]any
+	employees []map[string]any
+	updates   map[string][]eightfold.UpdateEmployeeRequest
+}
+
+// NewServer starts a fake Eightfold with no data. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Token:     "emulator-token",
+		BasicAuth: "ZW11bGF0b3I6c2VjcmV0",
+		Username:  "emulator@example.com",
+		Password:  "secret",
+		TokenTTL:  time.Hour,
+		PageSize:  100,
+		MaxLimit:  100,
+		issued:    map[string]time.Time{},
+		updates:   map[string][]eightfold.UpdateEmployeeRequest{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/v1/authenticate", s.authenticate)
+	mux.HandleFunc("GET /api/v2/core/courses", s.authorized(s.listCourses))
+	mux.HandleFunc("POST /api/v2/core/courses", s.authorized(s.upsertCourse))
+	mux.HandleFunc("GET /api/v2/core/employees", s.authorized(s.listEmployees))
+	mux.HandleFunc("PATCH /api/v2/core/employees/{id}", s.authorized(s.updateEmployee))
+	s.Server = httptest.NewServer(s.Handler(mux))
+	return s
+}
+
+// AddCourses appends course records to the catalog.
+func (s *Server) AddCourses(courses ...map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.courses = append(s.courses, courses...)
+}
+
+// AddEmployees appends employee records.
+func (s *Server) AddEmployees(employees ...map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.employees = append(s.employees, employees...)
+}
+
+// Courses returns a copy of the catalog.
+func (s *Server) Courses() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.courses)
+}
+
+// Employee returns the record of the employee with the given id, with the
+// updates applied, or nil.
+func (s *Server) Employee(id string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.employeeIndex(id); i >= 0 {
+		return s.employees[i]
+	}
+	return nil
+}
+
+// Updates returns the update requests received for an employee, in order.
+func (s *Server) Updates(id string) []eightfold.UpdateEmployeeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.updates[id])
+}
+
+// ExpireTokens invalidates every token issued by the password grant, so the
+// next request with one gets a 401.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.issued)
+}
+
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		expires, issued := s.issued[token]
+		s.mu.Unlock()
+		if !ok || token == "" || (token != s.Token && (!issued || time.Now().After(expires))) {
+			httpxtest.WriteError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) {
+	var req eightfold.AuthRequest
+	if r.Header.Get("Authorization") != "Basic "+s.BasicAuth {
+		httpxtest.WriteError(w, http.StatusUnauthorized, "invalid client credentials")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GrantType != "password" {
+		httpxtest.WriteError(w, http.StatusBadRequest, "invalid grant")
+		return
+	}
+	if req.Username != s.Username || req.Password != s.Password {
+		httpxtest.WriteError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+	s.mu.Lock()
+	s.tokens++
+	token := fmt.Sprintf("emulator-issued-%d", s.tokens)
+	s.issued[token] = time.Now().Add(s.TokenTTL)
+	s.mu.Unlock()
+
+	var resp eightfold.AuthResponse
+	resp.Data.AccessToken = token
+	resp.Data.ExpiresIn = int(s.TokenTTL / time.Second)
+	resp.Data.TokenType = "Bearer"
+	httpxtest.WriteJSON(w, resp)
+}
+
+// paging reads start and limit, rejecting what a tenant would.
+func (s *Server) paging(w http.ResponseWriter, q url.Values, known ...string) (start, limit int, ok bool) {
+	for k := range q {
+		if s.RejectUnknownParams && !slices.Contains(known, k) {
+			httpxtest.WriteError(w, http.StatusBadRequest, "error validating query parameters: "+k)
+			return 0, 0, false
+		}
+	}
+	start, limit = 0, s.PageSize
+	var err error
+	if v := q.Get("start"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil || start < 0 {
+			httpxtest.WriteError(w, http.StatusBadRequest, "invalid start")
+			return 0, 0, false
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > s.MaxLimit {
+			httpxtest.WriteError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", s.MaxLimit))
+			return 0, 0, false
+		}
+	}
+	return start, limit, true
+}
+
+func (s *Server) listCourses(w http.ResponseWriter, r *http.Request) {
+	start, limit, ok := s.paging(w, r.URL.Query(), "start", "limit")
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	rows, total := window(s.courses, start, limit)
+	s.mu.Unlock()
+	writeDataMeta(w, rows, start, total)
+}
+
+func (s *Server) upsertCourse(w http.ResponseWriter, r *http.Request) {
+	var course map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
+		httpxtest.WriteError(w, http.StatusBadRequest, "invalid course")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := course["lmsCourseId"].(string)
+	for i, c := range s.courses {
+		if c["lmsCourseId"] == id {
+			s.courses[i] = course
+			httpxtest.WriteJSON(w, map[string]any{"data": course})
+			return
+		}
+	}
+	s.courses = append(s.courses, course)
+	httpxtest.WriteJSON(w, map[string]any{"data": course})
+}
+
+func (s *Server) listEmployees(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var fields []string
+	if s.HonorFields && q.Get("fields") != "" {
+		fields = strings.Split(q.Get("fields"), ",")
+	}
+	if s.EmployeeShape == ResultsNext {
+		if _, _, ok := s.paging(w, q, s.employeeParams("page", "limit")...); !ok {
+			return
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		page = max(page, 1)
+		s.mu.Lock()
+		rows, total := window(s.employees, (page-1)*s.PageSize, s.PageSize)
+		s.mu.Unlock()
+		next := ""
+		if page*s.PageSize < total {
+			next = fmt.Sprintf("%s%s?page=%d", s.URL, r.URL.Path, page+1)
+		}
+		httpxtest.WriteJSON(w, map[string]any{"results": project(rows, fields), "next": next, "count": total})
+		return
+	}
+
+	start, limit, ok := s.paging(w, q, s.employeeParams("start", "limit")...)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	rows, total := window(s.employees, start, limit)
+	s.mu.Unlock()
+	writeDataMeta(w, project(rows, fields), start, total)
+}
+
+// employeeParams are the query parameters of the employees listing the
+// fake knows.
+func (s *Server) employeeParams(paging ...string) []string {
+	if s.HonorFields {
+		return append(paging, "fields")
+	}
+	return paging
+}
+
+// project keeps only fields in each row; no fields keeps them all.
+func project(rows []map[string]any, fields []string) []map[string]any {
+	if len(fields) == 0 {
+		return rows
+	}
+	out := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		out[i] = eightfold.ProjectFields(row, fields)
+	}
+	return out
+}
+
+func (s *Server) updateEmployee(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req eightfold.UpdateEmployeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpxtest.WriteError(w, http.StatusBadRequest, "invalid update")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.employeeIndex(id)
+	if i < 0 {
+		httpxtest.WriteError(w, http.StatusNotFound, "employee not found")
+		return
+	}
+	s.updates[id] = append(s.updates[id], req)
+	// Copy, so updates never change a record handed out earlier.
+	employee := maps.Clone(s.employees[i])
+	employee["candidateData"] = req.CandidateData
+	s.employees[i] = employee
+	httpxtest.WriteJSON(w, map[string]any{"data": employee})
+}
+
+func (s *Server) employeeIndex(id string) int {
+	return slices.IndexFunc(s.employees, func(e map[string]any) bool {
+		return fmt.Sprint(e["id"]) == id
+	})
+}
+
+// window returns rows[start:start+limit] (copied, never nil) and len(rows).
+func window(rows []map[string]any, start, limit int) ([]map[string]any, int) {
+	start = min(start, len(rows))
+	end := min(start+limit, len(rows))
+	return append([]map[string]any{}, rows[start:end]...), len(rows)
+}
+
+func writeDataMeta(w http.ResponseWriter, rows []map[string]any, start, total int) {
+	httpxtest.WriteJSON(w, map[string]any{
+		"data": rows,
+		"meta": eightfold.ListCoursesMeta{PageStartIndex: start, PageTotalCount: len(rows), TotalCount: total},
+	})
+}
@@ -0,0 +1,210 @@
+package eightfoldtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"course-sync/internal/providers/eightfold"
+)
+
+func employees(n int) []map[string]any {
+	out := make([]map[string]any, n)
+	for i := range out {
+		out[i] = map[string]any{"id": fmt.Sprintf("p%d", i), "email": fmt.Sprintf("e%d@example.com", i), "department": "Engineering"}
+	}
+	return out
+}
+
+func TestServerPasswordGrantAndPaging(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddEmployees(employees(250)...)
+	for i := range 150 {
+		srv.AddCourses(map[string]any{"lmsCourseId": fmt.Sprint(i), "provider": "Udemy"})
+	}
+
+	client := eightfold.New(srv.URL)
+	err := client.Authenticate(context.Background(), srv.BasicAuth, eightfold.AuthRequest{
+		GrantType: "password", Username: srv.Username, Password: srv.Password,
+	})
+	if err != nil {
+		t.Fatalf("Authenticate() error: %v", err)
+	}
+
+	all, err := client.ListAllEmployees(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListAllEmployees() error: %v", err)
+	}
+	if len(all) != 250 || all[249].ID != "p249" {
+		t.Errorf("Expected 250 employees, got %d", len(all))
+	}
+
+	n := 0
+	for _, err := range client.CoursesPaginator(40).Rows(context.Background()) {
+		if err != nil {
+			t.Fatalf("courses error: %v", err)
+		}
+		n++
+	}
+	if n != 150 {
+		t.Errorf("Expected 150 courses, got %d", n)
+	}
+	if reqs := srv.Requests(); !strings.Contains(strings.Join(reqs, "\n"), "start=120") {
+		t.Errorf("Expected start/limit paging, got %q", reqs)
+	}
+}
+
+func TestServerResultsNext(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.EmployeeShape = ResultsNext
+	srv.PageSize = 30
+	srv.AddEmployees(employees(70)...)
+
+	client := eightfold.New(srv.URL)
+	client.BearerToken = srv.Token
+	all, err := client.ListAllEmployees(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListAllEmployees() error: %v", err)
+	}
+	if len(all) != 70 || len(srv.Requests()) != 3 {
+		t.Errorf("Expected 70 employees in 3 pages, got %d in %q", len(all), srv.Requests())
+	}
+}
+
+func TestServerUpdateAndFaults(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddEmployees(employees(1)...)
+	srv.Inject(Fault{Method: http.MethodPatch, Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+	srv.Inject(Fault{Method: http.MethodPatch, Status: http.StatusBadGateway, Times: 1})
+
+	client := eightfold.New(srv.URL)
+	client.BearerToken = srv.Token
+	req := eightfold.UpdateEmployeeRequest{
+		Email:         "e0@example.com",
+		CandidateData: eightfold.CandidateData{CourseAttendance: []eightfold.CourseAttendance{{LmsCourseID: "c1"}}},
+	}
+	if err := client.UpdateEmployee(context.Background(), "p0", req); err != nil {
+		t.Fatalf("UpdateEmployee() error: %v", err)
+	}
+	if got := srv.Updates("p0"); len(got) != 1 || got[0].CandidateData.CourseAttendance[0].LmsCourseID != "c1" {
+		t.Errorf("Updates() = %+v", got)
+	}
+	if srv.Employee("p0")["candidateData"] == nil {
+		t.Error("Expected the update applied to the employee")
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("Expected 2 failed attempts and a success, got %d requests", n)
+	}
+
+	if err := client.UpdateEmployee(context.Background(), "nobody", req); err == nil {
+		t.Error("Expected a 404 for an unknown employee")
+	}
+}
+
+func TestServerSlowResponse(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.Inject(Fault{Path: "/api/v2/core/courses", Delay: time.Minute})
+
+	client := eightfold.New(srv.URL)
+	client.BearerToken = srv.Token
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListCourses(ctx, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to cut the slow response, got %v", err)
+	}
+}
+
+func TestServerExpiredToken(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddEmployees(employees(1)...)
+
+	client := eightfold.New(srv.URL)
+	if err := client.Authenticate(context.Background(), srv.BasicAuth, eightfold.AuthRequest{
+		GrantType: "password", Username: srv.Username, Password: srv.Password,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	if _, err := client.ListAllEmployees(context.Background(), 0); err != nil {
+		t.Errorf("Expected the client to re-authenticate after a 401, got %v", err)
+	}
+
+	if err := eightfold.New(srv.URL).Authenticate(context.Background(), srv.BasicAuth, eightfold.AuthRequest{
+		GrantType: "password", Username: srv.Username, Password: "wrong",
+	}); err == nil {
+		t.Error("Expected a wrong password to be rejected")
+	}
+}
+
+func TestServerRejectUnknownParams(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.RejectUnknownParams = true
+	srv.AddEmployees(employees(3)...)
+	srv.AddEmployees(map[string]any{"id": "s1", "email": "s1@example.com", "department": "Sales"})
+
+	client := eightfold.New(srv.URL)
+	client.BearerToken = srv.Token
+	n := 0
+	for e, err := range client.ListEmployees(context.Background(), eightfold.EmployeeQuery{Department: "sales"}) {
+		if err != nil {
+			t.Fatalf("ListEmployees() error: %v", err)
+		}
+		if e.ID != "s1" {
+			t.Errorf("Unexpected employee %s", e.ID)
+		}
+		n++
+	}
+	if reqs := srv.Requests(); n != 1 || len(reqs) != 2 {
+		t.Errorf("Expected a 400 then a plain listing filtered locally, got %d employees from %q", n, reqs)
+	}
+}
+
+// TestServerHonorFields lists employees from a tenant that applies the
+// fields projection but ignores the filters: the filtering is local, and
+// must still see the department, location, modification time and emails.
+func TestServerHonorFields(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.HonorFields = true
+	srv.AddEmployees(
+		map[string]any{"id": "p1", "email_list": []any{"p1@example.com"}, "departmentName": "Engineering", "location": "Madrid", "lastModifiedTs": 1767225600, "title": "x"},
+		map[string]any{"id": "p2", "emailList": []any{"p2@example.com"}, "department": "Engineering", "location": "Lisbon", "lastModifiedTs": 1767225600},
+		map[string]any{"id": "p3", "email": "p3@example.com", "department": "Engineering", "location_name": "Madrid", "last_modified": "2025-01-01T00:00:00Z"},
+		map[string]any{"id": "p4", "email": "p4@example.com", "department": "Engineering", "locationName": "madrid", "updated_at": "2026-02-01T00:00:00Z"},
+	)
+
+	client := eightfold.New(srv.URL)
+	client.BearerToken = srv.Token
+	q := eightfold.EmployeeQuery{
+		Fields:        []string{"id"},
+		Department:    "engineering",
+		Location:      "MADRID",
+		ModifiedSince: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	var got []string
+	for e, err := range client.ListEmployees(context.Background(), q) {
+		if err != nil {
+			t.Fatalf("ListEmployees() error: %v", err)
+		}
+		if len(e.Raw) != 1 || e.Email() == "" {
+			t.Errorf("Expected Raw projected to id with the email kept, got %+v", e)
+		}
+		got = append(got, e.ID+" "+e.Email())
+	}
+	if fmt.Sprint(got) != "[p1 p1@example.com p4 p4@example.com]" {
+		t.Errorf("Expected p1 and p4, got %v", got)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || !strings.Contains(reqs[0], "fields=id%2C") {
+		t.Errorf("Expected one projected listing, got %q", reqs)
+	}
+}
@@ -2,238 +2,189 @@ package eightfold
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
+	"iter"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"course-sync/internal/httpx"
 )
 
-// Response shape #1 (common in Eightfold): {"data": [...], "meta": {...}}
-type employeesResponseDataMeta struct {
-	Data []map[string]any `json:"data"`
-	Meta struct {
-		PageStartIndex int `json:"pageStartIndex"`
-		PageTotalCount int `json:"pageTotalCount"`
-		TotalCount     int `json:"totalCount"`
-	} `json:"meta"`
+// EmployeeQuery narrows an employee listing. The zero value lists every
+// employee with every field.
+type EmployeeQuery struct {
+	// PageSize is the page size hint (capped at MaxPageSize).
+	PageSize int
+	// Fields keeps only these raw fields in Employee.Raw. The typed fields
+	// are read before, and the projection asked of Eightfold always
+	// includes the keys they are read from, so they are set whatever
+	// Fields says.
+	Fields []string
+	// Department and Location keep the employees in that department or
+	// location (case-insensitive).
+	Department string
+	Location   string
+	// ModifiedSince keeps the employees modified at or after this time.
+	// Employees whose records carry no modification time are kept.
+	ModifiedSince time.Time
 }
 
-// Response shape #2: {"results": [...], "next": "..."}
-type employeesResponseResultsNext struct {
-	Results []map[string]any `json:"results"`
-	Next    string           `json:"next"`
-	Count   int              `json:"count"`
-}
-
-type employeesErrorResponse struct {
-	Message string `json:"message"`
-}
+// Query parameters of the employees endpoint for projection and filters.
+// Tenants that do not know them either ignore them or reject the request
+// with a 400; both are handled by filtering locally as well.
+const (
+	paramFields        = "fields"
+	paramDepartment    = "department"
+	paramLocation      = "location"
+	paramModifiedSince = "lastModifiedTs"
+)
 
-// ListAllEmployees fetches all employees from /api/v2/core/employees.
-//
-// Tenant behavior observed:
-// - NO acepta pageStartIndex / pageSize (400 validating query parameters)
-// - Primera página funciona SIN params y devuelve meta.totalCount/pageTotalCount
-//
-// Paginación implementada:
-// 1) Primera llamada SIN params.
-// 2) Si viene meta (totalCount/pageTotalCount), pagina con start+limit:
-//   - start = pageTotalCount (offset)
-//   - limit = pageTotalCount (cap a 100 por seguridad)
-//
-// También soporta results/next si el endpoint devuelve ese formato.
-func (c *Client) ListAllEmployees(ctx context.Context, pageSizeHint int) ([]map[string]any, error) {
-	if strings.TrimSpace(c.BearerToken) == "" {
-		return nil, errors.New("eightfold: missing bearer token (set EIGHTFOLD_BEARER_TOKEN or call Authenticate)")
+// params returns the server-side form of q.
+func (q EmployeeQuery) params() url.Values {
+	v := url.Values{}
+	if len(q.Fields) > 0 {
+		v.Set(paramFields, strings.Join(q.serverFields(), ","))
 	}
-
-	base, err := url.Parse(strings.TrimRight(c.BaseURL, "/") + "/api/v2/core/employees")
-	if err != nil {
-		return nil, fmt.Errorf("eightfold: invalid base url: %w", err)
+	if q.Department != "" {
+		v.Set(paramDepartment, q.Department)
 	}
-
-	// -------- First call: NO params --------
-	body0, status0, err := c.getRaw(ctx, base.String())
-	if err != nil {
-		return nil, err
+	if q.Location != "" {
+		v.Set(paramLocation, q.Location)
 	}
-	if status0 < 200 || status0 >= 300 {
-		return nil, fmt.Errorf("list employees failed: url=%s status=%d body=%s", base.String(), status0, string(body0))
+	if !q.ModifiedSince.IsZero() {
+		v.Set(paramModifiedSince, strconv.FormatInt(q.ModifiedSince.Unix(), 10))
 	}
+	return v
+}
 
-	// Try shape #1 (data/meta)
-	var dm0 employeesResponseDataMeta
-	if err := json.Unmarshal(body0, &dm0); err == nil && dm0.Data != nil {
-		all := make([]map[string]any, 0, max(dm0.Meta.TotalCount, len(dm0.Data)))
-		all = append(all, dm0.Data...)
-
-		// If meta doesn't give paging hints, return what we got.
-		if dm0.Meta.TotalCount <= 0 || dm0.Meta.PageTotalCount <= 0 {
-			return all, nil
-		}
-
-		total := dm0.Meta.TotalCount
-		limit := dm0.Meta.PageTotalCount
-
-		// Safety cap (Eightfold suele limitar a 100)
-		if limit <= 0 {
-			limit = 100
-		}
-		if limit > 100 {
-			limit = 100
-		}
-
-		// If pageSizeHint is provided, keep it but cap to 100.
-		if pageSizeHint > 0 {
-			limit = pageSizeHint
-			if limit > 100 {
-				limit = 100
-			}
-		}
-
-		// start is OFFSET, not page number
-		start := len(dm0.Data)
-
-		for start < total {
-			u := *base
-			q := u.Query()
-			q.Set("start", strconv.Itoa(start))
-			q.Set("limit", strconv.Itoa(limit))
-			u.RawQuery = q.Encode()
-
-			b, st, err := c.getRaw(ctx, u.String())
-			if err != nil {
-				return nil, err
-			}
-			if st < 200 || st >= 300 {
-				return nil, fmt.Errorf("list employees failed: url=%s status=%d body=%s", u.String(), st, string(b))
-			}
-
-			var dm employeesResponseDataMeta
-			if err := json.Unmarshal(b, &dm); err != nil {
-				return nil, fmt.Errorf("list employees: json parse error: %w body=%s", err, string(b))
-			}
-			if dm.Data == nil {
-				return nil, fmt.Errorf("list employees: unexpected response body=%s", string(b))
-			}
-
-			all = append(all, dm.Data...)
-
-			// advance by actual received count (más robusto)
-			got := len(dm.Data)
-			if got == 0 {
-				break
-			}
-			start += got
+// serverFields is the projection asked of Eightfold: Fields plus every key
+// the typed fields are read from. A tenant that honors the projection but
+// not the filters then still returns the department, location and
+// modification time Match needs, and all the email variants.
+func (q EmployeeQuery) serverFields() []string {
+	fields := slices.Clone(q.Fields)
+	for _, k := range employeeKeys() {
+		if !slices.Contains(fields, k) {
+			fields = append(fields, k)
 		}
-
-		return all, nil
 	}
+	return fields
+}
 
-	// Try shape #2 (results/next)
-	var rn0 employeesResponseResultsNext
-	if err := json.Unmarshal(body0, &rn0); err == nil && rn0.Results != nil {
-		all := make([]map[string]any, 0)
-		all = append(all, rn0.Results...)
-
-		next := strings.TrimSpace(rn0.Next)
-		for next != "" {
-			b, st, err := c.getRaw(ctx, next)
-			if err != nil {
-				return nil, err
-			}
-			if st < 200 || st >= 300 {
-				return nil, fmt.Errorf("list employees failed: url=%s status=%d body=%s", next, st, string(b))
-			}
+// Match reports whether e passes the filters of q.
+func (q EmployeeQuery) Match(e Employee) bool {
+	if q.Department != "" && !strings.EqualFold(e.Department, q.Department) {
+		return false
+	}
+	if q.Location != "" && !strings.EqualFold(e.Location, q.Location) {
+		return false
+	}
+	if !q.ModifiedSince.IsZero() && !e.Modified.IsZero() && e.Modified.Before(q.ModifiedSince) {
+		return false
+	}
+	return true
+}
 
-			var rn employeesResponseResultsNext
-			if err := json.Unmarshal(b, &rn); err != nil {
-				return nil, fmt.Errorf("list employees: json parse error: %w body=%s", err, string(b))
+// ListEmployees iterates over the employees from /api/v2/core/employees
+// that match q, one page in memory at a time. A failed page yields its
+// error and ends the iteration; stopping early fetches no further pages.
+//
+// The projection and filters are sent to Eightfold so that it returns
+// less; if it rejects them on the first page (400), the listing starts
+// over without them. Either way every employee is checked and projected
+// locally too, so the result does not depend on what the tenant supports.
+//
+// Tenant behavior observed:
+// - NO acepta pageStartIndex / pageSize (400 validating query parameters)
+// - Primera página funciona SIN params y devuelve meta.totalCount/pageTotalCount
+//
+// So the first page is asked for without paging parameters and the rest
+// with start+limit, limit being q.PageSize or else the size of the first
+// page (capped at MaxPageSize either way).
+func (c *Client) ListEmployees(ctx context.Context, q EmployeeQuery) iter.Seq2[Employee, error] {
+	paginator := func(params url.Values) *Paginator {
+		p := c.Paginate("/api/v2/core/employees", "eightfold.employees.page")
+		p.PageSize = q.PageSize
+		p.BareFirstPage = true
+		p.Query = params
+		return p
+	}
+	return func(yield func(Employee, error) bool) {
+		params := q.params()
+		for {
+			started, fallback := false, false
+			for row, err := range paginator(params).Rows(ctx) {
+				if err != nil {
+					var herr *httpx.HTTPError
+					if !started && len(params) > 0 && errors.As(err, &herr) && herr.StatusCode == http.StatusBadRequest {
+						params, fallback = nil, true
+						break
+					}
+					yield(Employee{}, err)
+					return
+				}
+				started = true
+				e := EmployeeFromMap(row)
+				if !q.Match(e) {
+					continue
+				}
+				if len(q.Fields) > 0 {
+					e.Raw = ProjectFields(e.Raw, q.Fields)
+				}
+				if !yield(e, nil) {
+					return
+				}
 			}
-			if rn.Results == nil {
-				return nil, fmt.Errorf("list employees: unexpected response body=%s", string(b))
+			if !fallback {
+				return
 			}
-
-			all = append(all, rn.Results...)
-			next = strings.TrimSpace(rn.Next)
 		}
-
-		return all, nil
 	}
-
-	return nil, fmt.Errorf("list employees: unsupported response body=%s", string(body0))
 }
 
-func (c *Client) getRaw(ctx context.Context, urlStr string) ([]byte, int, error) {
-	resp, body, err := httpx.DoWithRetry(
-		ctx,
-		c.HTTP,
-		func(ctx context.Context) (*http.Request, error) {
-			r, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
-			if err != nil {
-				return nil, err
-			}
-			r.Header.Set("Accept", "application/json")
-			r.Header.Set("Authorization", "Bearer "+c.BearerToken)
-			return r, nil
-		},
-		httpx.DefaultRetryConfig(),
-	)
-	if err != nil {
-		return nil, 0, fmt.Errorf("eightfold: request failed: %w", err)
-	}
-	if resp == nil {
-		return body, 0, nil
-	}
-	return body, resp.StatusCode, nil
+// Employees iterates over all employees; see ListEmployees.
+func (c *Client) Employees(ctx context.Context, pageSizeHint int) iter.Seq2[Employee, error] {
+	return c.ListEmployees(ctx, EmployeeQuery{PageSize: pageSizeHint})
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+// ListAllEmployees fetches all employees from /api/v2/core/employees into
+// memory. Prefer Employees for large workforces.
+func (c *Client) ListAllEmployees(ctx context.Context, pageSizeHint int) ([]Employee, error) {
+	var all []Employee
+	for e, err := range c.Employees(ctx, pageSizeHint) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, e)
 	}
-	return b
+	return all, nil
 }
 
-// ListEmployeesFields fetches all employees from /api/v2/core/employees and filters to only include specified fields.
-// This is an optimized version of ListAllEmployees that only returns the fields you need.
-func (c *Client) ListEmployeesFields(ctx context.Context, pageSizeHint int, fields []string) ([]map[string]any, error) {
-	// Get all employees using the standard method
-	allEmployees, err := c.ListAllEmployees(ctx, pageSizeHint)
-	if err != nil {
-		return nil, err
-	}
-
-	// If no fields specified, return all data
-	if len(fields) == 0 {
-		return allEmployees, nil
+// ListEmployeesFields fetches all employees from /api/v2/core/employees,
+// asking Eightfold for only the given fields (see ListEmployees) and
+// keeping only those in Raw.
+func (c *Client) ListEmployeesFields(ctx context.Context, pageSizeHint int, fields []string) ([]Employee, error) {
+	var all []Employee
+	for e, err := range c.ListEmployees(ctx, EmployeeQuery{PageSize: pageSizeHint, Fields: fields}) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, e)
 	}
+	return all, nil
+}
 
-	// Create a map for faster field lookup
-	fieldMap := make(map[string]bool, len(fields))
+// ProjectFields returns a copy of employee with only the given fields.
+func ProjectFields(employee map[string]any, fields []string) map[string]any {
+	filtered := make(map[string]any, len(fields))
 	for _, field := range fields {
-		fieldMap[field] = true
-	}
-
-	// Filter each employee to only include the specified fields
-	result := make([]map[string]any, len(allEmployees))
-	for i, employee := range allEmployees {
-		filtered := make(map[string]any)
-
-		// Only include fields that were requested
-		for _, field := range fields {
-			if value, exists := employee[field]; exists {
-				filtered[field] = value
-			}
+		if value, exists := employee[field]; exists {
+			filtered[field] = value
 		}
-
-		result[i] = filtered
 	}
-
-	return result, nil
+	return filtered
 }
@@ -0,0 +1,200 @@
+package eightfold
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// employeesServer serves total employees in the data/meta shape, pageSize at
+// a time (start/limit offsets after the first page), counting the requests.
+func employeesServer(t *testing.T, total, pageSize int, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit := pageSize
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil {
+			limit = l
+		}
+		end := min(start+limit, total)
+		rows := ""
+		for i := start; i < end; i++ {
+			if i > start {
+				rows += ","
+			}
+			rows += fmt.Sprintf(`{"id":"e%d","email":"e%d@example.com","title":"Engineer"}`, i, i)
+		}
+		fmt.Fprintf(w, `{"data":[%s],"meta":{"pageStartIndex":%d,"pageTotalCount":%d,"totalCount":%d}}`,
+			rows, start, end-start, total)
+	}))
+}
+
+func TestEmployeesDataMetaPages(t *testing.T) {
+	var requests atomic.Int32
+	server := employeesServer(t, 25, 10, &requests)
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+
+	var ids []string
+	for row, err := range client.Employees(context.Background(), 0) {
+		if err != nil {
+			t.Fatalf("Employees() error: %v", err)
+		}
+		ids = append(ids, row.ID)
+	}
+	if len(ids) != 25 || ids[0] != "e0" || ids[24] != "e24" {
+		t.Errorf("Expected e0..e24, got %v", ids)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 page requests, got %d", n)
+	}
+}
+
+func TestEmployeesStopsEarly(t *testing.T) {
+	var requests atomic.Int32
+	server := employeesServer(t, 25, 10, &requests)
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+
+	n := 0
+	for _, err := range client.Employees(context.Background(), 0) {
+		if err != nil {
+			t.Fatalf("Employees() error: %v", err)
+		}
+		if n++; n == 12 {
+			break
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected stopping in the second page to fetch 2 pages, got %d", got)
+	}
+}
+
+func TestEmployeesResultsNext(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"results":[{"id":"e2"}],"next":""}`))
+			return
+		}
+		fmt.Fprintf(w, `{"results":[{"id":"e0"},{"id":"e1"}],"next":"%s/api/v2/core/employees?page=2"}`, server.URL)
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+
+	all, err := client.ListAllEmployees(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListAllEmployees() error: %v", err)
+	}
+	if len(all) != 3 || all[2].ID != "e2" {
+		t.Errorf("Expected e0..e2, got %v", all)
+	}
+}
+
+func TestEmployeesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"unexpected":true}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+
+	var errs int
+	for row, err := range client.Employees(context.Background(), 0) {
+		if err == nil || row.Raw != nil {
+			t.Fatalf("Expected only an error, got %v, %v", row, err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("Expected one error, got %d", errs)
+	}
+
+	if _, err := New(server.URL).ListAllEmployees(context.Background(), 0); err == nil {
+		t.Error("Expected an error without a bearer token")
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	got := ProjectFields(map[string]any{"id": "e1", "email": "a@b.c", "title": "x"}, []string{"id", "email", "missing"})
+	if len(got) != 2 || got["id"] != "e1" || got["email"] != "a@b.c" {
+		t.Errorf("ProjectFields() = %v", got)
+	}
+}
+
+// filterServer serves four employees. With strict it rejects the filter
+// and projection parameters with a 400; otherwise it ignores them, so
+// that only local filtering narrows the listing.
+func filterServer(t *testing.T, strict bool, queries *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.RawQuery)
+		if strict && r.URL.Query().Get("department") != "" {
+			http.Error(w, `{"message":"error validating query parameters"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data":[
+			{"id":"e1","email":"a@example.com","department":"Engineering","location":"Madrid","lastModifiedTs":1767225600,"title":"x"},
+			{"id":"e2","email":"b@example.com","department":"engineering","location":"Lisbon","lastModifiedTs":1767225600},
+			{"id":"e3","email":"c@example.com","department":"Sales","location":"Madrid"},
+			{"id":"e4","email":"d@example.com","department":"Engineering","location":"Madrid","last_modified":"2025-01-01T00:00:00Z"}
+		]}`))
+	}))
+}
+
+func TestListEmployeesFilters(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			var queries []string
+			server := filterServer(t, strict, &queries)
+			defer server.Close()
+
+			client := New(server.URL)
+			client.BearerToken = "test-token"
+
+			q := EmployeeQuery{
+				Fields:        []string{"id", "email"},
+				Department:    "ENGINEERING",
+				Location:      "madrid",
+				ModifiedSince: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			}
+			var ids []string
+			for e, err := range client.ListEmployees(context.Background(), q) {
+				if err != nil {
+					t.Fatalf("ListEmployees() error: %v", err)
+				}
+				if len(e.Raw) != 2 || e.Department == "" {
+					t.Errorf("Expected Raw projected to id and email with typed fields kept, got %+v", e)
+				}
+				ids = append(ids, e.ID)
+			}
+			if fmt.Sprint(ids) != "[e1]" {
+				t.Errorf("Expected only e1, got %v", ids)
+			}
+
+			if !strings.Contains(queries[0], "department=ENGINEERING") || !strings.Contains(queries[0], "fields=id%2Cemail") {
+				t.Errorf("Expected the filters sent to Eightfold, got %q", queries[0])
+			}
+			if strict && (len(queries) != 2 || queries[1] != "") {
+				t.Errorf("Expected a retry without parameters after the 400, got %q", queries)
+			}
+			if !strict && len(queries) != 1 {
+				t.Errorf("Expected a single request, got %q", queries)
+			}
+		})
+	}
+}
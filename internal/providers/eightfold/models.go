@@ -0,0 +1,305 @@
+package eightfold
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Employee is an Eightfold employee record. Tenants differ in field names
+// (snake or camel case) and in how emails come back (a string, a list, or
+// a list of objects); EmployeeFromMap and UnmarshalJSON accept them all.
+// Raw is the record as returned, for fields without a typed counterpart;
+// an Employee marshals back to Raw.
+type Employee struct {
+	// ID is the profile id, used to address the employee in the API
+	// (falling back to the employee id when a tenant has no id).
+	ID         string
+	EmployeeID string
+	UserID     string
+	Username   string
+	Level      string
+	Department string
+	Location   string
+	// Emails are the employee's addresses, trimmed and de-duplicated.
+	Emails []string
+	// Modified is when the record last changed, zero when the tenant does
+	// not say.
+	Modified time.Time
+
+	Raw map[string]any
+}
+
+// Raw keys each typed Employee field is read from, in order of preference.
+var (
+	employeeIDKeys         = []string{"employee_id", "employeeId", "employeeID"}
+	employeeUserIDKeys     = []string{"user_id", "userId", "userID", "id"}
+	employeeUsernameKeys   = []string{"username", "userName", "user_name"}
+	employeeLevelKeys      = []string{"level", "job_level", "jobLevel", "career_level", "careerLevel"}
+	employeeDepartmentKeys = []string{"department", "department_name", "departmentName"}
+	employeeLocationKeys   = []string{"location", "location_name", "locationName"}
+	employeeEmailKeys      = []string{"email", "emails", "email_list", "emailList"}
+	employeeModifiedKeys   = []string{"lastModifiedTs", "last_modified_ts", "lastModified", "last_modified", "updatedAt", "updated_at"}
+)
+
+// employeeKeys lists every raw key EmployeeFromMap reads, so that a
+// projection asked of Eightfold never leaves a typed field empty.
+func employeeKeys() []string {
+	keys := []string{"id"}
+	for _, ks := range [][]string{
+		employeeIDKeys, employeeUserIDKeys, employeeUsernameKeys, employeeLevelKeys,
+		employeeDepartmentKeys, employeeLocationKeys, employeeEmailKeys, employeeModifiedKeys,
+	} {
+		for _, k := range ks {
+			if !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// EmployeeFromMap reads the typed fields of a decoded employee record.
+func EmployeeFromMap(m map[string]any) Employee {
+	e := Employee{
+		ID:         pickString(m, "id"),
+		EmployeeID: pickString(m, employeeIDKeys...),
+		UserID:     pickString(m, employeeUserIDKeys...),
+		Username:   pickString(m, employeeUsernameKeys...),
+		Level:      pickString(m, employeeLevelKeys...),
+		Department: pickString(m, employeeDepartmentKeys...),
+		Location:   pickString(m, employeeLocationKeys...),
+		Emails:     pickEmails(m),
+		Modified:   pickTime(m, employeeModifiedKeys...),
+		Raw:        m,
+	}
+	if e.ID == "" {
+		e.ID = e.EmployeeID
+	}
+	return e
+}
+
+// Email is the first email of the employee, or else the username (which
+// is the email on most tenants).
+func (e Employee) Email() string {
+	if len(e.Emails) > 0 {
+		return e.Emails[0]
+	}
+	return e.Username
+}
+
+func (e *Employee) UnmarshalJSON(b []byte) error {
+	var m map[string]any
+	if err := decodeJSON(b, &m); err != nil {
+		return err
+	}
+	*e = EmployeeFromMap(m)
+	return nil
+}
+
+func (e Employee) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Raw)
+}
+
+// Course is an Eightfold course record; see Employee for how field-name
+// variants and Raw are handled.
+type Course struct {
+	SystemID      string
+	LMSCourseID   string
+	Provider      string
+	Title         string
+	Description   string
+	CourseURL     string
+	Language      string
+	Category      string
+	Difficulty    string
+	DurationHours float64
+	Status        string
+	PublishedDate string
+	ImageURL      string
+
+	Raw map[string]any
+}
+
+// CourseFromMap reads the typed fields of a decoded course record.
+func CourseFromMap(m map[string]any) Course {
+	c := Course{
+		SystemID:      pickString(m, "systemId", "system_id"),
+		LMSCourseID:   pickString(m, "lmsCourseId", "lms_course_id"),
+		Provider:      pickString(m, "provider"),
+		Title:         pickString(m, "title"),
+		Description:   pickString(m, "description"),
+		CourseURL:     pickString(m, "courseUrl", "course_url"),
+		Language:      pickString(m, "language"),
+		Category:      pickString(m, "category"),
+		Difficulty:    pickString(m, "difficulty"),
+		Status:        pickString(m, "status"),
+		PublishedDate: pickString(m, "publishedDate", "published_date", "published_ts"),
+		ImageURL:      pickString(m, "imageUrl", "image_url"),
+		Raw:           m,
+	}
+	c.DurationHours, _ = pickFloat(m, "durationHours", "duration_hours")
+	return c
+}
+
+func (c *Course) UnmarshalJSON(b []byte) error {
+	var m map[string]any
+	if err := decodeJSON(b, &m); err != nil {
+		return err
+	}
+	*c = CourseFromMap(m)
+	return nil
+}
+
+func (c Course) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Raw)
+}
+
+// decodeJSON decodes b into v keeping numbers as json.Number, so that
+// numeric ids are not turned into floats.
+func decodeJSON(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// pickString returns the first non-empty value among keys, trimmed.
+func pickString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		v, ok := m[k]
+		if !ok || v == nil {
+			continue
+		}
+		s := anyToString(v)
+		if strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// pickFloat returns the first numeric value among keys.
+func pickFloat(m map[string]any, keys ...string) (float64, bool) {
+	for _, k := range keys {
+		switch t := m[k].(type) {
+		case float64:
+			return t, true
+		case int:
+			return float64(t), true
+		case json.Number:
+			if f, err := t.Float64(); err == nil {
+				return f, true
+			}
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(t), 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// pickTime returns the first timestamp among keys, given as Unix seconds
+// (or milliseconds) or as an RFC 3339 string.
+func pickTime(m map[string]any, keys ...string) time.Time {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok {
+			if t, err := time.Parse(time.RFC3339, strings.TrimSpace(s)); err == nil {
+				return t
+			}
+		}
+		if f, ok := pickFloat(m, k); ok && f > 0 {
+			if f > 1e11 {
+				return time.UnixMilli(int64(f))
+			}
+			return time.Unix(int64(f), 0)
+		}
+	}
+	return time.Time{}
+}
+
+func pickEmails(m map[string]any) []string {
+	for _, k := range employeeEmailKeys {
+		if v, ok := m[k]; ok && v != nil {
+			out := anyToStringSlice(v)
+			if len(out) > 0 {
+				return out
+			}
+		}
+	}
+	return nil
+}
+
+func anyToString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func anyToStringSlice(v any) []string {
+	out := []string{}
+	switch t := v.(type) {
+	case string:
+		if strings.TrimSpace(t) != "" {
+			out = append(out, strings.TrimSpace(t))
+		}
+	case []any:
+		for _, item := range t {
+			if item == nil {
+				continue
+			}
+			// string
+			if s, ok := item.(string); ok {
+				s = strings.TrimSpace(s)
+				if s != "" {
+					out = append(out, s)
+				}
+				continue
+			}
+			// map with "email"
+			if mm, ok := item.(map[string]any); ok {
+				if e, ok := mm["email"]; ok {
+					es := strings.TrimSpace(anyToString(e))
+					if es != "" {
+						out = append(out, es)
+					}
+				}
+			}
+		}
+	case map[string]any:
+		// Sometimes comes as {"email": "a@b"} or {"data": [...]}.
+		if e, ok := t["email"]; ok {
+			es := strings.TrimSpace(anyToString(e))
+			if es != "" {
+				out = append(out, es)
+			}
+		}
+		if list, ok := t["data"]; ok {
+			out = append(out, anyToStringSlice(list)...)
+		}
+	}
+
+	// de-dupe
+	seen := map[string]bool{}
+	uniq := []string{}
+	for _, s := range out {
+		if s == "" {
+			continue
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		uniq = append(uniq, s)
+	}
+	return uniq
+}
@@ -1,6 +1,8 @@
-package main
+package eightfold
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -251,3 +253,71 @@ func TestAnyToStringSlice(t *testing.T) {
 		})
 	}
 }
+
+func TestEmployeeUnmarshalJSON(t *testing.T) {
+	var e Employee
+	err := json.Unmarshal([]byte(`{
+		"employee_id": 1234567890123,
+		"userId": "u1",
+		"user_name": "jane.doe",
+		"job_level": "L5",
+		"email_list": [{"email": " jane@example.com "}, {"email": "jane@example.com"}, {"email": "j@example.com"}],
+		"department": "Engineering"
+	}`), &e)
+	if err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	want := Employee{
+		ID:         "1234567890123",
+		EmployeeID: "1234567890123",
+		UserID:     "u1",
+		Username:   "jane.doe",
+		Level:      "L5",
+		Department: "Engineering",
+		Emails:     []string{"jane@example.com", "j@example.com"},
+	}
+	e.Raw, want.Raw = nil, nil
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("Employee = %+v, want %+v", e, want)
+	}
+	if e.Email() != "jane@example.com" {
+		t.Errorf("Email() = %q", e.Email())
+	}
+	if (Employee{Username: "jd@example.com"}).Email() != "jd@example.com" {
+		t.Error("Expected Email() to fall back to the username")
+	}
+}
+
+func TestEmployeeKeepsRaw(t *testing.T) {
+	var e Employee
+	if err := json.Unmarshal([]byte(`{"id":"p1","email":"a@b.c","department":"Sales","score":12345678901}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Raw["department"] != "Sales" {
+		t.Errorf("Expected unknown fields in Raw, got %v", e.Raw)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"department":"Sales","email":"a@b.c","id":"p1","score":12345678901}` {
+		t.Errorf("Expected an Employee to marshal back to its record, got %s", b)
+	}
+}
+
+func TestCourseUnmarshalJSON(t *testing.T) {
+	var courses []Course
+	err := json.Unmarshal([]byte(`[
+		{"systemId": "UDM+1", "lmsCourseId": "1", "provider": "Udemy", "durationHours": 2.5, "courseUrl": "https://u/1"},
+		{"system_id": "PLS+2", "lms_course_id": "2", "duration_hours": "1.5", "course_url": "https://p/2", "image_url": "https://i/2"}
+	]`), &courses)
+	if err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if c := courses[0]; c.SystemID != "UDM+1" || c.LMSCourseID != "1" || c.Provider != "Udemy" || c.DurationHours != 2.5 || c.CourseURL != "https://u/1" {
+		t.Errorf("camelCase course = %+v", c)
+	}
+	if c := courses[1]; c.SystemID != "PLS+2" || c.LMSCourseID != "2" || c.DurationHours != 1.5 || c.CourseURL != "https://p/2" || c.ImageURL != "https://i/2" {
+		t.Errorf("snake_case course = %+v", c)
+	}
+}
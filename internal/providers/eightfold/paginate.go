@@ -0,0 +1,216 @@
+package eightfold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"course-sync/internal/tracing"
+)
+
+// DefaultMaxPageSize caps the page size asked of Eightfold when
+// Client.MaxPageSize is not set; tenants commonly reject larger limits.
+const DefaultMaxPageSize = 100
+
+// Page is one page of an Eightfold listing.
+type Page struct {
+	// Rows are the records of the page, as returned.
+	Rows []map[string]any
+	// Start is the offset of the first row in the listing.
+	Start int
+	// Total is the size of the listing when Eightfold reports it, else 0.
+	Total int
+}
+
+// Paginator pages through an Eightfold list endpoint. Two response shapes
+// are understood, whichever the tenant returns:
+//
+//   - {"data": [...], "meta": {"pageStartIndex", "pageTotalCount", "totalCount"}}:
+//     the next page is asked for with start (an offset, not a page number)
+//     and limit, until totalCount rows were read or a page comes back empty.
+//     Without meta the first page is the whole listing.
+//   - {"results": [...], "next": "<url>"}: next is followed until it is empty.
+//
+// A Paginator is cheap; make one per listing with Client.Paginate.
+type Paginator struct {
+	c    *Client
+	path string
+	span string
+
+	// PageSize is the limit asked for on each page. 0 uses the size of the
+	// first page. Either way it is capped at the client's MaxPageSize.
+	PageSize int
+	// MaxPages stops after this many pages; 0 reads them all.
+	MaxPages int
+	// BareFirstPage sends the first request without start or limit. Some
+	// tenants reject paging parameters on the employees endpoint until
+	// they have returned a first page with its meta.
+	BareFirstPage bool
+	// Query holds extra parameters sent with every request but those that
+	// follow a results/next link.
+	Query url.Values
+}
+
+// Paginate returns a Paginator over the listing at path (e.g.
+// "/api/v2/core/courses"). span names the trace span of each page.
+func (c *Client) Paginate(path, span string) *Paginator {
+	return &Paginator{c: c, path: path, span: span}
+}
+
+// pageCap is the largest page size to ask for.
+func (c *Client) pageCap() int {
+	if c.MaxPageSize > 0 {
+		return c.MaxPageSize
+	}
+	return DefaultMaxPageSize
+}
+
+// Pages iterates over the pages of the listing, fetching each one only when
+// the previous one has been consumed. A failed page yields its error and
+// ends the iteration.
+func (p *Paginator) Pages(ctx context.Context) iter.Seq2[Page, error] {
+	return func(yield func(Page, error) bool) {
+		if !p.c.hasAuth() {
+			yield(Page{}, errors.New("eightfold: missing bearer token (set EIGHTFOLD_BEARER_TOKEN or call Authenticate)"))
+			return
+		}
+		base, err := url.Parse(strings.TrimRight(p.c.BaseURL, "/") + p.path)
+		if err != nil {
+			yield(Page{}, fmt.Errorf("eightfold: invalid base url: %w", err))
+			return
+		}
+		offsetURL := func(start, limit int) string {
+			u := *base
+			q := url.Values{}
+			for k, v := range p.Query {
+				q[k] = v
+			}
+			if limit > 0 {
+				q.Set("limit", strconv.Itoa(limit))
+			}
+			if start > 0 {
+				q.Set("start", strconv.Itoa(start))
+			}
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+
+		limit := min(p.PageSize, p.c.pageCap())
+		next := offsetURL(0, limit)
+		if p.BareFirstPage {
+			next = offsetURL(0, 0)
+		}
+		start := 0
+		for page := 1; next != ""; page++ {
+			if p.MaxPages > 0 && page > p.MaxPages {
+				return
+			}
+			resp, err := p.fetch(ctx, next)
+			if err != nil {
+				yield(Page{}, err)
+				return
+			}
+			if !yield(Page{Rows: resp.rows, Start: start, Total: resp.total}, nil) {
+				return
+			}
+			start += len(resp.rows)
+
+			switch {
+			case resp.next != "":
+				next = resp.next
+			case !resp.paged || len(resp.rows) == 0 || (resp.total > 0 && start >= resp.total):
+				next = ""
+			default:
+				if limit <= 0 {
+					limit = min(resp.pageCount, p.c.pageCap())
+				}
+				next = offsetURL(start, limit)
+			}
+		}
+	}
+}
+
+// Rows iterates over the rows of every page; see Pages.
+func (p *Paginator) Rows(ctx context.Context) iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		for page, err := range p.Pages(ctx) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, row := range page.Rows {
+				if !yield(row, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// pageResponse is a page decoded from either response shape.
+type pageResponse struct {
+	rows []map[string]any
+	// paged is set when a data/meta page carried paging hints.
+	paged     bool
+	pageCount int
+	total     int
+	// next is the URL of the next page of a results/next listing.
+	next string
+}
+
+// Response shape #1 (common in Eightfold): {"data": [...], "meta": {...}}
+type dataMetaResponse struct {
+	Data []map[string]any `json:"data"`
+	Meta ListCoursesMeta  `json:"meta"`
+}
+
+// Response shape #2: {"results": [...], "next": "..."}
+type resultsNextResponse struct {
+	Results []map[string]any `json:"results"`
+	Next    string           `json:"next"`
+	Count   int              `json:"count"`
+}
+
+// fetch gets and decodes one page.
+func (p *Paginator) fetch(ctx context.Context, urlStr string) (_ pageResponse, err error) {
+	ctx, span := tracer.Start(ctx, p.span)
+	defer tracing.End(span, &err)
+	if u, perr := url.Parse(urlStr); perr == nil {
+		span.SetAttributes(
+			attribute.String("start", u.Query().Get("start")),
+			attribute.String("limit", u.Query().Get("limit")),
+		)
+	}
+
+	resp, body, err := p.c.do(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return pageResponse{}, fmt.Errorf("eightfold: list %s failed: %w", p.path, err)
+	}
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+
+	var dm dataMetaResponse
+	if err := decodeJSON(body, &dm); err == nil && dm.Data != nil {
+		span.SetAttributes(attribute.Int("results", len(dm.Data)))
+		return pageResponse{
+			rows:      dm.Data,
+			paged:     dm.Meta.PageTotalCount > 0,
+			pageCount: dm.Meta.PageTotalCount,
+			total:     dm.Meta.TotalCount,
+		}, nil
+	}
+	var rn resultsNextResponse
+	if err := decodeJSON(body, &rn); err == nil && rn.Results != nil {
+		span.SetAttributes(attribute.Int("results", len(rn.Results)))
+		return pageResponse{rows: rn.Results, total: rn.Count, next: strings.TrimSpace(rn.Next)}, nil
+	}
+	return pageResponse{}, fmt.Errorf("eightfold: list %s: unsupported response body=%s", p.path, string(body))
+}
@@ -0,0 +1,109 @@
+package eightfold
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPaginatorCourses(t *testing.T) {
+	var limits []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/core/courses" {
+			t.Errorf("Expected request to '/api/v2/core/courses', got '%s'", r.URL.Path)
+		}
+		if r.URL.Query().Get("provider") != "udemy" {
+			t.Errorf("Expected the extra query on every page, got %q", r.URL.RawQuery)
+		}
+		limits = append(limits, r.URL.Query().Get("limit"))
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		end := min(start+2, 5)
+		rows := ""
+		for i := start; i < end; i++ {
+			if i > start {
+				rows += ","
+			}
+			rows += fmt.Sprintf(`{"lmsCourseId":"c%d"}`, i)
+		}
+		fmt.Fprintf(w, `{"data":[%s],"meta":{"pageStartIndex":%d,"pageTotalCount":%d,"totalCount":5}}`, rows, start, end-start)
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+	client.MaxPageSize = 2
+
+	p := client.CoursesPaginator(500)
+	p.Query = map[string][]string{"provider": {"udemy"}}
+	var starts []int
+	n := 0
+	for page, err := range p.Pages(context.Background()) {
+		if err != nil {
+			t.Fatalf("Pages() error: %v", err)
+		}
+		if page.Total != 5 {
+			t.Errorf("Expected total 5, got %d", page.Total)
+		}
+		starts = append(starts, page.Start)
+		n += len(page.Rows)
+	}
+	if n != 5 || fmt.Sprint(starts) != "[0 2 4]" {
+		t.Errorf("Expected 5 rows from offsets [0 2 4], got %d from %v", n, starts)
+	}
+	for _, l := range limits {
+		if l != "2" {
+			t.Errorf("Expected the page size capped at 2, got limits %v", limits)
+			break
+		}
+	}
+}
+
+func TestPaginatorMaxPages(t *testing.T) {
+	var requests atomic.Int32
+	server := employeesServer(t, 25, 10, &requests)
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+
+	p := client.Paginate("/api/v2/core/employees", "test.page")
+	p.PageSize = 10
+	p.MaxPages = 2
+	n := 0
+	for _, err := range p.Rows(context.Background()) {
+		if err != nil {
+			t.Fatalf("Rows() error: %v", err)
+		}
+		n++
+	}
+	if n != 20 || requests.Load() != 2 {
+		t.Errorf("Expected 20 rows in 2 requests, got %d in %d", n, requests.Load())
+	}
+}
+
+func TestPaginatorWithoutMeta(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"data":[{"id":"c1"},{"id":"c2"}]}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "test-token"
+
+	n := 0
+	for _, err := range client.CoursesPaginator(10).Rows(context.Background()) {
+		if err != nil {
+			t.Fatalf("Rows() error: %v", err)
+		}
+		n++
+	}
+	if n != 2 || requests.Load() != 1 {
+		t.Errorf("Expected a single page of 2 rows, got %d rows in %d requests", n, requests.Load())
+	}
+}
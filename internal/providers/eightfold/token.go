@@ -0,0 +1,130 @@
+package eightfold
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshBefore is how long before expiry a token is proactively refreshed.
+const DefaultRefreshBefore = 5 * time.Minute
+
+// TokenFetcher obtains a fresh access token and its lifetime.
+// A lifetime <= 0 means the server didn't say; the token is then kept until
+// it is invalidated (e.g. by a 401).
+type TokenFetcher func(ctx context.Context) (token string, lifetime time.Duration, err error)
+
+// TokenSource hands out Eightfold bearer tokens and refreshes them before they
+// expire. It is safe for concurrent use: when the token needs refreshing, one
+// caller fetches a new one while the others wait for it, each until its own
+// context is done.
+type TokenSource struct {
+	// RefreshBefore is the refresh margin before expiry (default DefaultRefreshBefore).
+	// It is capped to half the token lifetime so short-lived tokens are not
+	// refreshed on every call.
+	RefreshBefore time.Duration
+
+	fetch TokenFetcher
+	now   func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time   // zero when the token never expires
+	inflight  *tokenFetch // the fetch in progress, if any
+}
+
+// tokenFetch is a fetch shared by the callers that need a new token; done is
+// closed once token and err are set.
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func NewTokenSource(fetch TokenFetcher) *TokenSource {
+	return &TokenSource{
+		RefreshBefore: DefaultRefreshBefore,
+		fetch:         fetch,
+		now:           time.Now,
+	}
+}
+
+// Token returns a valid token, fetching a new one if there is none yet or the
+// current one is within RefreshBefore of its expiry. The fetch runs without
+// the lock held, so callers waiting for it can give up when their context
+// is done.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	for {
+		ts.mu.Lock()
+		if ts.token != "" && (ts.refreshAt.IsZero() || ts.now().Before(ts.refreshAt)) {
+			token := ts.token
+			ts.mu.Unlock()
+			return token, nil
+		}
+		if ts.fetch == nil {
+			ts.mu.Unlock()
+			return "", errors.New("eightfold: token source has no fetcher")
+		}
+		f := ts.inflight
+		if f == nil {
+			f = &tokenFetch{done: make(chan struct{})}
+			ts.inflight = f
+			ts.mu.Unlock()
+			ts.refresh(ctx, f)
+			return f.token, f.err
+		}
+		ts.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		// A fetch that failed because its caller gave up says nothing
+		// about ours: try again.
+		if f.err != nil && ctx.Err() == nil &&
+			(errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded)) {
+			continue
+		}
+		return f.token, f.err
+	}
+}
+
+// refresh runs the fetch f and publishes its result to the waiting callers.
+func (ts *TokenSource) refresh(ctx context.Context, f *tokenFetch) {
+	token, lifetime, err := ts.fetch(ctx)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	defer close(f.done)
+	ts.inflight = nil
+	if err != nil {
+		f.err = err
+		return
+	}
+	ts.token = token
+	ts.refreshAt = time.Time{}
+	if lifetime > 0 {
+		margin := ts.RefreshBefore
+		if margin <= 0 {
+			margin = DefaultRefreshBefore
+		}
+		if margin > lifetime/2 {
+			margin = lifetime / 2
+		}
+		ts.refreshAt = ts.now().Add(lifetime - margin)
+	}
+	f.token = token
+}
+
+// Invalidate drops token so that the next Token call fetches a new one.
+// It is a no-op if token is no longer current, so a burst of 401s from
+// concurrent workers results in a single refresh.
+func (ts *TokenSource) Invalidate(token string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token == token {
+		ts.token = ""
+	}
+}
@@ -0,0 +1,238 @@
+package eightfold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenSourceRefreshesBeforeExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fetches := 0
+
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		fetches++
+		return fmt.Sprintf("token-%d", fetches), time.Hour, nil
+	})
+	ts.now = func() time.Time { return now }
+
+	tok, err := ts.Token(context.Background())
+	if err != nil || tok != "token-1" {
+		t.Fatalf("Token() = %q, %v; want token-1", tok, err)
+	}
+
+	// Still well inside the lifetime: cached.
+	now = now.Add(50 * time.Minute)
+	if tok, _ = ts.Token(context.Background()); tok != "token-1" {
+		t.Errorf("Expected cached token-1, got %q", tok)
+	}
+
+	// Within RefreshBefore (5m) of expiry: refreshed proactively.
+	now = now.Add(6 * time.Minute)
+	if tok, _ = ts.Token(context.Background()); tok != "token-2" {
+		t.Errorf("Expected refreshed token-2, got %q", tok)
+	}
+	if fetches != 2 {
+		t.Errorf("Expected 2 fetches, got %d", fetches)
+	}
+}
+
+func TestTokenSourceShortLifetime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fetches := 0
+
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		fetches++
+		return "tok", 2 * time.Minute, nil
+	})
+	ts.now = func() time.Time { return now }
+
+	ts.Token(context.Background())
+	now = now.Add(30 * time.Second)
+	ts.Token(context.Background())
+	if fetches != 1 {
+		t.Errorf("Expected the margin to be capped to half the lifetime (1 fetch), got %d fetches", fetches)
+	}
+
+	now = now.Add(61 * time.Second)
+	ts.Token(context.Background())
+	if fetches != 2 {
+		t.Errorf("Expected a refresh past half the lifetime, got %d fetches", fetches)
+	}
+}
+
+func TestTokenSourceInvalidate(t *testing.T) {
+	fetches := 0
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		fetches++
+		return fmt.Sprintf("token-%d", fetches), 0, nil
+	})
+
+	first, _ := ts.Token(context.Background())
+
+	// Invalidating a stale token must not drop the current one.
+	ts.Invalidate("something-else")
+	if tok, _ := ts.Token(context.Background()); tok != first {
+		t.Errorf("Expected %q to survive a stale Invalidate, got %q", first, tok)
+	}
+
+	ts.Invalidate(first)
+	if tok, _ := ts.Token(context.Background()); tok != "token-2" {
+		t.Errorf("Expected token-2 after Invalidate, got %q", tok)
+	}
+}
+
+func TestTokenSourceFetchError(t *testing.T) {
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		return "", 0, errors.New("boom")
+	})
+	if _, err := ts.Token(context.Background()); err == nil {
+		t.Error("Expected fetch error to be returned")
+	}
+}
+
+func TestTokenSourceConcurrentSingleFetch(t *testing.T) {
+	var fetches int32
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "tok", time.Hour, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tok, err := ts.Token(context.Background()); err != nil || tok != "tok" {
+				t.Errorf("Token() = %q, %v", tok, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("Expected a single fetch, got %d", n)
+	}
+}
+
+func TestTokenSourceWaiterHonoursContext(t *testing.T) {
+	started, unblock := make(chan struct{}), make(chan struct{})
+	var fetches int32
+	ts := NewTokenSource(func(ctx context.Context) (string, time.Duration, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+			<-unblock
+			return "", 0, ctx.Err()
+		}
+		return "tok", time.Hour, nil
+	})
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := ts.Token(leaderCtx)
+		leader <- err
+	}()
+	<-started
+
+	// A waiter whose context ends gives up without waiting for the fetch.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := ts.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the waiter's deadline, got %v", err)
+	}
+
+	// A waiter whose context is fine fetches again when the leader gives up.
+	waiter := make(chan string, 1)
+	go func() {
+		tok, err := ts.Token(context.Background())
+		if err != nil {
+			t.Errorf("Token() error: %v", err)
+		}
+		waiter <- tok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelLeader()
+	close(unblock)
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the leader's cancellation, got %v", err)
+	}
+	if tok := <-waiter; tok != "tok" {
+		t.Errorf("Expected the waiter to get a new token, got %q", tok)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("Expected 2 fetches, got %d", n)
+	}
+}
+
+func TestReauthenticateOn401(t *testing.T) {
+	var auths, patches int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/v1/authenticate":
+			n := atomic.AddInt32(&auths, 1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":{"access_token":"token-%d","expires_in":3600}}`, n)
+		case "/api/v2/core/employees/p1":
+			atomic.AddInt32(&patches, 1)
+			// The first token was revoked server-side.
+			if r.Header.Get("Authorization") != "Bearer token-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"message":"token expired"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	if err := client.Authenticate(context.Background(), "basic", AuthRequest{GrantType: "password"}); err != nil {
+		t.Fatalf("Authenticate() error: %v", err)
+	}
+	if client.BearerToken != "token-1" {
+		t.Errorf("Expected BearerToken token-1, got %q", client.BearerToken)
+	}
+
+	if err := client.UpdateEmployee(context.Background(), "p1", UpdateEmployeeRequest{Email: "a@b.c"}); err != nil {
+		t.Fatalf("UpdateEmployee() error: %v", err)
+	}
+
+	if auths != 2 {
+		t.Errorf("Expected 2 authentications, got %d", auths)
+	}
+	if patches != 2 {
+		t.Errorf("Expected the request to be retried once, got %d attempts", patches)
+	}
+}
+
+func TestStaticBearerTokenIsNotRefreshedOn401(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	client.BearerToken = "static"
+
+	err := client.UpdateEmployee(context.Background(), "p1", UpdateEmployeeRequest{})
+	if err == nil {
+		t.Fatal("Expected 401 error")
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt with a static token, got %d", calls)
+	}
+}
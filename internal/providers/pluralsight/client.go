@@ -10,9 +10,16 @@ import (
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"course-sync/internal/httpx"
+	"course-sync/internal/tracing"
 )
 
+var tracer = otel.Tracer("course-sync/internal/providers/pluralsight")
+
 type Client struct {
 	BaseURL string
 	Token   string
@@ -91,14 +98,22 @@ query CourseCatalog($first: Int!, $after: String) {
   }
 }`
 
-func (c *Client) ListCoursesPage(ctx context.Context, first int, after *string) (CourseCatalogGQLResponse, error) {
+func (c *Client) ListCoursesPage(ctx context.Context, first int, after *string) (_ CourseCatalogGQLResponse, err error) {
+	ctx, span := tracer.Start(ctx, "pluralsight.page", trace.WithAttributes(
+		attribute.Int("first", first),
+		attribute.Bool("cursor", after != nil && *after != ""),
+	))
+	defer tracing.End(span, &err)
+
 	const maxAttempts = 8
 	var lastErr error
 	var lastRetryAfter time.Duration
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		span.SetAttributes(attribute.Int("attempts", attempt))
 		out, retryable, retryAfter, err := c.listCoursesPageOnce(ctx, first, after)
 		if err == nil {
+			span.SetAttributes(attribute.Int("results", len(out.Data.CourseCatalog.Nodes)))
 			return out, nil
 		}
 		lastErr = err
@@ -106,6 +121,10 @@ func (c *Client) ListCoursesPage(ctx context.Context, first int, after *string)
 		if !retryable {
 			return CourseCatalogGQLResponse{}, err
 		}
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("reason", err.Error()),
+		))
 
 		sleep := lastRetryAfter
 		if sleep <= 0 {
@@ -324,7 +343,13 @@ func (c *Client) doGraphQL(ctx context.Context, reqBody graphQLRequest, out any)
 		return fmt.Errorf("read response body: %w", err)
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("pluralsight gql failed: status=%d body=%s", resp.StatusCode, string(body))
+		return fmt.Errorf("pluralsight gql failed: %w", &httpx.HTTPError{
+			Method:     r.Method,
+			URL:        r.URL.String(),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       body,
+		})
 	}
 
 	if err := json.Unmarshal(body, out); err != nil {
@@ -0,0 +1,269 @@
+// Package pluralsighttest provides an in-process fake of the Pluralsight
+// GraphQL API for end-to-end tests: the courseCatalog connection, paged with
+// first/after cursors, and the users and courseProgress lookups, behind a
+// bearer token, with scripted faults. Catalogs are loaded from UnifiedCourse
+// snapshots such as mocks/pluralsight.json and served as course nodes.
+//
+//	srv := pluralsighttest.NewServer()
+//	defer srv.Close()
+//	srv.LoadMocks("mocks/pluralsight.json")
+//	srv.Inject(pluralsighttest.Fault{Status: 503, Times: 1})
+//	// point PLURALSIGHT_GQL_URL at srv.GraphQLURL(), with srv.Token
+package pluralsighttest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"course-sync/internal/domain"
+	"course-sync/internal/httpx/httpxtest"
+	"course-sync/internal/providers/pluralsight"
+)
+
+// Fault makes matching requests fail or slow down; see httpxtest.Fault. A
+// GraphQL error answered with 200 is a Fault with a Body such as
+// {"errors":[{"message":"timeout"}]}.
+type Fault = httpxtest.Fault
+
+// Server is a fake Pluralsight GraphQL API. Its fields configure the
+// behaviour and may be set before the first request.
+type Server struct {
+	*httptest.Server
+	// Faults logs the requests and holds the injected faults.
+	httpxtest.Faults
+
+	// Token is the bearer token accepted.
+	Token string
+	// MaxFirst caps the page size asked for with first, as the real API
+	// truncates large pages.
+	MaxFirst int
+
+	mu       sync.Mutex
+	courses  []pluralsight.CourseNode
+	users    []pluralsight.UserNode
+	progress []pluralsight.CourseProgressNode
+}
+
+// NewServer starts a fake Pluralsight with no data. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Token:    "emulator-token",
+		MaxFirst: 1000,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", s.graphQL)
+	s.Server = httptest.NewServer(s.Handler(mux))
+	return s
+}
+
+// GraphQLURL is the endpoint the client posts to.
+func (s *Server) GraphQLURL() string {
+	return s.URL + "/graphql"
+}
+
+// LoadMocks adds the courses of a JSON snapshot of UnifiedCourses, such as
+// mocks/pluralsight.json.
+func (s *Server) LoadMocks(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var courses []domain.UnifiedCourse
+	if err := json.Unmarshal(b, &courses); err != nil {
+		return fmt.Errorf("pluralsighttest: %s: %w", path, err)
+	}
+	s.AddCourses(courses...)
+	return nil
+}
+
+// AddCourses appends courses to the catalog, converted to course nodes.
+func (s *Server) AddCourses(courses ...domain.UnifiedCourse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range courses {
+		s.courses = append(s.courses, courseNode(c))
+	}
+}
+
+// AddUsers adds users found by the users query.
+func (s *Server) AddUsers(users ...pluralsight.UserNode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, users...)
+}
+
+// AddProgress adds course progress found by the courseProgress query.
+func (s *Server) AddProgress(progress ...pluralsight.CourseProgressNode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = append(s.progress, progress...)
+}
+
+func courseNode(c domain.UnifiedCourse) pluralsight.CourseNode {
+	idNum, _ := strconv.ParseInt(c.SourceID, 10, 64)
+	slug := c.CourseURL
+	if i := strings.LastIndex(slug, "/courses/"); i >= 0 {
+		slug = slug[i+len("/courses/"):]
+	}
+	return pluralsight.CourseNode{
+		ID:            "course-" + c.SourceID,
+		IDNum:         idNum,
+		Slug:          strings.Trim(slug, "/"),
+		URL:           c.CourseURL,
+		Title:         c.Title,
+		Level:         c.Difficulty,
+		Description:   c.Description,
+		CourseSeconds: c.DurationHours * 3600,
+		ReleasedDate:  c.PublishedDate,
+		DisplayDate:   c.PublishedDate,
+		PublishedDate: c.PublishedDate,
+		Language:      c.Language,
+	}
+}
+
+type request struct {
+	Query     string          `json:"query"`
+	Variables json.RawMessage `json:"variables"`
+}
+
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		httpxtest.WriteError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpxtest.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	switch field := rootField(req.Query); field {
+	case "courseCatalog":
+		s.courseCatalog(w, req.Variables)
+	case "users":
+		s.usersByEmail(w, req.Variables)
+	case "courseProgress":
+		s.courseProgress(w, req.Variables)
+	default:
+		writeErrors(w, fmt.Sprintf("Cannot query field %q on type \"Query\".", field))
+	}
+}
+
+// rootField is the first field selected by a query document.
+func rootField(query string) string {
+	_, sel, ok := strings.Cut(query, "{")
+	if !ok {
+		return ""
+	}
+	sel = strings.TrimSpace(sel)
+	end := strings.IndexFunc(sel, func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if end < 0 {
+		return sel
+	}
+	return sel[:end]
+}
+
+func (s *Server) courseCatalog(w http.ResponseWriter, raw json.RawMessage) {
+	var vars struct {
+		First int     `json:"first"`
+		After *string `json:"after"`
+	}
+	if err := json.Unmarshal(raw, &vars); err != nil || vars.First < 1 {
+		writeErrors(w, "Variable \"$first\" must be a positive Int.")
+		return
+	}
+	start := 0
+	if vars.After != nil {
+		var ok bool
+		if start, ok = decodeCursor(*vars.After); !ok {
+			writeErrors(w, "Invalid cursor.")
+			return
+		}
+	}
+	first := min(vars.First, s.MaxFirst)
+
+	var out pluralsight.CourseCatalogGQLResponse
+	catalog := &out.Data.CourseCatalog
+	s.mu.Lock()
+	total := len(s.courses)
+	end := min(start+first, total)
+	catalog.Nodes = slices.Clone(s.courses[min(start, total):end])
+	s.mu.Unlock()
+	if catalog.Nodes == nil {
+		catalog.Nodes = []pluralsight.CourseNode{}
+	}
+	catalog.TotalCount = total
+	catalog.PageInfo.HasNextPage = end < total
+	if end > start {
+		catalog.PageInfo.EndCursor = encodeCursor(end)
+	}
+	httpxtest.WriteJSON(w, out)
+}
+
+// Cursors are opaque to clients; here they encode the offset of the next
+// node.
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, bool) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	v, ok := strings.CutPrefix(string(b), "offset:")
+	n, err := strconv.Atoi(v)
+	return n, ok && err == nil && n >= 0
+}
+
+func (s *Server) usersByEmail(w http.ResponseWriter, raw json.RawMessage) {
+	var vars struct {
+		Emails []string `json:"emails"`
+	}
+	if err := json.Unmarshal(raw, &vars); err != nil {
+		writeErrors(w, "Variable \"$emails\" must be a list of String.")
+		return
+	}
+	nodes := []pluralsight.UserNode{}
+	s.mu.Lock()
+	for _, u := range s.users {
+		if slices.ContainsFunc(vars.Emails, func(e string) bool { return strings.EqualFold(e, u.Email) }) {
+			nodes = append(nodes, u)
+		}
+	}
+	s.mu.Unlock()
+	httpxtest.WriteJSON(w, map[string]any{"data": map[string]any{"users": map[string]any{"nodes": nodes}}})
+}
+
+func (s *Server) courseProgress(w http.ResponseWriter, raw json.RawMessage) {
+	var vars struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.Unmarshal(raw, &vars); err != nil {
+		writeErrors(w, "Variable \"$ids\" must be a list of ID.")
+		return
+	}
+	nodes := []pluralsight.CourseProgressNode{}
+	s.mu.Lock()
+	for _, p := range s.progress {
+		if slices.Contains(vars.IDs, p.PsUserID) {
+			nodes = append(nodes, p)
+		}
+	}
+	s.mu.Unlock()
+	httpxtest.WriteJSON(w, map[string]any{"data": map[string]any{"courseProgress": map[string]any{"nodes": nodes}}})
+}
+
+// writeErrors answers a GraphQL error, which Pluralsight sends with 200.
+func writeErrors(w http.ResponseWriter, message string) {
+	httpxtest.WriteJSON(w, map[string]any{"data": nil, "errors": []map[string]string{{"message": message}}})
+}
@@ -0,0 +1,111 @@
+package pluralsighttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"course-sync/internal/domain"
+	"course-sync/internal/httpx"
+	"course-sync/internal/providers/pluralsight"
+)
+
+const mocksPath = "../../../../mocks/pluralsight.json"
+
+func TestServerServesMocks(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	if err := srv.LoadMocks(mocksPath); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pluralsight.Provider{C: pluralsight.New(srv.GraphQLURL(), srv.Token), First: 30}.ListCourses(context.Background())
+	if err != nil {
+		t.Fatalf("ListCourses() error: %v", err)
+	}
+
+	b, _ := os.ReadFile(mocksPath)
+	var want []domain.UnifiedCourse
+	if err := json.Unmarshal(b, &want); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d courses, got %d", len(want), len(got))
+	}
+	for i := range got {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("Course %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+	if n := len(srv.Requests()); n != 4 {
+		t.Errorf("Expected 4 pages of 30, got %d requests", n)
+	}
+}
+
+func TestServerCapsFirst(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.MaxFirst = 40
+	if err := srv.LoadMocks(mocksPath); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pluralsight.Provider{C: pluralsight.New(srv.GraphQLURL(), srv.Token)}.ListCourses(context.Background())
+	if err != nil {
+		t.Fatalf("ListCourses() error: %v", err)
+	}
+	if len(got) != 100 || len(srv.Requests()) != 3 {
+		t.Errorf("Expected 100 courses in 3 capped pages, got %d in %d requests", len(got), len(srv.Requests()))
+	}
+}
+
+func TestServerRetriesGraphQLErrors(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddCourses(domain.UnifiedCourse{SourceID: "1", Title: "Go"})
+	srv.Inject(Fault{Body: `{"data":null,"errors":[{"message":"upstream timeout"}]}`, Times: 1})
+
+	res, err := pluralsight.New(srv.GraphQLURL(), srv.Token).ListCoursesPage(context.Background(), 10, nil)
+	if err != nil {
+		t.Fatalf("ListCoursesPage() error: %v", err)
+	}
+	if nodes := res.Data.CourseCatalog.Nodes; len(nodes) != 1 || nodes[0].IDNum != 1 {
+		t.Errorf("Unexpected nodes %+v", nodes)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("Expected a retry after the GraphQL error, got %d requests", n)
+	}
+}
+
+func TestServerUsersAndProgress(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddUsers(pluralsight.UserNode{PsUserID: "u1", Email: "jane@example.com", FirstName: "Jane"})
+	srv.AddProgress(
+		pluralsight.CourseProgressNode{PsUserID: "u1", CourseID: "course-1", PercentComplete: 100, IsCourseCompleted: true},
+		pluralsight.CourseProgressNode{PsUserID: "u2", CourseID: "course-2"},
+	)
+
+	client := pluralsight.New(srv.GraphQLURL(), srv.Token)
+	user, err := client.GetUserByEmail(context.Background(), "Jane@example.com")
+	if err != nil || user == nil || user.PsUserID != "u1" {
+		t.Fatalf("GetUserByEmail() = %+v, %v", user, err)
+	}
+	if user, err := client.GetUserByEmail(context.Background(), "nobody@example.com"); err != nil || user != nil {
+		t.Errorf("Expected no user, got %+v, %v", user, err)
+	}
+	progress, err := client.GetCourseProgress(context.Background(), user.PsUserID)
+	if err != nil || len(progress) != 1 || !progress[0].IsCourseCompleted {
+		t.Errorf("GetCourseProgress() = %+v, %v", progress, err)
+	}
+
+	var herr *httpx.HTTPError
+	_, err = pluralsight.New(srv.GraphQLURL(), "wrong").GetUserByEmail(context.Background(), "jane@example.com")
+	if !errors.As(err, &herr) || herr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 for a wrong token, got %v", err)
+	}
+}
@@ -3,13 +3,10 @@ package udemy
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"hash/crc32"
-	"io"
 	"math"
 	"math/rand"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -17,8 +14,18 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"course-sync/internal/httpx"
+	"course-sync/internal/logging"
+	"course-sync/internal/tracing"
 )
 
+var tracer = otel.Tracer("course-sync/internal/providers/udemy")
+
 // Campos mínimos para reducir payload y parseo.
 // OJO: si en tu tenant esto te rompe algo, comentá esta línea en el query.
 const udemyCourseFieldsForXML = "id,title,description,url,estimated_content_length,categories,images,locale,last_update_date,level"
@@ -28,6 +35,7 @@ type Client struct {
 	ClientId     string
 	ClientSecret string
 	HTTP         *http.Client
+	Retry        httpx.RetryConfig
 }
 
 func New(baseURL, clientId string, clientSecret string) *Client {
@@ -52,6 +60,7 @@ func New(baseURL, clientId string, clientSecret string) *Client {
 			Timeout:   2 * time.Minute, // por-request
 			Transport: tr,
 		},
+		Retry: DefaultRetryConfig(),
 	}
 }
 
@@ -132,7 +141,7 @@ func (c *Client) ListCourses(ctx context.Context, pageSize int, maxPages int) ([
 	baseURL := u.String() // ya trae ?page_size=100&fields[course]=...
 
 	// 1) Page 1 para saber Count y pageSizeReal
-	firstResp, err := c.fetchPageWithRetry(ctx, baseURL)
+	firstResp, err := c.fetchPage(ctx, baseURL, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +156,8 @@ func (c *Client) ListCourses(ctx context.Context, pageSize int, maxPages int) ([
 		totalPages = maxPages
 	}
 
-	fmt.Printf("udemy page 1: results=%d total=%d\n", len(firstResp.Results), firstResp.Count)
+	log := logging.FromContext(ctx).With("provider", "udemy")
+	log.Info("fetched page", "page", 1, "pages", totalPages, "results", len(firstResp.Results), "total", firstResp.Count)
 
 	all := make([]Course, 0, minInt(firstResp.Count, totalPages*pageSizeReal))
 	all = append(all, firstResp.Results...)
@@ -201,7 +211,7 @@ loop:
 			}
 
 			pageURL := baseURL + fmt.Sprintf("&page=%d", p)
-			resp, err := c.fetchPageWithRetry(ctx, pageURL)
+			resp, err := c.fetchPage(ctx, pageURL, p)
 			if err != nil {
 				once.Do(func() {
 					firstErr = err
@@ -210,7 +220,7 @@ loop:
 				return
 			}
 
-			fmt.Printf("udemy page %d: results=%d total=%d\n", p, len(resp.Results), resp.Count)
+			log.Info("fetched page", "page", p, "pages", totalPages, "results", len(resp.Results), "total", resp.Count)
 
 			mu.Lock()
 			all = append(all, resp.Results...)
@@ -247,149 +257,52 @@ func minInt(a, b int) int {
 	return b
 }
 
-func (c *Client) fetchPageWithRetry(ctx context.Context, pageURL string) (*ListCoursesResponse, error) {
-	const maxAttempts = 12 // Aumentado de 8 a 12 para más reintentos
-
-	var lastErr error
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		out, retryAfter, err := c.fetchPageOnce(ctx, pageURL)
-		if err == nil {
-			return out, nil
-		}
-
-		lastErr = err
-		// Si es un error no recuperable, salimos inmediatamente
-		if retryAfter < 0 {
-			return nil, err
-		}
-
-		// Verificar si es un error GOAWAY
-		isGoAway := strings.Contains(err.Error(), "GOAWAY") ||
-			strings.Contains(err.Error(), "connection closed")
+func (c *Client) fetchPage(ctx context.Context, pageURL string, page int) (_ *ListCoursesResponse, err error) {
+	ctx, span := tracer.Start(ctx, "udemy.page", trace.WithAttributes(attribute.Int("page", page)))
+	defer tracing.End(span, &err)
 
-		sleep := retryAfter
-		if sleep == 0 {
-			// Backoff exponencial con jitter
-			base := 1000 * time.Millisecond // Aumentado de 700ms a 1s
-			sleep = base * time.Duration(1<<(attempt-1))
-
-			// Para errores GOAWAY, esperar más tiempo
-			if isGoAway && attempt > 1 {
-				sleep = sleep * 2
-			}
-
-			// Limitar el tiempo máximo de espera
-			if sleep > 45*time.Second { // Aumentado de 30s a 45s
-				sleep = 45 * time.Second
+	var out ListCoursesResponse
+	err = httpx.DoJSON(
+		ctx,
+		c.HTTP,
+		func(ctx context.Context) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+			if err != nil {
+				return nil, err
 			}
-
-			// Añadir jitter para evitar sincronización
-			jitter := time.Duration(rand.Intn(1000)) * time.Millisecond // Aumentado de 500ms a 1s
-			sleep += jitter
-		}
-
-		// Loguear el reintento para debugging
-		fmt.Printf("udemy: retrying page %s (attempt %d/%d) after %v: %v\n",
-			pageURL, attempt, maxAttempts, sleep, err)
-
-		select {
-		case <-time.After(sleep):
-		case <-ctx.Done():
-			return nil, fmt.Errorf("udemy: context canceled while retrying: %w", ctx.Err())
-		}
-	}
-
-	return nil, lastErr
-}
-
-func (c *Client) fetchPageOnce(ctx context.Context, pageURL string) (*ListCoursesResponse, time.Duration, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
-	if err != nil {
-		return nil, -1, fmt.Errorf("udemy: build request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.SetBasicAuth(c.ClientId, c.ClientSecret)
-
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		if isNetRetryable(err) {
-			return nil, 0, fmt.Errorf("udemy: request failed (retryable): %w", err)
-		}
-		return nil, -1, fmt.Errorf("udemy: request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+			req.Header.Set("Accept", "application/json")
+			req.SetBasicAuth(c.ClientId, c.ClientSecret)
+			return req, nil
+		},
+		&out,
+		c.Retry,
+	)
 	if err != nil {
-		if isNetRetryable(err) {
-			return nil, 0, fmt.Errorf("udemy: read body failed (retryable): %w", err)
-		}
-		return nil, -1, fmt.Errorf("udemy: read response body: %w", err)
-	}
-
-	if resp.StatusCode != 200 {
-		if resp.StatusCode == 429 || (resp.StatusCode >= 500 && resp.StatusCode <= 599) {
-			return nil, parseRetryAfter(resp), fmt.Errorf("udemy list failed: status=%d body=%s", resp.StatusCode, string(body))
-		}
-		return nil, -1, fmt.Errorf("udemy list failed: status=%d body=%s", resp.StatusCode, string(body))
-	}
-
-	var out ListCoursesResponse
-	if err := json.Unmarshal(body, &out); err != nil {
-		if looksLikeHTML(body) {
-			return nil, 0, fmt.Errorf("udemy: json parse error but looks like HTML (retryable): %w body=%s", err, string(body))
-		}
-		return nil, -1, fmt.Errorf("udemy: json parse error: %w", err)
-	}
-
-	return &out, -1, nil
-}
-
-func looksLikeHTML(b []byte) bool {
-	s := string(b)
-	if len(s) == 0 {
-		return false
-	}
-	return (len(s) >= 6 && (s[0:6] == "<html>" || s[0:5] == "<!DOC" || s[0:4] == "<htm"))
-}
-
-func parseRetryAfter(resp *http.Response) time.Duration {
-	v := resp.Header.Get("Retry-After")
-	if v == "" {
-		return 0
-	}
-	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
-		return time.Duration(secs) * time.Second
+		return nil, fmt.Errorf("udemy: list courses failed: %w", err)
 	}
-	if t, err := http.ParseTime(v); err == nil {
-		d := time.Until(t)
-		if d < 0 {
-			return 0
-		}
-		return d
-	}
-	return 0
+	span.SetAttributes(attribute.Int("results", len(out.Results)))
+	return &out, nil
 }
 
-func isNetRetryable(err error) bool {
-	// Verificar si es un error de red
-	var nerr net.Error
-	if errors.As(err, &nerr) {
-		return nerr.Timeout() || nerr.Temporary()
-	}
-
-	// Verificar si es un error de contexto
-	if errors.Is(err, context.DeadlineExceeded) {
-		return true
+// DefaultRetryConfig is the retry policy for the Udemy courses API. Udemy's
+// edge answers bursts with 429s, HTTP/2 GOAWAYs and the occasional HTML error
+// page served with status 200, so it retries longer than httpx's default and
+// backs off twice as much after a GOAWAY.
+func DefaultRetryConfig() httpx.RetryConfig {
+	return httpx.RetryConfig{
+		MaxAttempts: 12,
+		BaseDelay:   1 * time.Second,
+		MaxDelay:    45 * time.Second,
+		Jitter:      1 * time.Second,
+		Retry5xx:    true,
+		RetryStatuses: map[int]bool{
+			http.StatusTooManyRequests: true,
+		},
+		RetryOnHTMLBody: true,
+		Multipliers: map[httpx.ErrorClass]float64{
+			httpx.ClassGoAway: 2,
+		},
 	}
-
-	// Verificar si es un error GOAWAY de HTTP/2
-	errStr := err.Error()
-	return strings.Contains(errStr, "GOAWAY") ||
-		strings.Contains(errStr, "connection closed") ||
-		strings.Contains(errStr, "EOF") ||
-		strings.Contains(errStr, "reset by peer")
 }
 
 func pickUdemyImageURL(raw json.RawMessage) string {
@@ -3,12 +3,16 @@ package udemy
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"course-sync/internal/httpx"
 )
 
 const (
@@ -83,114 +87,98 @@ func TestMinInt(t *testing.T) {
 	}
 }
 
-func TestLooksLikeHTML(t *testing.T) {
-	testCases := []struct {
-		input    string
-		expected bool
-	}{
-		{"<html><body>Test</body></html>", true},
-		{"<!DOCTYPE html>", true},
-		{"<html lang=\"en\">", true},
-		{"{\"key\": \"value\"}", false},
-		{"", false},
-		{"plain text", false},
-	}
+func TestListCoursesRetriesThroughHTMLAndRateLimit(t *testing.T) {
+	t.Setenv("UDEMY_ORG_ID", "42")
 
-	for _, tc := range testCases {
-		result := looksLikeHTML([]byte(tc.input))
-		if result != tc.expected {
-			t.Errorf("looksLikeHTML(%q) = %v; expected %v", tc.input, result, tc.expected)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/organizations/42/courses/list/" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != testClientID || pass != testClientSecret {
+			t.Errorf("expected basic auth %s/%s, got %q/%q", testClientID, testClientSecret, user, pass)
 		}
-	}
-}
 
-func TestIsNetRetryable(t *testing.T) {
-	testCases := []struct {
-		name     string
-		err      error
-		expected bool
-	}{
-		{"GOAWAY error", fmt.Errorf("http2: server sent GOAWAY"), true},
-		{"Connection closed", fmt.Errorf("connection closed"), true},
-		{"EOF error", fmt.Errorf("unexpected EOF"), true},
-		{"Reset by peer", fmt.Errorf("connection reset by peer"), true},
-		{"Context deadline", context.DeadlineExceeded, true},
-		{"Other error", fmt.Errorf("some other error"), false},
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set(retryAfterHeader, "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		case 2:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<!DOCTYPE html><html><body>maintenance</body></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"count":1,"next":"","results":[{"id":7,"title":"Go"}]}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, testClientID, testClientSecret)
+	client.Retry.BaseDelay = time.Millisecond
+	client.Retry.MaxDelay = 5 * time.Millisecond
+	client.Retry.Jitter = time.Millisecond
+
+	var retried []httpx.ErrorClass
+	client.Retry.OnRetry = func(attempt int, class httpx.ErrorClass, err error, sleep time.Duration) {
+		retried = append(retried, class)
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result := isNetRetryable(tc.err)
-			if result != tc.expected {
-				t.Errorf("isNetRetryable(%v) = %v; expected %v", tc.err, result, tc.expected)
-			}
-		})
+	courses, err := client.ListCourses(context.Background(), 100, 1)
+	if err != nil {
+		t.Fatalf("ListCourses() returned error: %v", err)
 	}
-}
-
-func TestParseRetryAfter(t *testing.T) {
-	testCases := []struct {
-		name     string
-		header   string
-		expected time.Duration
-	}{
-		{"Empty header", "", 0},
-		{"Seconds value", "30", 30 * time.Second},
-		{"Invalid value", "invalid", 0},
-		{"Negative value", "-10", 0},
+	if len(courses) != 1 || courses[0].ID != 7 {
+		t.Fatalf("unexpected courses: %+v", courses)
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			resp := &http.Response{
-				Header: http.Header{},
-			}
-			if tc.header != "" {
-				resp.Header.Set(retryAfterHeader, tc.header)
-			}
-
-			result := parseRetryAfter(resp)
-			if result != tc.expected {
-				t.Errorf("parseRetryAfter() = %v; expected %v", result, tc.expected)
-			}
-		})
+	want := []httpx.ErrorClass{httpx.ClassStatus, httpx.ClassHTMLBody}
+	if !reflect.DeepEqual(retried, want) {
+		t.Errorf("retry classes = %v, want %v", retried, want)
 	}
+}
 
-	// Skip HTTP date format test as it's time-dependent and can be flaky
-	t.Run("HTTP date format", func(t *testing.T) {
-		t.Skip("Skipping time-dependent test")
-		// Create a future time
-		futureTime := time.Now().Add(60 * time.Second)
-		dateStr := futureTime.Format(time.RFC1123)
+func TestListCoursesDoesNotRetryClientErrors(t *testing.T) {
+	t.Setenv("UDEMY_ORG_ID", "42")
 
-		resp := &http.Response{
-			Header: http.Header{},
-		}
-		resp.Header.Set("Retry-After", dateStr)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"detail":"forbidden"}`))
+	}))
+	defer server.Close()
 
-		result := parseRetryAfter(resp)
-		// Allow for small differences due to time parsing
-		if result < 55*time.Second || result > 65*time.Second {
-			t.Errorf("parseRetryAfter() = %v; expected ~60s", result)
-		}
-	})
+	client := New(server.URL, testClientID, testClientSecret)
+	client.Retry.OnRetry = nil
 
-	// Test past date (should return 0)
-	t.Run("Past date", func(t *testing.T) {
-		// Create a past time
-		pastTime := time.Now().Add(-60 * time.Second)
-		dateStr := pastTime.Format(time.RFC1123)
+	_, err := client.ListCourses(context.Background(), 100, 1)
+	if err == nil || !strings.Contains(err.Error(), "status=403") {
+		t.Fatalf("expected 403 error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected a single attempt, got %d", n)
+	}
+}
 
-		resp := &http.Response{
-			Header: http.Header{},
-		}
-		resp.Header.Set("Retry-After", dateStr)
+func TestDefaultRetryConfig(t *testing.T) {
+	cfg := DefaultRetryConfig()
 
-		result := parseRetryAfter(resp)
-		if result != 0 {
-			t.Errorf("parseRetryAfter() = %v; expected 0 for past date", result)
-		}
-	})
+	if cfg.MaxAttempts != 12 {
+		t.Errorf("Expected 12 attempts, got %d", cfg.MaxAttempts)
+	}
+	if !cfg.RetryOnHTMLBody {
+		t.Error("Expected HTML bodies to be retried")
+	}
+	if cfg.Multipliers[httpx.ClassGoAway] != 2 {
+		t.Errorf("Expected GOAWAY multiplier 2, got %v", cfg.Multipliers[httpx.ClassGoAway])
+	}
+	if !cfg.RetryStatuses[http.StatusTooManyRequests] || !cfg.Retry5xx {
+		t.Error("Expected 429 and 5xx to be retried")
+	}
+	if cfg.RetryStatuses[http.StatusRequestTimeout] {
+		t.Error("Expected 408 not to be retried")
+	}
 }
 
 func TestPickUdemyImageURL(t *testing.T) {
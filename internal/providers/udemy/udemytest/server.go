@@ -0,0 +1,234 @@
+// Package udemytest provides an in-process fake of the Udemy Business REST
+// API for end-to-end tests: the organization course list, paged with
+// page/page_size and next links, behind basic auth, with scripted faults.
+// Catalogs are loaded from UnifiedCourse snapshots such as mocks/udemy.json
+// and served in Udemy's wire format.
+//
+//	srv := udemytest.NewServer()
+//	defer srv.Close()
+//	srv.LoadMocks("mocks/udemy.json")
+//	srv.Inject(udemytest.Fault{Status: 429, RetryAfter: "1", Times: 1})
+//	// point UDEMY_BASE_URL at srv.URL and UDEMY_ORG_ID at srv.OrgID
+package udemytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"course-sync/internal/domain"
+	"course-sync/internal/httpx/httpxtest"
+)
+
+// Fault makes matching requests fail or slow down; see httpxtest.Fault.
+type Fault = httpxtest.Fault
+
+// Server is a fake Udemy API. Its fields configure the behaviour and may be
+// set before the first request.
+type Server struct {
+	*httptest.Server
+	// Faults logs the requests and holds the injected faults.
+	httpxtest.Faults
+
+	// OrgID is the organization whose catalog is served; other ids get a
+	// 404.
+	OrgID string
+	// ClientID and ClientSecret are the basic auth credentials accepted.
+	ClientID     string
+	ClientSecret string
+
+	// PageSize is the size of a page asked for without page_size, and
+	// MaxPageSize the largest page_size served (larger ones are capped, as
+	// Udemy does).
+	PageSize    int
+	MaxPageSize int
+
+	mu      sync.Mutex
+	courses []map[string]any
+}
+
+// NewServer starts a fake Udemy with an empty catalog. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		OrgID:        "emulator-org",
+		ClientID:     "emulator-client",
+		ClientSecret: "emulator-secret",
+		PageSize:     12,
+		MaxPageSize:  100,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /organizations/{org}/courses/list/", s.authorized(s.listCourses))
+	s.Server = httptest.NewServer(s.Handler(mux))
+	return s
+}
+
+// LoadMocks adds the courses of a JSON snapshot of UnifiedCourses, such as
+// mocks/udemy.json.
+func (s *Server) LoadMocks(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var courses []domain.UnifiedCourse
+	if err := json.Unmarshal(b, &courses); err != nil {
+		return fmt.Errorf("udemytest: %s: %w", path, err)
+	}
+	s.AddCourses(courses...)
+	return nil
+}
+
+// AddCourses appends courses to the catalog, converted to Udemy records:
+// numeric ids, relative urls, durations in seconds, the locale as an object
+// and categories as a list of titles.
+func (s *Server) AddCourses(courses ...domain.UnifiedCourse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range courses {
+		s.courses = append(s.courses, wireCourse(c))
+	}
+}
+
+// Courses returns the number of courses in the catalog.
+func (s *Server) Courses() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.courses)
+}
+
+func wireCourse(c domain.UnifiedCourse) map[string]any {
+	id, _ := strconv.Atoi(c.SourceID)
+	course := map[string]any{
+		"_class":                   "course",
+		"id":                       id,
+		"title":                    c.Title,
+		"description":              c.Description,
+		"url":                      relativeURL(c.CourseURL),
+		"estimated_content_length": int64(math.Round(c.DurationHours * 3600)),
+		"locale":                   map[string]any{"_class": "locale", "locale": c.Language, "title": c.Language},
+		"last_update_date":         c.PublishedDate,
+		"level":                    c.Difficulty,
+		"categories":               []string{},
+		"images":                   map[string]any{},
+	}
+	if c.Category != "" {
+		course["categories"] = strings.Split(c.Category, " | ")
+	}
+	if c.ImageURL != "" {
+		course["images"] = map[string]any{"size_480x270": c.ImageURL}
+	}
+	return course
+}
+
+// relativeURL strips the scheme and host, as Udemy returns course paths.
+func relativeURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	return u.RequestURI()
+}
+
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != s.ClientID || secret != s.ClientSecret {
+			writeDetail(w, http.StatusUnauthorized, "Invalid username/password.")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) listCourses(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("org") != s.OrgID {
+		writeDetail(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	q := r.URL.Query()
+	page, size := 1, s.PageSize
+	var err error
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			writeDetail(w, http.StatusNotFound, "Invalid page.")
+			return
+		}
+	}
+	if v := q.Get("page_size"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil || size < 1 {
+			writeDetail(w, http.StatusBadRequest, "Invalid page_size.")
+			return
+		}
+		size = min(size, s.MaxPageSize)
+	}
+	fields := fieldSet(q.Get("fields[course]"))
+
+	s.mu.Lock()
+	total := len(s.courses)
+	start := (page - 1) * size
+	if start >= total && page > 1 {
+		s.mu.Unlock()
+		writeDetail(w, http.StatusNotFound, "Invalid page.")
+		return
+	}
+	rows := make([]map[string]any, 0, size)
+	for _, c := range s.courses[min(start, total):min(start+size, total)] {
+		rows = append(rows, project(c, fields))
+	}
+	s.mu.Unlock()
+
+	httpxtest.WriteJSON(w, map[string]any{
+		"count":        total,
+		"next":         s.pageURL(r, page+1, start+size < total),
+		"previous":     s.pageURL(r, page-1, page > 1),
+		"results":      rows,
+		"aggregations": nil,
+	})
+}
+
+// pageURL is the absolute url of another page of the same listing, or nil.
+func (s *Server) pageURL(r *http.Request, page int, ok bool) any {
+	if !ok {
+		return nil
+	}
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	return s.URL + r.URL.Path + "?" + q.Encode()
+}
+
+// fieldSet parses a fields[course] value; nil means all fields.
+func fieldSet(v string) map[string]bool {
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	set := map[string]bool{"_class": true, "id": true}
+	for f := range strings.SplitSeq(v, ",") {
+		set[strings.TrimSpace(f)] = true
+	}
+	return set
+}
+
+func project(c map[string]any, fields map[string]bool) map[string]any {
+	if fields == nil {
+		return c
+	}
+	out := make(map[string]any, len(fields))
+	for k, v := range c {
+		if fields[k] {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func writeDetail(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"detail": detail})
+}
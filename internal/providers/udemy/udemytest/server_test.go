@@ -0,0 +1,118 @@
+package udemytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"course-sync/internal/domain"
+	"course-sync/internal/httpx"
+	"course-sync/internal/providers/udemy"
+)
+
+const mocksPath = "../../../../mocks/udemy.json"
+
+// newClient returns a client for srv that retries without waiting.
+func newClient(t *testing.T, srv *Server) *udemy.Client {
+	t.Setenv("UDEMY_ORG_ID", srv.OrgID)
+	t.Setenv("UDEMY_RPS", "100")
+	c := udemy.New(srv.URL, srv.ClientID, srv.ClientSecret)
+	c.Retry.BaseDelay = time.Millisecond
+	c.Retry.Jitter = time.Millisecond
+	return c
+}
+
+func TestServerServesMocks(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	if err := srv.LoadMocks(mocksPath); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := udemy.Provider{C: newClient(t, srv), PageSize: 30}.ListCourses(context.Background())
+	if err != nil {
+		t.Fatalf("ListCourses() error: %v", err)
+	}
+
+	b, _ := os.ReadFile(mocksPath)
+	var want []domain.UnifiedCourse
+	if err := json.Unmarshal(b, &want); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d courses, got %d", len(want), len(got))
+	}
+	// Pages are fetched in parallel, and course urls come back relative to
+	// the emulator.
+	bySourceID := func(a, b domain.UnifiedCourse) int { return strings.Compare(a.SourceID, b.SourceID) }
+	slices.SortFunc(got, bySourceID)
+	slices.SortFunc(want, bySourceID)
+	for i := range got {
+		got[i].CourseURL = coursePath(got[i].CourseURL)
+		want[i].CourseURL = coursePath(want[i].CourseURL)
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("Course %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+
+	if reqs := srv.Requests(); len(reqs) != 4 || !strings.Contains(strings.Join(reqs, "\n"), "page=4") {
+		t.Errorf("Expected 4 pages of 30, got %q", reqs)
+	}
+}
+
+func coursePath(s string) string {
+	u, _ := url.Parse(s)
+	return u.Path
+}
+
+func TestServerFaults(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	if err := srv.LoadMocks(mocksPath); err != nil {
+		t.Fatal(err)
+	}
+	srv.Inject(Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	srv.Inject(Fault{Body: "<!DOCTYPE html><html><body>Maintenance</body></html>", ContentType: "text/html", Times: 1})
+	srv.Inject(Fault{Status: http.StatusTooManyRequests, Times: 1})
+
+	courses, err := newClient(t, srv).ListCourses(context.Background(), 50, 0)
+	if err != nil {
+		t.Fatalf("ListCourses() error: %v", err)
+	}
+	if len(courses) != 100 {
+		t.Errorf("Expected 100 courses, got %d", len(courses))
+	}
+	if n := len(srv.Requests()); n != 5 {
+		t.Errorf("Expected 3 failed attempts and 2 pages, got %d requests", n)
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddCourses(domain.UnifiedCourse{SourceID: "1", Title: "Go"})
+
+	c := newClient(t, srv)
+	c.ClientSecret = "wrong"
+	var herr *httpx.HTTPError
+	if _, err := c.ListCourses(context.Background(), 10, 0); !errors.As(err, &herr) || herr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 for wrong credentials, got %v", err)
+	}
+
+	c = newClient(t, srv)
+	t.Setenv("UDEMY_ORG_ID", "other-org")
+	if _, err := c.ListCourses(context.Background(), 10, 0); !errors.As(err, &herr) || herr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 for another organization, got %v", err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("Expected client errors not to be retried, got %d requests", n)
+	}
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// Thursday 2026-01-15 10:17:30 UTC
+	from := time.Date(2026, 1, 15, 10, 17, 30, 0, time.UTC)
+
+	testCases := []struct {
+		spec string
+		want string
+	}{
+		{"* * * * *", "2026-01-15 10:18"},
+		{"30 2 * * *", "2026-01-16 02:30"},
+		{"*/15 * * * *", "2026-01-15 10:30"},
+		{"0 */6 * * *", "2026-01-15 12:00"},
+		{"5,45 10 * * *", "2026-01-15 10:45"},
+		{"0 9 * * mon-fri", "2026-01-16 09:00"},
+		{"0 9 * * sat", "2026-01-17 09:00"},
+		{"0 9 * * 7", "2026-01-18 09:00"},
+		{"0 0 1 * *", "2026-02-01 00:00"},
+		{"0 0 1 jun *", "2026-06-01 00:00"},
+		{"0 0 29 2 *", "2028-02-29 00:00"},
+		// day-of-month OR day-of-week when both are restricted
+		{"0 8 20 * mon", "2026-01-19 08:00"},
+		{"10-20/5 10 * * *", "2026-01-15 10:20"},
+		{"@hourly", "2026-01-15 11:00"},
+		{"@daily", "2026-01-16 00:00"},
+		{"@weekly", "2026-01-18 00:00"},
+		{"@monthly", "2026-02-01 00:00"},
+		{"@yearly", "2027-01-01 00:00"},
+	}
+
+	for _, tc := range testCases {
+		s, err := Parse(tc.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tc.spec, err)
+			continue
+		}
+		if got := s.Next(from).Format("2006-01-02 15:04"); got != tc.want {
+			t.Errorf("Parse(%q).Next() = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestNextIsStrictlyAfter(t *testing.T) {
+	s, _ := Parse("30 2 * * *")
+	at := time.Date(2026, 1, 15, 2, 30, 0, 0, time.UTC)
+	if got := s.Next(at); !got.Equal(at.AddDate(0, 0, 1)) {
+		t.Errorf("Next(%v) = %v, want the following day", at, got)
+	}
+}
+
+func TestNextUsesLocation(t *testing.T) {
+	loc := time.FixedZone("CST", -6*3600)
+	s, _ := Parse("0 2 * * *")
+	from := time.Date(2026, 1, 15, 1, 0, 0, 0, loc)
+	got := s.Next(from)
+	if got.Location() != loc || got.Hour() != 2 || got.Day() != 15 {
+		t.Errorf("Next() = %v, want 02:00 the same day in CST", got)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s, err := Parse("@every 90m")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	from := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	if got := s.Next(from); !got.Equal(from.Add(90 * time.Minute)) {
+		t.Errorf("Next() = %v", got)
+	}
+}
+
+func TestImpossibleDate(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Expected zero time for Feb 31, got %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"", "empty spec"},
+		{"* * * *", "expected 5 fields"},
+		{"60 * * * *", "minute: value 60 out of range"},
+		{"* 24 * * *", "hour"},
+		{"* * 0 * *", "day-of-month"},
+		{"* * * 13 *", "month"},
+		{"* * * * 8", "day-of-week"},
+		{"*/0 * * * *", "invalid step"},
+		{"5-1 * * * *", "invalid range"},
+		{"* * * foo *", `invalid value "foo"`},
+		{"@fortnightly", "unknown descriptor"},
+		{"@every 10s", "at least 1m"},
+		{"@every soon", "invalid duration"},
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse(tc.spec)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tc.spec, err, tc.wantErr)
+		}
+	}
+}
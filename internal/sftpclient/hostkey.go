@@ -0,0 +1,268 @@
+package sftpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"course-sync/internal/logging"
+)
+
+var (
+	// ErrHostKeyMismatch is returned, wrapped, when the server's key is not
+	// the pinned or known one, or is revoked.
+	ErrHostKeyMismatch = errors.New("sftp: host key mismatch")
+	// ErrHostKeyUnknown is returned, wrapped, when the server is not in the
+	// known_hosts files and TrustOnFirstUse is off.
+	ErrHostKeyUnknown = errors.New("sftp: unknown host key")
+)
+
+// errNoHostKeyCheck is returned when neither known_hosts files nor a pinned
+// key are configured and the check is not explicitly disabled.
+var errNoHostKeyCheck = errors.New("sftp: host key check enabled but SFTP_HOST_KEY not set and SFTP_KNOWN_HOSTS empty: " +
+	"set one of them (`course-sync sftp hostkey` shows the server's key and fingerprint), " +
+	"or SFTP_INSECURE_IGNORE_HOSTKEY=true to skip the check (not for production)")
+
+// Fingerprint is the OpenSSH SHA-256 fingerprint of key, "SHA256:...".
+func Fingerprint(key ssh.PublicKey) string {
+	return ssh.FingerprintSHA256(key)
+}
+
+// hostKeyCallback returns the host key check for cfg, and the host key
+// algorithms to ask the server for so that one of its known keys is
+// offered (a server usually has several):
+//   - InsecureIgnoreHostKey accepts any key;
+//   - HostKey pins a single key;
+//   - KnownHosts checks OpenSSH known_hosts files, with hashed host names,
+//     several keys per host and @revoked lines. With TrustOnFirstUse, the key
+//     of a host not in them yet is accepted and appended to the first file.
+func hostKeyCallback(ctx context.Context, cfg Config) (ssh.HostKeyCallback, []string, error) {
+	switch {
+	case cfg.InsecureIgnoreHostKey:
+		return ssh.InsecureIgnoreHostKey(), nil, nil
+	case strings.TrimSpace(cfg.HostKey) != "":
+		return pinnedKey(cfg.HostKey)
+	case len(cfg.KnownHosts) > 0:
+		k := &knownHosts{files: cfg.KnownHosts, tofu: cfg.TrustOnFirstUse, ctx: ctx}
+		algos, err := k.algorithms(net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port)))
+		if err != nil {
+			return nil, nil, permanent(err)
+		}
+		return k.check, algos, nil
+	default:
+		return nil, nil, permanent(errNoHostKeyCheck)
+	}
+}
+
+func pinnedKey(hostKey string) (ssh.HostKeyCallback, []string, error) {
+	expectedType, expectedB64, err := splitKey(hostKey)
+	if err != nil {
+		return nil, nil, permanent(fmt.Errorf("sftp: invalid SFTP_HOST_KEY: %w", err))
+	}
+	expectedRaw, err := base64.StdEncoding.DecodeString(expectedB64)
+	if err != nil {
+		return nil, nil, permanent(fmt.Errorf("sftp: invalid SFTP_HOST_KEY base64: %w", err))
+	}
+	expected, err := ssh.ParsePublicKey(expectedRaw)
+	if err != nil {
+		return nil, nil, permanent(fmt.Errorf("sftp: invalid SFTP_HOST_KEY: %w", err))
+	}
+	if expected.Type() != expectedType {
+		return nil, nil, permanent(fmt.Errorf("sftp: invalid SFTP_HOST_KEY: type %s does not match the key (%s)", expectedType, expected.Type()))
+	}
+	cb := func(hostname string, remoteAddr net.Addr, key ssh.PublicKey) error {
+		if key.Type() != expected.Type() || !bytes.Equal(key.Marshal(), expected.Marshal()) {
+			return fmt.Errorf("%w for %s: server offered %s %s, SFTP_HOST_KEY is %s %s",
+				ErrHostKeyMismatch, hostname, key.Type(), Fingerprint(key), expected.Type(), Fingerprint(expected))
+		}
+		return nil
+	}
+	return cb, keyAlgorithms(expected.Type()), nil
+}
+
+// knownHosts checks keys against known_hosts files. The files are read on
+// every check, so a key recorded by TrustOnFirstUse is seen by the next
+// connection.
+type knownHosts struct {
+	files []string
+	tofu  bool
+	ctx   context.Context // for logging
+	mu    sync.Mutex
+}
+
+// callback parses the files. With TrustOnFirstUse, missing files are
+// treated as empty (the first one is created when a key is recorded).
+func (k *knownHosts) callback() (ssh.HostKeyCallback, error) {
+	var files []string
+	for _, f := range k.files {
+		if _, err := os.Stat(f); err != nil {
+			if k.tofu && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("sftp: known_hosts: %w", err)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return func(string, net.Addr, ssh.PublicKey) error { return &knownhosts.KeyError{} }, nil
+	}
+	cb, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: known_hosts: %w", err)
+	}
+	return cb, nil
+}
+
+func (k *knownHosts) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	cb, err := k.callback()
+	if err != nil {
+		return err
+	}
+	err = cb(hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	var revoked *knownhosts.RevokedError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &revoked):
+		return fmt.Errorf("%w for %s: %s %s is marked @revoked in %s:%d",
+			ErrHostKeyMismatch, hostname, key.Type(), Fingerprint(key), revoked.Revoked.Filename, revoked.Revoked.Line)
+	case errors.As(err, &keyErr) && len(keyErr.Want) > 0:
+		var known []string
+		for _, w := range keyErr.Want {
+			known = append(known, fmt.Sprintf("%s %s (%s:%d)", w.Key.Type(), Fingerprint(w.Key), w.Filename, w.Line))
+		}
+		return fmt.Errorf("%w for %s: server offered %s %s, known_hosts has %s; "+
+			"if the server's key was changed on purpose, remove the old line",
+			ErrHostKeyMismatch, hostname, key.Type(), Fingerprint(key), strings.Join(known, ", "))
+	case errors.As(err, &keyErr) && k.tofu:
+		if err := appendKnownHost(k.files[0], hostname, key); err != nil {
+			return fmt.Errorf("sftp: trust on first use: %w", err)
+		}
+		logging.FromContext(k.ctx).Warn("trusting new SFTP host key", "component", "sftp",
+			"host", hostname, "key_type", key.Type(), "fingerprint", Fingerprint(key), "known_hosts", k.files[0])
+		return nil
+	case errors.As(err, &keyErr):
+		return fmt.Errorf("%w: %s is not in %s (server offered %s %s); "+
+			"check the fingerprint with the server's operator and add it, or set SFTP_TRUST_ON_FIRST_USE=true",
+			ErrHostKeyUnknown, hostname, strings.Join(k.files, ", "), key.Type(), Fingerprint(key))
+	default:
+		return err
+	}
+}
+
+// algorithms returns the host key algorithms of the keys known for
+// hostport, or nil (the defaults) when none are.
+func (k *knownHosts) algorithms(hostport string) ([]string, error) {
+	cb, err := k.callback()
+	if err != nil {
+		return nil, err
+	}
+	// A key that is in no file makes the callback list the known ones.
+	probe, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return nil, err
+	}
+	var keyErr *knownhosts.KeyError
+	if err := cb(hostport, &net.TCPAddr{IP: net.IPv4zero}, probe); !errors.As(err, &keyErr) {
+		return nil, nil
+	}
+	var algos []string
+	seen := map[string]bool{}
+	for _, w := range keyErr.Want {
+		for _, a := range keyAlgorithms(w.Key.Type()) {
+			if !seen[a] {
+				seen[a] = true
+				algos = append(algos, a)
+			}
+		}
+	}
+	return algos, nil
+}
+
+// keyAlgorithms maps a key type to the signature algorithms that use it.
+func keyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// appendKnownHost adds a known_hosts line for hostname (host:port), creating
+// the file and its directory if needed.
+func appendKnownHost(file, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// errKeyFetched stops the handshake once HostKey has the server's key.
+var errKeyFetched = errors.New("host key fetched")
+
+// HostKey connects to cfg's server and returns its host key, without
+// checking it or authenticating. Use it to show the fingerprint to compare
+// with the one the server's operator publishes.
+func HostKey(ctx context.Context, cfg Config) (ssh.PublicKey, error) {
+	if cfg.Port <= 0 {
+		cfg.Port = 22
+	}
+	keys := make(chan ssh.PublicKey, 1)
+	sshCfg := &ssh.ClientConfig{
+		User: cfg.User,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			keys <- k
+			return errKeyFetched
+		},
+		Timeout: dialTimeout,
+	}
+	c, err := dial(ctx, cfg, sshCfg)
+	if err == nil {
+		c.Close()
+	}
+	select {
+	case key := <-keys:
+		return key, nil
+	default:
+		return nil, err
+	}
+}
+
+// CheckHostKey runs key through the check configured in cfg, as a
+// connection would, recording it under TrustOnFirstUse.
+func CheckHostKey(ctx context.Context, cfg Config, key ssh.PublicKey) error {
+	if cfg.Port <= 0 {
+		cfg.Port = 22
+	}
+	cb, _, err := hostKeyCallback(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	hostport := net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port))
+	addr, _ := net.ResolveTCPAddr("tcp", hostport)
+	if addr == nil {
+		addr = &net.TCPAddr{}
+	}
+	return cb(hostport, addr, key)
+}
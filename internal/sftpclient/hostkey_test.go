@@ -0,0 +1,164 @@
+package sftpclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"course-sync/internal/sftpclient/sftptest"
+)
+
+func serverKey(t *testing.T, srv *sftptest.Server) ssh.PublicKey {
+	t.Helper()
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(srv.HostKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func otherKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func knownHostsConfig(srv *sftptest.Server, files ...string) Config {
+	cfg := testConfig(srv)
+	cfg.HostKey = ""
+	cfg.KnownHosts = files
+	return cfg
+}
+
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(p, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestKnownHostsHashedAndSeveralKeyTypes(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, _ := ssh.NewPublicKey(&rsaKey.PublicKey)
+	host := knownhosts.Normalize(srv.Addr())
+	file := writeKnownHosts(t,
+		"# other servers",
+		knownhosts.Line([]string{"sftp.example.com"}, otherKey(t)),
+		knownhosts.Line([]string{knownhosts.HashHostname(host)}, rsaPub),
+		knownhosts.Line([]string{knownhosts.HashHostname(host)}, serverKey(t, srv)),
+	)
+
+	k := &knownHosts{files: []string{file}, ctx: context.Background()}
+	algos, err := k.algorithms(srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(algos, ssh.KeyAlgoED25519) || !slices.Contains(algos, ssh.KeyAlgoRSASHA256) {
+		t.Errorf("Expected the algorithms of both known keys, got %v", algos)
+	}
+
+	local, _ := testFile(t, 10)
+	if err := UploadFile(context.Background(), knownHostsConfig(srv, file), local, "f.xml"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+}
+
+func TestKnownHostsMismatch(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	file := writeKnownHosts(t, knownhosts.Line([]string{knownhosts.Normalize(srv.Addr())}, otherKey(t)))
+
+	local, _ := testFile(t, 10)
+	err := UploadFile(context.Background(), knownHostsConfig(srv, file), local, "f.xml")
+	if !errors.Is(err, ErrHostKeyMismatch) || retryable(err) {
+		t.Fatalf("Expected a permanent host key mismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), Fingerprint(serverKey(t, srv))) || !strings.Contains(err.Error(), file+":1") {
+		t.Errorf("Expected the offered fingerprint and the known_hosts line in %q", err)
+	}
+}
+
+func TestKnownHostsUnknownHost(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	file := writeKnownHosts(t, knownhosts.Line([]string{"sftp.example.com"}, serverKey(t, srv)))
+	local, _ := testFile(t, 10)
+	err := UploadFile(context.Background(), knownHostsConfig(srv, file), local, "f.xml")
+	if !errors.Is(err, ErrHostKeyUnknown) || retryable(err) || !strings.Contains(err.Error(), Fingerprint(serverKey(t, srv))) {
+		t.Errorf("Expected a permanent unknown host error with the fingerprint, got %v", err)
+	}
+}
+
+func TestKnownHostsTrustOnFirstUse(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	file := filepath.Join(t.TempDir(), "state", "known_hosts")
+	local, _ := testFile(t, 10)
+
+	cfg := knownHostsConfig(srv, file)
+	if err := UploadFile(context.Background(), cfg, local, "f.xml"); !errors.Is(err, os.ErrNotExist) || retryable(err) {
+		t.Fatalf("Expected a missing known_hosts file to fail without trust on first use, got %v", err)
+	}
+
+	cfg.TrustOnFirstUse = true
+	if err := UploadFile(context.Background(), cfg, local, "f.xml"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := knownhosts.Line([]string{knownhosts.Normalize(srv.Addr())}, serverKey(t, srv)) + "\n"; string(b) != want {
+		t.Errorf("known_hosts = %q, want %q", b, want)
+	}
+
+	// Once recorded, the key is checked like any other.
+	cfg.TrustOnFirstUse = false
+	if err := UploadFile(context.Background(), cfg, local, "f.xml"); err != nil {
+		t.Fatalf("UploadFile() with the recorded key: %v", err)
+	}
+	if err := CheckHostKey(context.Background(), cfg, otherKey(t)); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected a different key to be rejected after trust on first use, got %v", err)
+	}
+}
+
+func TestPinnedHostKeyMismatch(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	cfg := testConfig(srv)
+	cfg.HostKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(otherKey(t))))
+	local, _ := testFile(t, 10)
+	if err := UploadFile(context.Background(), cfg, local, "f.xml"); !errors.Is(err, ErrHostKeyMismatch) || retryable(err) {
+		t.Errorf("Expected a permanent host key mismatch, got %v", err)
+	}
+}
+
+func TestHostKey(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	key, err := HostKey(context.Background(), Config{Host: srv.Host, Port: srv.Port, User: "anyone"})
+	if err != nil {
+		t.Fatalf("HostKey() error: %v", err)
+	}
+	if Fingerprint(key) != Fingerprint(serverKey(t, srv)) {
+		t.Errorf("HostKey() = %s, want %s", Fingerprint(key), Fingerprint(serverKey(t, srv)))
+	}
+}
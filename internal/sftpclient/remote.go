@@ -0,0 +1,164 @@
+package sftpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"course-sync/internal/atomicfile"
+)
+
+// RemoteFile describes a file on the server.
+type RemoteFile struct {
+	// Path is the full remote path; Name its base name.
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+func remoteFile(p string, fi os.FileInfo) RemoteFile {
+	return RemoteFile{Path: p, Name: path.Base(p), Size: fi.Size(), ModTime: fi.ModTime(), IsDir: fi.IsDir()}
+}
+
+// Path resolves name against RemoteDir; absolute names are used as is.
+func (s *Session) Path(name string) string {
+	if path.IsAbs(name) {
+		return path.Clean(name)
+	}
+	return path.Join(s.cfg.RemoteDir, name)
+}
+
+// ready reconnects a session whose connection was dropped after an error.
+func (s *Session) ready(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.client == nil {
+		return s.connect(ctx)
+	}
+	return nil
+}
+
+// failed drops the connection after an error that may have broken it, so
+// the next operation reconnects.
+func (s *Session) failed(err error) error {
+	if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, os.ErrPermission) {
+		s.Close()
+	}
+	return err
+}
+
+// List returns the entries of dir (RemoteDir when empty), sorted by name.
+func (s *Session) List(ctx context.Context, dir string) ([]RemoteFile, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+	dir = s.Path(dir)
+	infos, err := s.client.ReadDir(dir)
+	if err != nil {
+		return nil, s.failed(fmt.Errorf("sftp: list %s: %w", dir, err))
+	}
+	files := make([]RemoteFile, len(infos))
+	for i, fi := range infos {
+		files[i] = remoteFile(path.Join(dir, fi.Name()), fi)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// Stat describes the remote file name. A missing file is reported with an
+// error wrapping os.ErrNotExist.
+func (s *Session) Stat(ctx context.Context, name string) (RemoteFile, error) {
+	if err := s.ready(ctx); err != nil {
+		return RemoteFile{}, err
+	}
+	p := s.Path(name)
+	fi, err := s.client.Stat(p)
+	if err != nil {
+		return RemoteFile{}, s.failed(fmt.Errorf("sftp: stat %s: %w", p, err))
+	}
+	return remoteFile(p, fi), nil
+}
+
+// Download copies the remote file name to localPath and returns its size.
+// The local file is written under a temporary name and renamed once
+// complete, so an interrupted download leaves no truncated file behind.
+func (s *Session) Download(ctx context.Context, name, localPath string) (n int64, err error) {
+	if err := s.ready(ctx); err != nil {
+		return 0, err
+	}
+	p := s.Path(name)
+	src, err := s.client.Open(p)
+	if err != nil {
+		return 0, s.failed(fmt.Errorf("sftp: open %s: %w", p, err))
+	}
+	defer src.Close()
+
+	var copyErr error
+	err = atomicfile.Write(localPath, 0o600, func(w io.Writer) error {
+		n, copyErr = io.Copy(w, src)
+		return copyErr
+	})
+	switch {
+	case copyErr != nil:
+		return n, s.failed(fmt.Errorf("sftp: download %s: %w", p, copyErr))
+	case err != nil:
+		return n, fmt.Errorf("sftp: download %s: %w", p, err)
+	}
+	return n, nil
+}
+
+// Remove deletes the remote file name. Directories are refused.
+func (s *Session) Remove(ctx context.Context, name string) error {
+	f, err := s.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	if f.IsDir {
+		return fmt.Errorf("sftp: remove %s: is a directory", f.Path)
+	}
+	if err := s.client.Remove(f.Path); err != nil {
+		return s.failed(fmt.Errorf("sftp: remove %s: %w", f.Path, err))
+	}
+	return nil
+}
+
+// ErrNotConsumed is returned, wrapped, by WaitConsumed when the file is
+// still there when ctx is done.
+var ErrNotConsumed = errors.New("sftp: file not consumed")
+
+// WaitConsumed polls every interval until the remote file name is gone,
+// which is how Eightfold signals that it picked the file up (it removes or
+// moves it). It gives up when ctx is done. A dropped connection is
+// re-established on the next poll.
+func (s *Session) WaitConsumed(ctx context.Context, name string, interval time.Duration) error {
+	p := s.Path(name)
+	var lastErr error
+	for {
+		_, err := s.Stat(ctx, name)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return nil
+		case err != nil && !retryable(err):
+			return err
+		case err != nil && ctx.Err() == nil:
+			// Keep polling through network trouble; report it on timeout.
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%w: %s (last error: %v)", ErrNotConsumed, p, lastErr)
+			}
+			return fmt.Errorf("%w: %s is still there", ErrNotConsumed, p)
+		case <-time.After(interval):
+		}
+	}
+}
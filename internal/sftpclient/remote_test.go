@@ -0,0 +1,106 @@
+package sftpclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"course-sync/internal/sftpclient/sftptest"
+)
+
+func dialTest(t *testing.T, srv *sftptest.Server) *Session {
+	t.Helper()
+	s, err := Dial(context.Background(), testConfig(srv))
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSessionListStatDownloadRemove(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	for name, content := range map[string]string{
+		"/inbound/b.xml":       "<b/>",
+		"/inbound/a.xml":       "<a/>",
+		"/inbound/old/c.xml":   "<c/>",
+		"/outbound/report.csv": "x,y\n",
+	} {
+		if err := srv.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := dialTest(t, srv)
+	ctx := context.Background()
+
+	files, err := s.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if len(files) != 3 || names[0] != "a.xml" || names[1] != "b.xml" || !files[2].IsDir || files[0].Path != "/inbound/a.xml" {
+		t.Errorf("Unexpected listing %+v", files)
+	}
+
+	f, err := s.Stat(ctx, "a.xml")
+	if err != nil || f.Size != 4 || f.IsDir {
+		t.Errorf("Stat(a.xml) = %+v, %v", f, err)
+	}
+	if _, err := s.Stat(ctx, "missing.xml"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for a missing file, got %v", err)
+	}
+
+	local := filepath.Join(t.TempDir(), "report.csv")
+	if n, err := s.Download(ctx, "/outbound/report.csv", local); err != nil || n != 4 {
+		t.Fatalf("Download() = %d, %v", n, err)
+	}
+	if b, _ := os.ReadFile(local); string(b) != "x,y\n" {
+		t.Errorf("Downloaded %q", b)
+	}
+	if _, err := s.Download(ctx, "missing.xml", filepath.Join(t.TempDir(), "m.xml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist downloading a missing file, got %v", err)
+	}
+
+	if err := s.Remove(ctx, "old"); err == nil {
+		t.Error("Expected Remove to refuse a directory")
+	}
+	if err := s.Remove(ctx, "a.xml"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if _, err := srv.Stat("/inbound/a.xml"); err == nil {
+		t.Error("Expected a.xml to be removed")
+	}
+	if n := srv.Sessions(); n != 1 {
+		t.Errorf("Expected the operations to share 1 session, got %d", n)
+	}
+}
+
+func TestSessionWaitConsumed(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	if err := srv.WriteFile("/inbound/ef_course_add.xml", []byte("<courses/>")); err != nil {
+		t.Fatal(err)
+	}
+	s := dialTest(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.WaitConsumed(ctx, "ef_course_add.xml", 10*time.Millisecond); !errors.Is(err, ErrNotConsumed) {
+		t.Fatalf("Expected ErrNotConsumed on timeout, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.Remove("/inbound/ef_course_add.xml")
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.WaitConsumed(ctx, "ef_course_add.xml", 10*time.Millisecond); err != nil {
+		t.Fatalf("WaitConsumed() error: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package sftpclient
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/pkg/sftp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/ssh"
+
+	"course-sync/internal/logging"
+	"course-sync/internal/tracing"
+)
+
+// Session is one SSH connection with an SFTP channel, reused for several
+// uploads. A connection lost mid-upload is re-established on the next
+// attempt. A Session is not safe for concurrent use.
+type Session struct {
+	cfg    Config
+	sshCfg *ssh.ClientConfig
+
+	ssh    *ssh.Client
+	client *sftp.Client
+}
+
+// Dial validates cfg, connects and makes sure RemoteDir exists. The caller
+// must Close the session.
+func Dial(ctx context.Context, cfg Config) (*Session, error) {
+	cfg, sshCfg, err := clientConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{cfg: cfg, sshCfg: sshCfg}
+	log := logging.FromContext(ctx).With("component", "sftp", "host", cfg.Host)
+	if err := s.retry(ctx, log, trace.SpanFromContext(ctx), s.connect); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the connection.
+func (s *Session) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	s.client.Close()
+	err := s.ssh.Close()
+	s.ssh, s.client = nil, nil
+	return err
+}
+
+func (s *Session) connect(ctx context.Context) error {
+	sshClient, err := dial(ctx, s.cfg, s.sshCfg)
+	if err != nil {
+		return err
+	}
+	sftpCli, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return fmt.Errorf("sftp: new client: %w", err)
+	}
+
+	// Dir: intentar crear; si no se puede, validar que exista.
+	if s.cfg.RemoteDir != "/" {
+		if err := sftpCli.MkdirAll(s.cfg.RemoteDir); err != nil {
+			// si no deja crear, al menos que exista
+			if _, statErr := sftpCli.Stat(s.cfg.RemoteDir); statErr != nil {
+				sftpCli.Close()
+				sshClient.Close()
+				return fmt.Errorf("sftp: remote dir not accessible %s: mkdirErr=%v statErr=%v", s.cfg.RemoteDir, err, statErr)
+			}
+		}
+	}
+	s.ssh, s.client = sshClient, sftpCli
+	return nil
+}
+
+// Upload uploads localPath to RemoteDir/remoteFileName. The data goes to
+// remoteFileName+PartSuffix first; after its size (and, with Verify, its
+// checksum) matches the local file it is renamed into place. An attempt cut
+// short by a network failure is retried over a new connection, resuming the
+// partial file.
+func (s *Session) Upload(ctx context.Context, localPath, remoteFileName string) error {
+	_, err := s.upload(ctx, localPath, remoteFileName)
+	return err
+}
+
+// upload is Upload, also returning the size and checksum of the file.
+func (s *Session) upload(ctx context.Context, localPath, remoteFileName string) (local localFile, err error) {
+	remotePath := path.Join(s.cfg.RemoteDir, remoteFileName)
+	ctx, span := tracer.Start(ctx, "sftp.upload", trace.WithAttributes(
+		attribute.String("server.address", s.cfg.Host),
+		attribute.String("file.path", localPath),
+		attribute.String("sftp.remote_path", remotePath),
+	))
+	uploadStart := time.Now()
+	var transferred int64
+	defer func() {
+		span.SetAttributes(attribute.Int64("bytes", transferred))
+		tracing.End(span, &err)
+		uploadedBytes.Add(float64(transferred))
+		uploadDuration.Observe(time.Since(uploadStart).Seconds())
+		if err != nil {
+			uploadsTotal.Inc("error")
+		} else {
+			uploadsTotal.Inc("ok")
+		}
+	}()
+
+	local, err = inspectLocal(localPath)
+	if err != nil {
+		return local, err
+	}
+	log := logging.FromContext(ctx).With("component", "sftp", "remote", remotePath)
+	err = s.retry(ctx, log, span, func(ctx context.Context) error {
+		if s.client == nil {
+			if err := s.connect(ctx); err != nil {
+				return err
+			}
+		}
+		n, err := s.put(ctx, local, remotePath)
+		transferred += n
+		if err != nil && retryable(err) {
+			// The connection may be gone; the next attempt redials.
+			s.Close()
+		}
+		return err
+	})
+	return local, err
+}
+
+// retry calls fn up to cfg.Attempts times while it fails with a retryable
+// error, waiting retryDelay, 2*retryDelay, ... in between.
+func (s *Session) retry(ctx context.Context, log *slog.Logger, span trace.Span, fn func(context.Context) error) error {
+	attempts := s.cfg.Attempts
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		delay := time.Duration(attempt) * retryDelay
+		log.Warn("sftp attempt failed; retrying", "attempt", attempt, "attempts", attempts, "wait", delay, logging.Err(err))
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("reason", err.Error()),
+		))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("sftp: canceled while retrying: %w", ctx.Err())
+		}
+	}
+}
+
+// File is one file of a batch upload.
+type File struct {
+	LocalPath  string
+	RemoteName string
+}
+
+// Result is the outcome of uploading one File.
+type Result struct {
+	File
+	// RemotePath is where the file was put; SHA256 the hex checksum of the
+	// local file, which the server's copy was checked against in size
+	// (and content, with Verify).
+	RemotePath string
+	Bytes      int64
+	SHA256     string
+	Duration   time.Duration
+	// Finished is when the file was in place under its final name.
+	Finished time.Time
+	// Err is nil when the file was uploaded, and ErrSkipped when it was not
+	// tried because an earlier file failed.
+	Err error
+}
+
+// ErrSkipped is the Result.Err of the files after a failed one.
+var ErrSkipped = errors.New("sftp: skipped after an earlier failure")
+
+// UploadAll uploads files in order and stops at the first failure, so the
+// receiving side never sees a later file (e.g. deletes) without the earlier
+// ones. It returns a result per file and the first error.
+func (s *Session) UploadAll(ctx context.Context, files []File) ([]Result, error) {
+	results := make([]Result, len(files))
+	var firstErr error
+	for i, f := range files {
+		results[i].File = f
+		results[i].RemotePath = path.Join(s.cfg.RemoteDir, f.RemoteName)
+		if firstErr != nil {
+			results[i].Err = ErrSkipped
+			continue
+		}
+		start := time.Now()
+		local, err := s.upload(ctx, f.LocalPath, f.RemoteName)
+		results[i].Bytes, results[i].Duration, results[i].Err = local.size, time.Since(start), err
+		if err == nil {
+			results[i].SHA256, results[i].Finished = hex.EncodeToString(local.sum), time.Now()
+		}
+		if err != nil {
+			firstErr = fmt.Errorf("%s: %w", f.LocalPath, err)
+		}
+	}
+	return results, firstErr
+}
+
+// UploadFiles connects, uploads files in order over the one connection (see
+// UploadAll) and disconnects. When the connection cannot be made, the first
+// result carries the error and the rest ErrSkipped.
+func UploadFiles(ctx context.Context, cfg Config, files []File) ([]Result, error) {
+	s, err := Dial(ctx, cfg)
+	if err != nil {
+		results := make([]Result, len(files))
+		for i, f := range files {
+			results[i] = Result{File: f, RemotePath: path.Join(cfg.RemoteDir, f.RemoteName), Err: ErrSkipped}
+		}
+		if len(results) > 0 {
+			results[0].Err = err
+		}
+		return results, err
+	}
+	defer s.Close()
+	return s.UploadAll(ctx, files)
+}
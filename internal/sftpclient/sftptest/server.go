@@ -0,0 +1,375 @@
+// Package sftptest provides an in-process SFTP server for end-to-end tests:
+// password auth, a generated ed25519 host key and an in-memory file system,
+// with scripted connection drops to exercise retries and resume.
+//
+//	srv := sftptest.NewServer(t)
+//	srv.DropAfter(64 << 10) // cut the connection after 64 KiB written
+//	cfg := sftpclient.Config{Host: srv.Host, Port: srv.Port, User: srv.User, Pass: srv.Password,
+//		HostKey: srv.HostKey, RemoteDir: "/inbound"}
+package sftptest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Server is an SFTP server listening on localhost. Its fields configure the
+// behaviour and may be changed between connections.
+type Server struct {
+	Host string
+	Port int
+	// HostKey is the server's public key as "<type> <base64>", the format of
+	// sftp.host_key.
+	HostKey string
+	// User and Password are the credentials accepted.
+	User     string
+	Password string
+
+	signer   ssh.Signer
+	listener net.Listener
+	handlers sftp.Handlers
+
+	mu         sync.Mutex
+	conns      map[net.Conn]bool
+	dropAfter  int64
+	written    int64
+	sessions   int
+	fileWrites map[string]int
+	refused    map[string]bool
+	renamed    []string
+	wg         sync.WaitGroup
+}
+
+// NewServer starts a server with an empty file system; it is closed when
+// the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	s := &Server{
+		Host:       addr.IP.String(),
+		Port:       addr.Port,
+		HostKey:    strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+		User:       "emulator",
+		Password:   "emulator-password",
+		signer:     signer,
+		listener:   ln,
+		handlers:   sftp.InMemHandler(),
+		conns:      map[net.Conn]bool{},
+		fileWrites: map[string]int{},
+		refused:    map[string]bool{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Addr is host:port.
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// Close stops the server and drops open connections.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.dropConns()
+	s.wg.Wait()
+}
+
+// DropAfter cuts every open connection once n more bytes have been
+// written to files, as a network failure mid-upload would. 0 disables it.
+func (s *Server) DropAfter(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropAfter, s.written = n, 0
+}
+
+// Refuse makes opening path for writing fail with a permission error, as
+// a server that does not accept some file names would.
+func (s *Server) Refuse(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refused[path] = true
+}
+
+// Sessions is the number of SFTP sessions opened so far.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions
+}
+
+// Writes is the number of times path was opened for writing.
+func (s *Server) Writes(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fileWrites[path]
+}
+
+// Renamed lists the targets of renames, in order: the files an upload put
+// in place.
+func (s *Server) Renamed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.renamed...)
+}
+
+// ReadFile returns the content of a file.
+func (s *Server) ReadFile(path string) ([]byte, error) {
+	req := sftp.NewRequest("Get", path)
+	req.Flags = 1 // SSH_FXF_READ
+	r, err := s.handlers.FileGet.Fileread(req)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := s.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, fi.Size())
+	if _, err := r.ReadAt(b, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return b, nil
+}
+
+// WriteFile creates or replaces a file, creating its directories.
+func (s *Server) WriteFile(path string, b []byte) error {
+	var dirs []string
+	for dir := parentDir(path); dir != "/"; dir = parentDir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if _, err := s.Stat(dirs[i]); err != nil {
+			if err := s.handlers.FileCmd.Filecmd(sftp.NewRequest("Mkdir", dirs[i])); err != nil {
+				return err
+			}
+		}
+	}
+	req := sftp.NewRequest("Put", path)
+	req.Flags = 0x02 | 0x08 | 0x10 // SSH_FXF_WRITE|CREAT|TRUNC
+	w, err := s.handlers.FilePut.Filewrite(req)
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteAt(b, 0)
+	return err
+}
+
+// Remove deletes a file, as the receiving side does once it has consumed it.
+func (s *Server) Remove(path string) error {
+	return s.handlers.FileCmd.Filecmd(sftp.NewRequest("Remove", path))
+}
+
+// Stat returns the file info of path.
+func (s *Server) Stat(path string) (os.FileInfo, error) {
+	l, err := s.handlers.FileList.Filelist(sftp.NewRequest("Stat", path))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 1)
+	if n, err := l.ListAt(infos, 0); n == 0 {
+		if err == nil {
+			err = os.ErrNotExist
+		}
+		return nil, err
+	}
+	return infos[0], nil
+}
+
+// List returns the names in dir.
+func (s *Server) List(dir string) ([]string, error) {
+	l, err := s.handlers.FileList.Filelist(sftp.NewRequest("List", dir))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	infos := make([]os.FileInfo, 64)
+	for off := int64(0); ; {
+		n, err := l.ListAt(infos, off)
+		for _, fi := range infos[:n] {
+			names = append(names, fi.Name())
+		}
+		off += int64(n)
+		if err != nil || n == 0 {
+			break
+		}
+	}
+	return names, nil
+}
+
+func parentDir(p string) string {
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == s.User && string(pass) == s.Password {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+	}
+	cfg.AddHostKey(s.signer)
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, reqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range reqs {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					s.mu.Lock()
+					s.sessions++
+					s.mu.Unlock()
+					srv := sftp.NewRequestServer(ch, s.wrap())
+					srv.Serve()
+					srv.Close()
+					return
+				}
+			}
+		}()
+	}
+}
+
+// wrap counts writes and renames and applies DropAfter and Refuse.
+func (s *Server) wrap() sftp.Handlers {
+	h := s.handlers
+	h.FileCmd = cmder{s}
+	h.FilePut = putFunc(func(r *sftp.Request) (io.WriterAt, error) {
+		s.mu.Lock()
+		refused := s.refused[r.Filepath]
+		s.mu.Unlock()
+		if refused {
+			return nil, os.ErrPermission
+		}
+		w, err := s.handlers.FilePut.Filewrite(r)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.fileWrites[r.Filepath]++
+		s.mu.Unlock()
+		return writerAt{w, s}, nil
+	})
+	return h
+}
+
+// cmder records renames; it keeps the POSIX rename extension of the
+// in-memory handler.
+type cmder struct{ s *Server }
+
+func (c cmder) Filecmd(r *sftp.Request) error {
+	return c.renamed(r, c.s.handlers.FileCmd.Filecmd(r))
+}
+
+func (c cmder) PosixRename(r *sftp.Request) error {
+	return c.renamed(r, c.s.handlers.FileCmd.(sftp.PosixRenameFileCmder).PosixRename(r))
+}
+
+func (c cmder) renamed(r *sftp.Request, err error) error {
+	if err == nil && (r.Method == "Rename" || r.Method == "PosixRename") {
+		c.s.mu.Lock()
+		c.s.renamed = append(c.s.renamed, r.Target)
+		c.s.mu.Unlock()
+	}
+	return err
+}
+
+type putFunc func(*sftp.Request) (io.WriterAt, error)
+
+func (f putFunc) Filewrite(r *sftp.Request) (io.WriterAt, error) { return f(r) }
+
+type writerAt struct {
+	io.WriterAt
+	s *Server
+}
+
+var errDropped = errors.New("sftptest: connection dropped")
+
+func (w writerAt) WriteAt(b []byte, off int64) (int, error) {
+	w.s.mu.Lock()
+	drop := w.s.dropAfter > 0 && w.s.written+int64(len(b)) > w.s.dropAfter
+	if drop {
+		b = b[:w.s.dropAfter-w.s.written]
+		w.s.dropAfter = 0
+	}
+	w.s.written += int64(len(b))
+	w.s.mu.Unlock()
+
+	n, err := w.WriterAt.WriteAt(b, off)
+	if drop {
+		w.s.dropConns()
+		return n, errDropped
+	}
+	return n, err
+}
+
+func (s *Server) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
@@ -2,22 +2,31 @@ package sftpclient
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"crypto/subtle"
-	"encoding/base64"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"net"
+	"math"
 	"os"
 	"path"
 	"strings"
 	"time"
 
 	"github.com/pkg/sftp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/ssh"
+
+	"course-sync/internal/logging"
+	"course-sync/internal/metrics"
+	"course-sync/internal/tracing"
 )
 
+var tracer = otel.Tracer("course-sync/internal/sftpclient")
+
 type Config struct {
 	Host                  string
 	Port                  int
@@ -28,17 +37,96 @@ type Config struct {
 
 	// Host key pinning: "ssh-rsa AAAA..." (SIN hostname). Opcional si InsecureIgnoreHostKey=true.
 	HostKey string
+	// KnownHosts are OpenSSH known_hosts files to check the server's key
+	// against when HostKey is not set.
+	KnownHosts []string
+	// TrustOnFirstUse accepts the key of a server not in KnownHosts yet and
+	// appends it to the first file; later keys for it must match.
+	TrustOnFirstUse bool
 
 	KeyPath       string
 	KeyPassphrase string
+
+	// Checksum writes a <name>.sha256 file (sha256sum format) next to each
+	// upload, for the receiving side to check. It is put in place before
+	// the upload is renamed to its final name; an upload whose checksum
+	// cannot be written fails.
+	Checksum bool
+	// Verify reads each upload back and compares its SHA-256 with the local
+	// file before renaming it into place.
+	Verify bool
+	// Attempts is the number of tries per file (default 3); each retry
+	// resumes the partial upload.
+	Attempts int
 }
 
+var (
+	uploadedBytes = metrics.NewCounter("course_sync_sftp_uploaded_bytes_total",
+		"Bytes written to the SFTP server, including failed uploads.")
+	uploadsTotal = metrics.NewCounter("course_sync_sftp_uploads_total",
+		"SFTP uploads by result (ok or error).", "result")
+	uploadDuration = metrics.NewHistogram("course_sync_sftp_upload_duration_seconds",
+		"Duration of SFTP uploads, including retries and reconnects.", metrics.TransferBuckets)
+)
+
+// PartSuffix is appended to the remote name while a file is uploaded; the
+// file is renamed to its final name only once complete, so the inbound
+// directory never holds a truncated file under the name Eightfold ingests.
+const PartSuffix = ".part"
+
+// ChecksumSuffix is appended to the remote name for the checksum sidecar.
+const ChecksumSuffix = ".sha256"
+
+const (
+	defaultAttempts = 3
+	dialTimeout     = 20 * time.Second
+	// resumeCheck is how much of the end of a partial upload is compared
+	// with the local file before resuming it.
+	resumeCheck = 64 << 10
+)
+
+// retryDelay is the wait before the second attempt; it grows linearly.
+var retryDelay = 2 * time.Second
+
+// UploadFile uploads localPath to RemoteDir/remoteFileName over a
+// connection of its own; see Session.Upload.
 func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileName string) error {
+	s, err := Dial(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.Upload(ctx, localPath, remoteFileName)
+}
+
+// localFile is what is known about the file being uploaded.
+type localFile struct {
+	path string
+	size int64
+	sum  []byte // SHA-256
+}
+
+func inspectLocal(localPath string) (localFile, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return localFile{}, permanent(fmt.Errorf("sftp: open local file: %w", err))
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return localFile{}, permanent(fmt.Errorf("sftp: read local file: %w", err))
+	}
+	return localFile{path: localPath, size: n, sum: h.Sum(nil)}, nil
+}
+
+// clientConfig validates cfg, fills in defaults and builds the SSH config.
+func clientConfig(ctx context.Context, cfg Config) (Config, *ssh.ClientConfig, error) {
 	if cfg.Host == "" || cfg.User == "" {
-		return fmt.Errorf("sftp: missing SFTP_HOST / SFTP_USER")
+		return cfg, nil, permanent(fmt.Errorf("sftp: missing SFTP_HOST / SFTP_USER"))
 	}
 	if cfg.Pass == "" && cfg.KeyPath == "" {
-		return fmt.Errorf("sftp: no auth method configured (set SFTP_KEY_PATH or SFTP_PASS)")
+		return cfg, nil, permanent(fmt.Errorf("sftp: no auth method configured (set SFTP_KEY_PATH or SFTP_PASS)"))
 	}
 	if cfg.Port <= 0 {
 		cfg.Port = 22
@@ -47,31 +135,9 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 		cfg.RemoteDir = "/"
 	}
 
-	// Host key callback
-	var hostKeyCb ssh.HostKeyCallback
-	if cfg.InsecureIgnoreHostKey {
-		hostKeyCb = ssh.InsecureIgnoreHostKey()
-	} else {
-		if strings.TrimSpace(cfg.HostKey) == "" {
-			return fmt.Errorf("sftp: host key check enabled but SFTP_HOST_KEY not set (set SFTP_HOST_KEY or set SFTP_INSECURE_IGNORE_HOSTKEY=true)")
-		}
-		expectedType, expectedB64, err := splitKey(cfg.HostKey)
-		if err != nil {
-			return fmt.Errorf("sftp: invalid SFTP_HOST_KEY: %w", err)
-		}
-		expectedRaw, err := base64.StdEncoding.DecodeString(expectedB64)
-		if err != nil {
-			return fmt.Errorf("sftp: invalid SFTP_HOST_KEY base64: %w", err)
-		}
-		hostKeyCb = func(hostname string, remoteAddr net.Addr, key ssh.PublicKey) error {
-			if key.Type() != expectedType {
-				return fmt.Errorf("sftp: host key mismatch for %s: type %s != %s", remoteAddr.String(), key.Type(), expectedType)
-			}
-			if subtle.ConstantTimeCompare(key.Marshal(), expectedRaw) != 1 {
-				return fmt.Errorf("sftp: host key mismatch for %s", remoteAddr.String())
-			}
-			return nil
-		}
+	hostKeyCb, hostKeyAlgos, err := hostKeyCallback(ctx, cfg)
+	if err != nil {
+		return cfg, nil, err
 	}
 
 	// Auth
@@ -80,7 +146,7 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 	if cfg.KeyPath != "" {
 		keyBytes, err := os.ReadFile(cfg.KeyPath)
 		if err != nil {
-			return fmt.Errorf("sftp: read key: %w", err)
+			return cfg, nil, permanent(fmt.Errorf("sftp: read key: %w", err))
 		}
 
 		var signer ssh.Signer
@@ -90,7 +156,7 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 			signer, err = ssh.ParsePrivateKey(keyBytes)
 		}
 		if err != nil {
-			return fmt.Errorf("sftp: parse key: %w", err)
+			return cfg, nil, permanent(fmt.Errorf("sftp: parse key: %w", err))
 		}
 		auth = append(auth, ssh.PublicKeys(signer))
 	}
@@ -99,11 +165,12 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 		auth = append(auth, ssh.Password(cfg.Pass))
 	}
 
-	sshCfg := &ssh.ClientConfig{
-		User:            cfg.User,
-		Auth:            auth,
-		HostKeyCallback: hostKeyCb,
-		Timeout:         20 * time.Second,
+	return cfg, &ssh.ClientConfig{
+		User:              cfg.User,
+		Auth:              auth,
+		HostKeyCallback:   hostKeyCb,
+		HostKeyAlgorithms: hostKeyAlgos,
+		Timeout:           dialTimeout,
 		// Habilitar compresión para mejorar velocidad
 		Config: ssh.Config{
 			Ciphers: []string{
@@ -114,8 +181,11 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 				"aes256-ctr",
 			},
 		},
-	}
+	}, nil
+}
 
+// dial connects to the server, giving up when ctx is done.
+func dial(ctx context.Context, cfg Config, sshCfg *ssh.ClientConfig) (*ssh.Client, error) {
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 
 	type dialRes struct {
@@ -128,48 +198,59 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 		ch <- dialRes{client: c, err: err}
 	}()
 
-	var sshClient *ssh.Client
+	_, dialSpan := tracer.Start(ctx, "sftp.dial", trace.WithAttributes(attribute.String("server.address", addr)))
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("sftp: dial canceled: %w", ctx.Err())
+		err := fmt.Errorf("sftp: dial canceled: %w", ctx.Err())
+		tracing.End(dialSpan, &err)
+		return nil, err
 	case r := <-ch:
 		if r.err != nil {
-			return fmt.Errorf("sftp: dial error: %w", r.err)
+			err := fmt.Errorf("sftp: dial error: %w", r.err)
+			if errors.Is(r.err, ErrHostKeyMismatch) || errors.Is(r.err, ErrHostKeyUnknown) || errors.Is(r.err, errNoHostKeyCheck) || strings.Contains(r.err.Error(), "unable to authenticate") {
+				err = permanent(err)
+			}
+			tracing.End(dialSpan, &err)
+			return nil, err
 		}
-		sshClient = r.client
+		dialSpan.End()
+		return r.client, nil
 	}
-	defer sshClient.Close()
-
-	sftpCli, err := sftp.NewClient(sshClient)
-	if err != nil {
-		return fmt.Errorf("sftp: new client: %w", err)
-	}
-	defer sftpCli.Close()
+}
 
-	// Dir: intentar crear; si no se puede, validar que exista.
-	if cfg.RemoteDir != "/" {
-		if err := sftpCli.MkdirAll(cfg.RemoteDir); err != nil {
-			// si no deja crear, al menos que exista
-			if _, statErr := sftpCli.Stat(cfg.RemoteDir); statErr != nil {
-				return fmt.Errorf("sftp: remote dir not accessible %s: mkdirErr=%v statErr=%v", cfg.RemoteDir, err, statErr)
-			}
-		}
+// put makes one upload attempt over the session's connection and returns
+// the bytes it wrote.
+func (s *Session) put(ctx context.Context, local localFile, remotePath string) (transferred int64, err error) {
+	sftpCli, cfg := s.client, s.cfg
+	log := logging.FromContext(ctx).With("component", "sftp", "remote", remotePath)
+	partPath := remotePath + PartSuffix
+	offset := resumeOffset(sftpCli, partPath, local)
+	if offset > 0 {
+		log.Info("resuming upload", "offset", offset, "size", local.size)
 	}
 
-	src, err := os.Open(localPath)
+	src, err := os.Open(local.path)
 	if err != nil {
-		return fmt.Errorf("sftp: open local file: %w", err)
+		return 0, permanent(fmt.Errorf("sftp: open local file: %w", err))
 	}
 	defer src.Close()
 
-	remotePath := path.Join(cfg.RemoteDir, remoteFileName)
-
 	// IMPORTANTE: abrir WRITE-ONLY (evita SSH_FX_OP_UNSUPPORTED por READ flag)
-	dst, err := sftpCli.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	dst, err := sftpCli.OpenFile(partPath, flags)
 	if err != nil {
-		return fmt.Errorf("sftp: create remote file %s: %w", remotePath, err)
+		return 0, fmt.Errorf("sftp: create remote file %s: %w", partPath, err)
 	}
 	defer dst.Close()
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("sftp: seek local file: %w", err)
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("sftp: seek remote file: %w", err)
+	}
 
 	// Usar buffer grande para mejorar rendimiento
 	bufSize := 1024 * 1024 // 1MB buffer
@@ -178,30 +259,22 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 	// Crear un escritor bufferizado para mejorar rendimiento
 	bufWriter := bufio.NewWriterSize(dst, bufSize)
 
-	// Obtener tamaño del archivo para reportar progreso
-	fileInfo, err := src.Stat()
-	if err != nil {
-		return fmt.Errorf("sftp: get file size: %w", err)
-	}
-	totalSize := fileInfo.Size()
-
 	// Iniciar tiempo para calcular velocidad
 	startTime := time.Now()
-	transferred := int64(0)
 	lastReport := time.Now()
 
 	// Copiar con buffer grande y reportar progreso
 	for {
 		n, err := src.Read(buf)
 		if err != nil && err != io.EOF {
-			return fmt.Errorf("sftp: read error: %w", err)
+			return transferred, fmt.Errorf("sftp: read error: %w", err)
 		}
 		if n == 0 {
 			break
 		}
 
 		if _, err := bufWriter.Write(buf[:n]); err != nil {
-			return fmt.Errorf("sftp: write error: %w", err)
+			return transferred, fmt.Errorf("sftp: write error: %w", err)
 		}
 
 		transferred += int64(n)
@@ -210,20 +283,152 @@ func UploadFile(ctx context.Context, cfg Config, localPath string, remoteFileNam
 		if time.Since(lastReport) > 3*time.Second {
 			elapsed := time.Since(startTime).Seconds()
 			speed := float64(transferred) / elapsed / 1024 / 1024 // MB/s
-			percent := float64(transferred) * 100 / float64(totalSize)
-			log.Printf("SFTP: Transferido %.2f%% (%.2f MB/s)", percent, speed)
+			percent := float64(offset+transferred) * 100 / float64(local.size)
+			log.Info("upload progress", "percent", math.Round(percent*100)/100, "mb_per_s", math.Round(speed*100)/100)
 			lastReport = time.Now()
 		}
 	}
 
 	// Asegurar que todos los datos se escriban
 	if err := bufWriter.Flush(); err != nil {
-		return fmt.Errorf("sftp: flush error: %w", err)
+		return transferred, fmt.Errorf("sftp: flush error: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return transferred, fmt.Errorf("sftp: close remote file %s: %w", partPath, err)
+	}
+
+	if err := verifyUpload(sftpCli, cfg, partPath, local); err != nil {
+		// Start over next time rather than resume a bad file.
+		_ = sftpCli.Remove(partPath)
+		return transferred, err
+	}
+	// The checksum goes in place first, so that the new file never shows up
+	// next to the checksum of the old one.
+	if cfg.Checksum {
+		if err := writeChecksum(sftpCli, remotePath, local); err != nil {
+			return transferred, err
+		}
 	}
+	if err := rename(sftpCli, partPath, remotePath); err != nil {
+		return transferred, err
+	}
+	return transferred, nil
+}
+
+// resumeOffset returns where to resume a partial upload: its size, if it is
+// not larger than the local file and its tail matches the local file at the
+// same offset (so it is a prefix of this file, not of an older version).
+func resumeOffset(c *sftp.Client, partPath string, local localFile) int64 {
+	fi, err := c.Stat(partPath)
+	if err != nil || fi.Size() == 0 || fi.Size() > local.size {
+		return 0
+	}
+	size := fi.Size()
+	n := min(size, resumeCheck)
 
+	remote, err := c.Open(partPath)
+	if err != nil {
+		return 0
+	}
+	defer remote.Close()
+	got := make([]byte, n)
+	if _, err := remote.ReadAt(got, size-n); err != nil && !errors.Is(err, io.EOF) {
+		return 0
+	}
+	f, err := os.Open(local.path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	want := make([]byte, n)
+	if _, err := f.ReadAt(want, size-n); err != nil {
+		return 0
+	}
+	if !bytes.Equal(got, want) {
+		return 0
+	}
+	return size
+}
+
+// verifyUpload checks the size of the uploaded file and, with cfg.Verify,
+// reads it back to compare checksums.
+func verifyUpload(c *sftp.Client, cfg Config, remotePath string, local localFile) error {
+	fi, err := c.Stat(remotePath)
+	if err != nil {
+		return fmt.Errorf("sftp: stat %s: %w", remotePath, err)
+	}
+	if fi.Size() != local.size {
+		return fmt.Errorf("sftp: %s has %d bytes, expected %d", remotePath, fi.Size(), local.size)
+	}
+	if !cfg.Verify {
+		return nil
+	}
+	f, err := c.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("sftp: open %s to verify: %w", remotePath, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := f.WriteTo(h); err != nil {
+		return fmt.Errorf("sftp: read %s to verify: %w", remotePath, err)
+	}
+	if !bytes.Equal(h.Sum(nil), local.sum) {
+		return fmt.Errorf("sftp: %s: checksum mismatch after upload", remotePath)
+	}
+	return nil
+}
+
+// rename moves the finished upload into place, replacing an older file.
+// Plain SFTP rename fails when the target exists, so the POSIX rename
+// extension is used when the server has it.
+func rename(c *sftp.Client, from, to string) error {
+	if _, ok := c.HasExtension("posix-rename@openssh.com"); ok {
+		if err := c.PosixRename(from, to); err != nil {
+			return fmt.Errorf("sftp: rename %s: %w", from, err)
+		}
+		return nil
+	}
+	if err := c.Remove(to); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("sftp: replace %s: %w", to, err)
+	}
+	if err := c.Rename(from, to); err != nil {
+		return fmt.Errorf("sftp: rename %s: %w", from, err)
+	}
 	return nil
 }
 
+// writeChecksum writes remotePath+ChecksumSuffix in sha256sum format,
+// through a partial file like the upload itself.
+func writeChecksum(c *sftp.Client, remotePath string, local localFile) error {
+	sumPath := remotePath + ChecksumSuffix
+	f, err := c.OpenFile(sumPath+PartSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("sftp: create checksum file %s: %w", sumPath, err)
+	}
+	if _, err := fmt.Fprintf(f, "%x  %s\n", local.sum, path.Base(remotePath)); err != nil {
+		f.Close()
+		return fmt.Errorf("sftp: write checksum file %s: %w", sumPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("sftp: write checksum file %s: %w", sumPath, err)
+	}
+	return rename(c, sumPath+PartSuffix, sumPath)
+}
+
+// permanentError marks an error that a retry cannot fix (configuration,
+// credentials, the local file).
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error { return permanentError{err} }
+
+func retryable(err error) bool {
+	var p permanentError
+	return !errors.As(err, &p)
+}
+
 func splitKey(s string) (keyType string, b64 string, err error) {
 	parts := strings.Fields(strings.TrimSpace(s))
 	if len(parts) < 2 {
@@ -1,9 +1,19 @@
 package sftpclient
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"course-sync/internal/sftpclient/sftptest"
 )
 
 func TestConfig(t *testing.T) {
@@ -91,3 +101,174 @@ func TestUploadFileValidation(t *testing.T) {
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func testFile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7 % 251)
+	}
+	p := filepath.Join(t.TempDir(), "ef_course_add.xml")
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p, data
+}
+
+func testConfig(srv *sftptest.Server) Config {
+	return Config{Host: srv.Host, Port: srv.Port, User: srv.User, Pass: srv.Password, HostKey: srv.HostKey, RemoteDir: "/inbound"}
+}
+
+func TestUploadFileAtomicWithChecksum(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	if err := srv.WriteFile("/inbound/ef_course_add.xml", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	local, data := testFile(t, 150<<10)
+
+	cfg := testConfig(srv)
+	cfg.Checksum = true
+	cfg.Verify = true
+	if err := UploadFile(context.Background(), cfg, local, "ef_course_add.xml"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+
+	got, err := srv.ReadFile("/inbound/ef_course_add.xml")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Expected the uploaded file to replace the old one, got %d bytes, %v", len(got), err)
+	}
+	if _, err := srv.Stat("/inbound/ef_course_add.xml" + PartSuffix); err == nil {
+		t.Error("Expected the partial file to be renamed away")
+	}
+	sum, _ := srv.ReadFile("/inbound/ef_course_add.xml" + ChecksumSuffix)
+	if want := fmt.Sprintf("%x  ef_course_add.xml\n", sha256.Sum256(data)); string(sum) != want {
+		t.Errorf("Checksum file = %q, want %q", sum, want)
+	}
+	want := []string{"/inbound/ef_course_add.xml" + ChecksumSuffix, "/inbound/ef_course_add.xml"}
+	if got := srv.Renamed(); !slices.Equal(got, want) {
+		t.Errorf("Expected the checksum in place before the upload, got renames %v", got)
+	}
+}
+
+func TestUploadFileChecksumFailure(t *testing.T) {
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = 2 * time.Second })
+
+	srv := sftptest.NewServer(t)
+	if err := srv.WriteFile("/inbound/ef_course_add.xml", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	srv.Refuse("/inbound/ef_course_add.xml" + ChecksumSuffix + PartSuffix)
+	local, _ := testFile(t, 1<<10)
+
+	cfg := testConfig(srv)
+	cfg.Checksum = true
+	if err := UploadFile(context.Background(), cfg, local, "ef_course_add.xml"); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("Expected the upload to fail on the checksum file, got %v", err)
+	}
+	if got, _ := srv.ReadFile("/inbound/ef_course_add.xml"); string(got) != "old" {
+		t.Errorf("Expected the old file left in place, got %d bytes", len(got))
+	}
+}
+
+func TestUploadFileRetriesAndResumes(t *testing.T) {
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = 2 * time.Second })
+
+	srv := sftptest.NewServer(t)
+	local, data := testFile(t, 150<<10)
+	srv.DropAfter(64 << 10)
+
+	if err := UploadFile(context.Background(), testConfig(srv), local, "ef_course_add.xml"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+	if got, _ := srv.ReadFile("/inbound/ef_course_add.xml"); !bytes.Equal(got, data) {
+		t.Errorf("Expected the complete file after the retry, got %d bytes", len(got))
+	}
+	if n := srv.Sessions(); n != 2 {
+		t.Errorf("Expected 2 sessions, got %d", n)
+	}
+}
+
+func TestUploadFileResumeOffset(t *testing.T) {
+	local, data := testFile(t, 150<<10)
+	const done = 100 << 10
+
+	for _, tc := range []struct {
+		name    string
+		partial []byte
+		resumed bool
+	}{
+		{"prefix of the file", data[:done], true},
+		{"older version", bytes.Repeat([]byte("x"), done), false},
+		{"larger than the file", append(slices.Clone(data), 'x'), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := sftptest.NewServer(t)
+			if err := srv.WriteFile("/inbound/ef_course_add.xml"+PartSuffix, tc.partial); err != nil {
+				t.Fatal(err)
+			}
+			// A resumed upload writes only the rest, under the limit; a
+			// restarted one goes over it and fails (a single attempt).
+			srv.DropAfter(len64(data) - done + 1)
+			cfg := testConfig(srv)
+			cfg.Attempts = 1
+			err := UploadFile(context.Background(), cfg, local, "ef_course_add.xml")
+			if resumed := err == nil; resumed != tc.resumed {
+				t.Fatalf("Expected resumed=%v, got error %v", tc.resumed, err)
+			}
+			if err == nil {
+				if got, _ := srv.ReadFile("/inbound/ef_course_add.xml"); !bytes.Equal(got, data) {
+					t.Errorf("Expected the complete file, got %d bytes", len(got))
+				}
+			}
+		})
+	}
+}
+
+func len64(b []byte) int64 { return int64(len(b)) }
+
+func TestUploadFileWrongPassword(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	local, _ := testFile(t, 10)
+	cfg := testConfig(srv)
+	cfg.Pass = "wrong"
+	start := time.Now()
+	if err := UploadFile(context.Background(), cfg, local, "f.xml"); err == nil || retryable(err) {
+		t.Errorf("Expected a permanent auth error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected no retries for a wrong password")
+	}
+}
+
+func TestUploadFilesResults(t *testing.T) {
+	srv := sftptest.NewServer(t)
+	local, data := testFile(t, 1<<10)
+	files := []File{
+		{LocalPath: local, RemoteName: "ef_course_add.xml"},
+		{LocalPath: filepath.Join(t.TempDir(), "missing.xml"), RemoteName: "ef_course_update.xml"},
+		{LocalPath: local, RemoteName: "ef_course_delete.xml"},
+	}
+	results, err := UploadFiles(context.Background(), testConfig(srv), files)
+	if err == nil || !strings.Contains(err.Error(), "missing.xml") {
+		t.Fatalf("Expected the second file's error, got %v", err)
+	}
+	if n := srv.Sessions(); n != 1 {
+		t.Errorf("Expected one session for the batch, got %d", n)
+	}
+	first := results[0]
+	if first.Err != nil || first.RemotePath != "/inbound/ef_course_add.xml" || first.Bytes != int64(len(data)) ||
+		first.SHA256 != fmt.Sprintf("%x", sha256.Sum256(data)) {
+		t.Errorf("Unexpected first result %+v", first)
+	}
+	if results[1].Err == nil || results[1].SHA256 != "" {
+		t.Errorf("Expected the second file to fail without a checksum, got %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, ErrSkipped) {
+		t.Errorf("Expected the file after the failure to be skipped, got %v", results[2].Err)
+	}
+	if _, err := srv.Stat("/inbound/ef_course_delete.xml"); err == nil {
+		t.Error("Expected the skipped file not to be uploaded")
+	}
+}
@@ -1,13 +1,20 @@
 package sync
 
 import (
+	"context"
 	"math"
 	"strings"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"course-sync/internal/domain"
 	"course-sync/internal/export"
 )
 
+var tracer = otel.Tracer("course-sync/internal/sync")
+
 // Diff compares provider courses (Udemy + Pluralsight) with the current Eightfold catalog.
 //
 // Matching is done by (provider, lms_course_id), because in this tenant Eightfold stores
@@ -19,6 +26,13 @@ import (
 // - update: present in both but changed
 // - del: present in Eightfold but not in providers (only for managed providers)
 func Diff(provider []domain.UnifiedCourse, eightfold []EFCourse) (create []domain.UnifiedCourse, update []domain.UnifiedCourse, del []export.DeleteCourse) {
+	return DiffContext(context.Background(), provider, eightfold)
+}
+
+// DiffContext is Diff with a span per phase (indexing each side, create/update
+// and deletes) under the span in ctx.
+func DiffContext(ctx context.Context, provider []domain.UnifiedCourse, eightfold []EFCourse) (create []domain.UnifiedCourse, update []domain.UnifiedCourse, del []export.DeleteCourse) {
+	_, span := tracer.Start(ctx, "diff.index_providers", trace.WithAttributes(attribute.Int("courses", len(provider))))
 	provByKey := map[string]domain.UnifiedCourse{}
 	for _, c := range provider {
 		src := normProvider(c.Source)
@@ -31,7 +45,10 @@ func Diff(provider []domain.UnifiedCourse, eightfold []EFCourse) (create []domai
 		}
 		provByKey[key(src, lms)] = c
 	}
+	span.SetAttributes(attribute.Int("keys", len(provByKey)))
+	span.End()
 
+	_, span = tracer.Start(ctx, "diff.index_eightfold", trace.WithAttributes(attribute.Int("courses", len(eightfold))))
 	efByKey := map[string]EFCourse{}
 	for _, c := range eightfold {
 		src := normProvider(c.Provider)
@@ -47,8 +64,11 @@ func Diff(provider []domain.UnifiedCourse, eightfold []EFCourse) (create []domai
 		}
 		efByKey[key(src, lms)] = c
 	}
+	span.SetAttributes(attribute.Int("keys", len(efByKey)))
+	span.End()
 
 	// create/update
+	_, span = tracer.Start(ctx, "diff.create_update")
 	for k, pc := range provByKey {
 		efc, ok := efByKey[k]
 		if !ok {
@@ -59,8 +79,11 @@ func Diff(provider []domain.UnifiedCourse, eightfold []EFCourse) (create []domai
 			update = append(update, pc)
 		}
 	}
+	span.SetAttributes(attribute.Int("create", len(create)), attribute.Int("update", len(update)))
+	span.End()
 
 	// deletes
+	_, span = tracer.Start(ctx, "diff.deletes")
 	for k, efc := range efByKey {
 		if _, ok := provByKey[k]; ok {
 			continue
@@ -72,6 +95,8 @@ func Diff(provider []domain.UnifiedCourse, eightfold []EFCourse) (create []domai
 		}
 		del = append(del, export.DeleteCourse{Title: strings.TrimSpace(efc.Title), LMSCourseID: id})
 	}
+	span.SetAttributes(attribute.Int("delete", len(del)))
+	span.End()
 
 	return create, update, del
 }
@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"course-sync/internal/domain"
+)
+
+func TestDiff(t *testing.T) {
+	provider := []domain.UnifiedCourse{
+		{Source: "udemy", SourceID: "1", Title: "Go"},
+		{Source: "udemy", SourceID: "2", Title: "Rust 2nd edition"},
+		{Source: "pluralsight", SourceID: "ps-3", Title: "Kubernetes"},
+		{Source: "coursera", SourceID: "4", Title: "Ignored"},
+	}
+	eightfold := []EFCourse{
+		{Provider: "Udemy", LMSCourseID: "2", Title: "Rust"},
+		{LMSCourseID: "PLS+ps-3", Title: "Kubernetes"},
+		{Provider: "udemy", LMSCourseID: "9", Title: "Gone"},
+	}
+
+	create, update, del := Diff(provider, eightfold)
+
+	if len(create) != 1 || create[0].SourceID != "1" {
+		t.Errorf("create = %+v, want udemy 1", create)
+	}
+	if len(update) != 1 || update[0].SourceID != "2" {
+		t.Errorf("update = %+v, want udemy 2", update)
+	}
+	if len(del) != 1 || del[0].LMSCourseID != "9" || del[0].Title != "Gone" {
+		t.Errorf("del = %+v, want 9", del)
+	}
+}
+
+func TestDiffContextSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	otel.SetTracerProvider(tp)
+
+	ctx, root := tp.Tracer("test").Start(context.Background(), "sync")
+	DiffContext(ctx, []domain.UnifiedCourse{{Source: "udemy", SourceID: "1"}}, nil)
+	root.End()
+
+	want := []string{"diff.index_providers", "diff.index_eightfold", "diff.create_update", "diff.deletes", "sync"}
+	spans := rec.Ended()
+	if len(spans) != len(want) {
+		t.Fatalf("Expected %d spans, got %d", len(want), len(spans))
+	}
+	for i, s := range spans {
+		if s.Name() != want[i] {
+			t.Errorf("span %d = %s, want %s", i, s.Name(), want[i])
+		}
+		if s.Name() != "sync" && s.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the caller's span", s.Name())
+		}
+	}
+}
@@ -2,70 +2,36 @@ package sync
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	"course-sync/internal/providers/eightfold"
+	"course-sync/internal/tracing"
 )
 
-// FetchEightfoldCourses fetches courses from Eightfold and maps them into EFCourse.
-// This implementation is "best effort": it uses the existing Eightfold client's ListCourses()
-// and supports pagination if the backend honors pageStartIndex.
+// FetchEightfoldCourses fetches the courses Eightfold has from Udemy and
+// Pluralsight and maps them into EFCourse, page by page through the
+// client's paginator (both response shapes, page size capped at the
+// client's MaxPageSize).
 //
-// limit: request page size (recommended 200-500)
+// limit: request page size (0 = DefaultMaxPageSize)
 // maxPages: 0 means iterate until backend stops returning data.
-func FetchEightfoldCourses(ctx context.Context, ef *eightfold.Client, limit int, maxPages int) ([]EFCourse, error) {
+func FetchEightfoldCourses(ctx context.Context, ef *eightfold.Client, limit int, maxPages int) (_ []EFCourse, err error) {
+	ctx, span := tracer.Start(ctx, "eightfold.fetch_courses")
+	defer tracing.End(span, &err)
+
 	if limit <= 0 {
-		limit = 200
-	}
-	if maxPages < 0 {
-		maxPages = 0
+		limit = eightfold.DefaultMaxPageSize
 	}
+	p := ef.CoursesPaginator(limit)
+	p.MaxPages = max(maxPages, 0)
 
-	// Try pageStartIndex pagination if supported.
-	startIndex := 0
-	page := 0
 	out := make([]EFCourse, 0, 1024)
-
-	for {
-		page++
-		if maxPages > 0 && page > maxPages {
-			break
-		}
-
-		rows, meta, err := ef.ListCoursesPage(ctx, startIndex, limit)
+	for page, err := range p.Pages(ctx) {
 		if err != nil {
-			// Fall back to single-shot ListCourses if paging isn't supported.
-			if page == 1 {
-				raw, err2 := ef.ListCourses(ctx, limit)
-				if err2 != nil {
-					return nil, err
-				}
-				mapped := mapEightfoldRows(raw)
-				return filterManagedEightfold(mapped), nil
-			}
 			return nil, err
 		}
-
-		mapped := mapEightfoldRows(rows)
-		mapped = filterManagedEightfold(mapped)
-		out = append(out, mapped...)
-
-		if len(rows) == 0 {
-			break
-		}
-
-		// Advance
-		if meta.PageTotalCount <= 0 {
-			// Unknown paging; stop.
-			break
-		}
-		startIndex = meta.PageStartIndex + meta.PageTotalCount
-		if meta.TotalCount > 0 && startIndex >= meta.TotalCount {
-			break
-		}
+		out = append(out, filterManagedEightfold(mapEightfoldRows(page.Rows))...)
 	}
-
 	return out, nil
 }
 
@@ -92,24 +58,22 @@ func filterManagedEightfold(in []EFCourse) []EFCourse {
 func mapEightfoldRows(rows []map[string]any) []EFCourse {
 	out := make([]EFCourse, 0, len(rows))
 	for _, r := range rows {
-		c := EFCourse{
-			SystemID:      getString(r, "systemId", "system_id"),
-			LMSCourseID:   getString(r, "lmsCourseId", "lms_course_id"),
-			Provider:      getString(r, "provider"),
-			Title:         getString(r, "title"),
-			Description:   getString(r, "description"),
-			CourseURL:     getString(r, "courseUrl", "course_url"),
-			Language:      getString(r, "language"),
-			Category:      getString(r, "category"),
-			Difficulty:    getString(r, "difficulty"),
-			Status:        getString(r, "status"),
-			PublishedDate: getString(r, "publishedDate", "published_ts"),
-			ImageURL:      getString(r, "imageUrl", "image_url"),
-		}
-		if v, ok := getFloat(r, "durationHours", "duration_hours"); ok {
-			c.DurationHours = v
-		}
-		out = append(out, c)
+		c := eightfold.CourseFromMap(r)
+		out = append(out, EFCourse{
+			SystemID:      c.SystemID,
+			LMSCourseID:   c.LMSCourseID,
+			Provider:      c.Provider,
+			Title:         c.Title,
+			Description:   c.Description,
+			CourseURL:     c.CourseURL,
+			Language:      c.Language,
+			Category:      c.Category,
+			Difficulty:    c.Difficulty,
+			DurationHours: c.DurationHours,
+			Status:        c.Status,
+			PublishedDate: c.PublishedDate,
+			ImageURL:      c.ImageURL,
+		})
 	}
 	return out
 }
@@ -122,45 +86,3 @@ func firstNonEmpty(v ...string) string {
 	}
 	return ""
 }
-
-func getString(m map[string]any, keys ...string) string {
-	for _, k := range keys {
-		if v, ok := m[k]; ok && v != nil {
-			switch t := v.(type) {
-			case string:
-				return t
-			default:
-				return fmt.Sprintf("%v", t)
-			}
-		}
-	}
-	return ""
-}
-
-func getFloat(m map[string]any, keys ...string) (float64, bool) {
-	for _, k := range keys {
-		if v, ok := m[k]; ok && v != nil {
-			switch t := v.(type) {
-			case float64:
-				return t, true
-			case float32:
-				return float64(t), true
-			case int:
-				return float64(t), true
-			case int64:
-				return float64(t), true
-			case jsonNumber:
-				f, err := t.Float64()
-				if err == nil {
-					return f, true
-				}
-			}
-		}
-	}
-	return 0, false
-}
-
-// jsonNumber is a tiny interface to avoid importing encoding/json in this file.
-type jsonNumber interface {
-	Float64() (float64, error)
-}
@@ -0,0 +1,125 @@
+// Package tracing sets up OpenTelemetry tracing for course-sync.
+//
+// Packages create spans with the global tracer provider:
+//
+//	var tracer = otel.Tracer("course-sync/internal/httpx")
+//
+//	ctx, span := tracer.Start(ctx, "udemy.page", trace.WithAttributes(...))
+//	defer tracing.End(span, &err)
+//
+// Until Setup installs a provider those spans are no-ops. Setup picks the
+// exporter from $COURSE_SYNC_TRACES:
+//
+//	otlp         OTLP over HTTP; endpoint, headers etc. from the standard
+//	             OTEL_EXPORTER_OTLP_* variables (the default when
+//	             OTEL_EXPORTER_OTLP_ENDPOINT or ..._TRACES_ENDPOINT is set)
+//	stdout       one JSON span per line on stdout
+//	file:<path>  the same, appended to path (for offline runs)
+//	none         tracing off (the default otherwise)
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	exporterEnv = "COURSE_SYNC_TRACES"
+
+	serviceName = "course-sync"
+)
+
+// Setup installs the global tracer provider selected by $COURSE_SYNC_TRACES.
+// shutdown flushes pending spans; call it before the process exits.
+func Setup(ctx context.Context, stdout io.Writer) (shutdown func(context.Context) error, err error) {
+	exp, closer, err := newExporter(ctx, stdout)
+	if err != nil || exp == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, stdout io.Writer) (sdktrace.SpanExporter, io.Closer, error) {
+	mode := strings.TrimSpace(os.Getenv(exporterEnv))
+	if mode == "" && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		mode = "otlp"
+	}
+
+	switch {
+	case mode == "" || mode == "none":
+		return nil, nil, nil
+	case mode == "otlp":
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tracing: otlp exporter: %w", err)
+		}
+		return exp, nil, nil
+	case mode == "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("tracing: stdout exporter: %w", err)
+		}
+		return exp, nil, nil
+	case strings.HasPrefix(mode, "file:"):
+		path := strings.TrimPrefix(mode, "file:")
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tracing: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("tracing: file exporter: %w", err)
+		}
+		return exp, f, nil
+	default:
+		return nil, nil, fmt.Errorf("%s: unknown exporter %q (want otlp, stdout, file:<path> or none)", exporterEnv, mode)
+	}
+}
+
+// End records *errp (if any) on span and ends it. Use it deferred with a
+// named error result:
+//
+//	defer tracing.End(span, &err)
+func End(span trace.Span, errp *error) {
+	if errp != nil && *errp != nil {
+		span.RecordError(*errp)
+		span.SetStatus(codes.Error, (*errp).Error())
+	}
+	span.End()
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	t.Setenv(exporterEnv, "file:"+path)
+
+	shutdown, err := Setup(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+
+	func() (err error) {
+		_, span := otel.Tracer("test").Start(context.Background(), "sftp.upload")
+		defer End(span, &err)
+		return errors.New("boom")
+	}()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var span struct {
+		Name   string
+		Status struct{ Code, Description string }
+		Events []struct{ Name string }
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(b), &span); err != nil {
+		t.Fatalf("Expected one JSON span, got %q: %v", b, err)
+	}
+	if span.Name != "sftp.upload" || span.Status.Code != "Error" || span.Status.Description != "boom" ||
+		len(span.Events) != 1 || span.Events[0].Name != "exception" {
+		t.Errorf("Unexpected span: %+v", span)
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv(exporterEnv, "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	shutdown, err := Setup(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error: %v", err)
+	}
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	t.Setenv(exporterEnv, "jaeger")
+	if _, err := Setup(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "unknown exporter") {
+		t.Errorf("Expected an unknown exporter error, got %v", err)
+	}
+}